@@ -2,8 +2,9 @@ package main
 
 import (
 	"context"
+	"deepllm/components/agent"
+	"deepllm/components/config"
 	"deepllm/components/mock"
-	"deepllm/components/ollama"
 	"deepllm/components/tools"
 	"deepllm/internal/data"
 	"fmt"
@@ -21,16 +22,36 @@ func main() {
 	dataLoader := data.NewDataLoader(dataPath)
 	dataQuery := data.NewDataQuery(dataLoader)
 
-	// Initialize Ollama chat model
-	ollamaBaseURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaBaseURL == "" {
-		ollamaBaseURL = "http://localhost:11434"
+	// Keep the data in memory and reload it when the files change
+	repository, err := data.NewRepository(dataLoader, data.WithReloadHandler(func(snapshot *data.Snapshot, err error) {
+		if err != nil {
+			log.Printf("重新加载数据失败，继续使用旧数据: %v", err)
+			return
+		}
+		log.Printf("数据已重新加载: %d 个景点, %d 家餐厅, %d 家酒店",
+			len(snapshot.Attractions), len(snapshot.Restaurants), len(snapshot.Hotels))
+	}))
+	if err != nil {
+		log.Fatalf("加载数据失败: %v", err)
+	}
+	dataQuery.UseDataset(repository)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go repository.Watch(watchCtx, data.DefaultReloadInterval)
+
+	// Initialize chat model (Ollama or an OpenAI-compatible server)
+	modelConfig, err := config.ModelConfigFromEnv()
+	if err != nil {
+		log.Fatalf("读取模型配置失败: %v", err)
 	}
-	ollamaModel := os.Getenv("OLLAMA_MODEL")
-	if ollamaModel == "" {
-		ollamaModel = "deepseek-r1:14b"
+	chatModel, err := config.NewChatModel(modelConfig)
+	if err != nil {
+		log.Fatalf("创建模型失败: %v", err)
+	}
+	// Fail fast when the server is down or the model is not installed
+	if err := config.Preflight(context.Background(), modelConfig, chatModel); err != nil {
+		log.Fatalf("模型预检失败: %v", err)
 	}
-	chatModel := ollama.NewChatModel(ollamaBaseURL, ollamaModel)
 
 	// Create tourism tools
 	tourismTools := tools.CreateTourismTools(dataQuery)
@@ -69,21 +90,28 @@ func main() {
 		},
 	}
 
-	// Bind tools to chat model
-	chatModel.BindTools(tourismTools)
+	// Create a ReAct agent that queries our data through the tourism tools
+	// and streams its answer as it is generated
+	guide := agent.NewBaseAgent("guide", chatModel, tourismTools, dataQuery)
+	guide.SetStreamHandler(func(chunk *mock.Message) {
+		fmt.Print(chunk.Content)
+	})
+	ctx := context.Background()
+	reactAgent, err := guide.CreateReactAgent(ctx, systemPrompt)
+	if err != nil {
+		log.Fatalf("创建智能体失败: %v", err)
+	}
 
 	// Call the model
-	ctx := context.Background()
-	response, err := chatModel.Generate(ctx, messages)
+	fmt.Printf("\n=== 旅游助手回答 ===\n")
+	response, err := reactAgent.Invoke(ctx, messages)
 	if err != nil {
 		log.Fatalf("调用模型失败: %v", err)
 	}
+	fmt.Println()
 
 	// Output result
-	fmt.Printf("\n=== 旅游助手回答 ===\n")
-	if response != nil && response.Content != "" {
-		fmt.Printf("%s\n", response.Content)
-	} else {
+	if response == nil || response.Content == "" {
 		fmt.Println("抱歉，助手暂时无法回答您的问题。")
 	}
 
@@ -91,7 +119,7 @@ func main() {
 	fmt.Println("\n=== 数据查询演示 ===")
 
 	// 加载并筛选景点
-	attractions, err := dataLoader.LoadAttractions()
+	query, err := dataQuery.Attractions()
 	if err != nil {
 		log.Fatalf("加载景点数据失败: %v", err)
 	}
@@ -103,26 +131,42 @@ func main() {
 	}
 
 	// 扩大搜索范围到5公里
-	nearbyAttractions := data.FindNearbyAttractions(attractions, location, 5.0)
+	nearbyAttractions := query.Near(location, 5.0).Run()
 	fmt.Printf("\n【附近景点】\n")
-	fmt.Printf("在西湖5公里范围内找到%d个景点\n", len(nearbyAttractions))
+	fmt.Printf("在西湖5公里范围内找到%d个景点\n", nearbyAttractions.Total)
 
-	// 按偏好筛选
+	// 在附近景点中按偏好（类别或标签）筛选，按评分排序，取前5个
 	preferences := []string{"自然风光", "人文景观", "历史文化"}
-	filteredAttractions := dataQuery.FilterAttractionsByPreferences(nearbyAttractions, preferences)
+	fields := query.Fields()
+	topAttractions := query.
+		Where(data.Or(fields.HasCategory(preferences...), fields.HasTag(preferences...))).
+		SortBy(data.Desc(data.SortByRating)).
+		Limit(5).
+		Run()
 	fmt.Printf("\n【推荐景点】\n")
-	fmt.Printf("符合偏好的景点数量：%d（偏好：%s）\n", len(filteredAttractions), "自然风光、人文景观、历史文化")
+	fmt.Printf("符合偏好的景点数量：%d（偏好：%s）\n", topAttractions.Total, "自然风光、人文景观、历史文化")
 
-	// 按评分排序并展示详情
-	sortedAttractions := dataQuery.SortByRating(filteredAttractions)
-	fmt.Println("\n【景点排名】（按评分排序）")
-	for i, attraction := range sortedAttractions {
-		if i >= 5 { // 显示前5个景点
-			break
+	// 语义搜索：按与偏好描述的相似度排序，不要求字面匹配
+	embeddingConfig := config.EmbeddingConfigFromEnv()
+	dataQuery.EnableSemanticSearch(config.NewEmbedder(embeddingConfig), embeddingConfig.IndexPath)
+	matches, err := dataQuery.SemanticSearch(ctx, "观光 自然风光 湖景", data.KindAttraction, 3)
+	if err != nil {
+		log.Printf("语义搜索失败: %v", err)
+	} else {
+		fmt.Printf("\n【语义搜索】（观光 自然风光 湖景）\n")
+		for i, match := range matches {
+			fmt.Printf("%d. %s  相似度: %.2f\n", i+1, match.Name(), match.Score)
 		}
-		fmt.Printf("%d. %s\n   评分: %.1f  价格: %.0f元\n   类别: %v\n   描述: %s\n\n",
+	}
+
+	// 展示评分最高的景点详情
+	fmt.Println("\n【景点排名】（按评分排序）")
+	for i, hit := range topAttractions.Hits {
+		attraction := hit.Item
+		fmt.Printf("%d. %s（距西湖%s）\n   评分: %.1f  价格: %.0f元\n   类别: %v\n   描述: %s\n\n",
 			i+1,
 			attraction.Name,
+			hit.DistanceText(),
 			attraction.Rating,
 			attraction.Price,
 			attraction.Category,
@@ -131,7 +175,7 @@ func main() {
 	}
 
 	// 加载并显示天气信息
-	weatherData, err := dataLoader.LoadWeather()
+	weatherData, err := dataQuery.LoadWeather()
 	if err != nil {
 		log.Fatalf("加载天气数据失败: %v", err)
 	}
@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"deepllm/components/agent"
 	"deepllm/components/agent/coordinator"
+	"deepllm/components/cache"
+	"deepllm/components/cassette"
+	"deepllm/components/config"
 	"deepllm/components/mock"
-	"deepllm/components/ollama"
+	"deepllm/components/router"
 	"deepllm/internal/data"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,16 +27,41 @@ func main() {
 	dataLoader := data.NewDataLoader(dataPath)
 	dataQuery := data.NewDataQuery(dataLoader)
 
-	// Initialize Ollama chat model
-	ollamaBaseURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaBaseURL == "" {
-		ollamaBaseURL = "http://localhost:11434"
+	// Keep the data in memory and reload it when the files change
+	repository, err := data.NewRepository(dataLoader, data.WithReloadHandler(func(snapshot *data.Snapshot, err error) {
+		if err != nil {
+			log.Printf("重新加载数据失败，继续使用旧数据: %v", err)
+			return
+		}
+		log.Printf("数据已重新加载: %d 个景点, %d 家餐厅, %d 家酒店",
+			len(snapshot.Attractions), len(snapshot.Restaurants), len(snapshot.Hotels))
+	}))
+	if err != nil {
+		log.Fatalf("加载数据失败: %v", err)
+	}
+	dataQuery.UseDataset(repository)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go repository.Watch(watchCtx, data.DefaultReloadInterval)
+
+	// Initialize chat model (Ollama or an OpenAI-compatible server)
+	modelConfig, err := config.ModelConfigFromEnv()
+	if err != nil {
+		log.Fatalf("读取模型配置失败: %v", err)
+	}
+	chatModel, err := config.NewChatModel(modelConfig)
+	if err != nil {
+		log.Fatalf("创建模型失败: %v", err)
 	}
-	ollamaModel := os.Getenv("OLLAMA_MODEL")
-	if ollamaModel == "" {
-		ollamaModel = "deepseek-r1:14b"
+	if r, ok := chatModel.(*router.Router); ok {
+		for _, name := range r.Routes() {
+			log.Printf("模型路由: %s -> %s", name, strings.Join(r.ModelNames(name), ", "))
+		}
+	}
+	// Fail fast when the server is down or the model is not installed
+	if err := config.Preflight(context.Background(), modelConfig, chatModel); err != nil {
+		log.Fatalf("模型预检失败: %v", err)
 	}
-	chatModel := ollama.NewChatModel(ollamaBaseURL, ollamaModel)
 
 	// Create tools
 	tools := []mock.Tool{
@@ -78,6 +109,9 @@ func main() {
 
 	// Create coordinator agent
 	coordinatorAgent := coordinator.NewCoordinatorAgent(chatModel, tools, dataQuery)
+	if structured, _ := strconv.ParseBool(os.Getenv("STRUCTURED_OUTPUT")); structured {
+		coordinatorAgent.SetStructuredOutput(true)
+	}
 
 	// Create sample trip request
 	request := &data.TripPlanRequest{
@@ -112,8 +146,10 @@ func main() {
 		Requirements: []string{"无障碍设施"},
 	}
 
-	// Process the request
-	ctx := context.Background()
+	// Process the request, reporting prompts cut to fit the context window
+	ctx := agent.WithTruncationHandler(context.Background(), func(t agent.Truncation) {
+		log.Printf("上下文预算不足: %s 仅保留 %d/%d 项", t.Section, t.Kept, t.Total)
+	})
 	result, err := coordinatorAgent.Process(ctx, request)
 	if err != nil {
 		log.Fatalf("处理请求失败: %v", err)
@@ -121,4 +157,27 @@ func main() {
 
 	// Print the result
 	fmt.Printf("行程规划:\n%s\n", result.(*mock.Message).Content)
+
+	// Sum the cache statistics of every model
+	models := []mock.ChatModel{chatModel}
+	if r, ok := chatModel.(*router.Router); ok {
+		models = r.Models()
+	}
+	var stats cache.Stats
+	var cached bool
+	for _, model := range models {
+		if recorder, ok := model.(*cassette.Recorder); ok {
+			model = recorder.Unwrap()
+		}
+		if c, ok := model.(*cache.ChatModel); ok {
+			s := c.Stats()
+			stats.Hits += s.Hits
+			stats.Misses += s.Misses
+			stats.Errors += s.Errors
+			cached = true
+		}
+	}
+	if cached {
+		log.Printf("响应缓存: 命中 %d, 未命中 %d, 命中率 %.0f%%", stats.Hits, stats.Misses, stats.HitRate()*100)
+	}
 }
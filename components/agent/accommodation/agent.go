@@ -27,32 +27,18 @@ func (a *AccommodationAgent) Process(ctx context.Context, input interface{}) (in
 		return nil, fmt.Errorf("invalid input type for accommodation agent")
 	}
 
-	// Load hotels data
-	hotels, err := a.DataQuery.Loader.LoadHotels()
+	// Find the hotels within 5km in budget with the preferred amenities, best
+	// rated first so truncation drops the least appealing
+	query, err := a.DataQuery.Hotels()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load hotels: %v", err)
 	}
-
-	// Find nearby hotels
-	nearbyHotels := data.FindNearbyHotels(hotels, request.Location, 5.0) // Within 5km
-
-	// Filter by preferences and budget
-	filteredHotels := a.DataQuery.FilterHotelsByPreferences(
-		nearbyHotels,
-		request.Preferences.Hotel,
-		request.Budget.Hotel,
-	)
-
-	// Sort hotels by rating
-	sortedHotels := make([]data.Hotel, len(filteredHotels))
-	copy(sortedHotels, filteredHotels)
-	for i := 0; i < len(sortedHotels)-1; i++ {
-		for j := i + 1; j < len(sortedHotels); j++ {
-			if sortedHotels[j].Rating > sortedHotels[i].Rating {
-				sortedHotels[i], sortedHotels[j] = sortedHotels[j], sortedHotels[i]
-			}
-		}
-	}
+	hotels := query.
+		Near(request.Location, 5.0).
+		MaxPrice(request.Budget.Hotel).
+		Tag(request.Preferences.Hotel...).
+		SortBy(data.Desc(data.SortByRating), data.Asc(data.SortByDistance)).
+		Run()
 
 	// Use LLM to analyze and recommend hotels
 	systemPrompt := a.BuildPrompt(
@@ -60,6 +46,23 @@ func (a *AccommodationAgent) Process(ctx context.Context, input interface{}) (in
 		"analyze hotel options and provide personalized recommendations based on user preferences and requirements",
 	)
 
+	criteria := fmt.Sprintf("Please recommend hotels based on the following criteria:\n"+
+		"Budget per night: %.2f\n"+
+		"Preferred amenities: %v\n"+
+		"Number of people: %d\n"+
+		"Special requirements: %v\n\n",
+		request.Budget.Hotel,
+		request.Preferences.Hotel,
+		request.PartySize,
+		request.Requirements,
+	)
+
+	// Give the hotel list half of the remaining budget, leaving room for
+	// tool calls and their results
+	budget := a.Budget(ctx)
+	hotelList := agent.FitList(ctx, budget, "hotels", hotels.Hits, budget.Remaining(systemPrompt, criteria)/2,
+		func(h data.Nearby[data.Hotel]) string { return fmt.Sprintf("%+v (%s away)", h.Item, h.DistanceText()) })
+
 	agent, err := a.CreateReactAgent(ctx, systemPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %v", err)
@@ -72,19 +75,8 @@ func (a *AccommodationAgent) Process(ctx context.Context, input interface{}) (in
 			Content: systemPrompt,
 		},
 		{
-			Role: "user",
-			Content: fmt.Sprintf("Please recommend hotels based on the following criteria:\n"+
-				"Budget per night: %.2f\n"+
-				"Preferred amenities: %v\n"+
-				"Number of people: %d\n"+
-				"Special requirements: %v\n\n"+
-				"Available hotels: %+v",
-				request.Budget.Hotel,
-				request.Preferences.Hotel,
-				request.PartySize,
-				request.Requirements,
-				sortedHotels,
-			),
+			Role:    "user",
+			Content: criteria + "Available hotels:\n" + hotelList,
 		},
 	}
 
@@ -20,10 +20,26 @@ type BaseAgent struct {
 	model     mock.ChatModel
 	tools     []mock.Tool
 	DataQuery *data.DataQuery
+
+	streamHandler StreamHandler
+	options       mock.Options
 }
 
-// NewBaseAgent creates a new base agent
+// StreamHandler receives incremental message chunks while an agent is generating
+type StreamHandler func(chunk *mock.Message)
+
+// ModelRouter is a chat model that hands every agent its own model, such as
+// router.Router
+type ModelRouter interface {
+	ModelFor(agent string) mock.ChatModel
+}
+
+// NewBaseAgent creates a new base agent. When model is a ModelRouter, the
+// agent uses the model routed to its name.
 func NewBaseAgent(name string, model mock.ChatModel, tools []mock.Tool, dataQuery *data.DataQuery) *BaseAgent {
+	if router, ok := model.(ModelRouter); ok {
+		model = router.ModelFor(name)
+	}
 	return &BaseAgent{
 		name:      name,
 		model:     model,
@@ -37,6 +53,34 @@ func (b *BaseAgent) Name() string {
 	return b.name
 }
 
+// Model returns the chat model used by the agent
+func (b *BaseAgent) Model() mock.ChatModel {
+	return b.model
+}
+
+// SetStreamHandler enables streaming for the agent. When the underlying model
+// supports streaming, every chunk is passed to handler as it arrives.
+func (b *BaseAgent) SetStreamHandler(handler StreamHandler) {
+	b.streamHandler = handler
+}
+
+// SetOptions sets the generation options used by the agent. They override
+// the model defaults and are themselves overridden by per-call options.
+func (b *BaseAgent) SetOptions(opts mock.Options) {
+	b.options = opts
+}
+
+// Options returns the agent's generation options
+func (b *BaseAgent) Options() mock.Options {
+	return b.options
+}
+
+// Budget returns the prompt budget of the agent's model, given the agent's
+// options and those carried by ctx
+func (b *BaseAgent) Budget(ctx context.Context) *Budget {
+	return BudgetFor(ctx, b.model, b.options)
+}
+
 // BuildPrompt builds a prompt for the agent
 func (b *BaseAgent) BuildPrompt(role string, context string) string {
 	return `You are a ${role} for the Hangzhou Tourism Assistant system.
@@ -54,21 +98,12 @@ Respond in a clear and organized manner.`
 }
 
 // CreateReactAgent creates a ReAct agent with the given tools
-func (b *BaseAgent) CreateReactAgent(ctx context.Context, systemPrompt string) (mock.Runnable[[]*mock.Message, *mock.Message], error) {
-	// For now, we'll return a simple mock implementation
-	return &mockReactAgent{
-		model: b.model,
-		tools: b.tools,
-	}, nil
-}
-
-// mockReactAgent is a mock implementation of the ReAct agent
-type mockReactAgent struct {
-	model mock.ChatModel
-	tools []mock.Tool
-}
-
-// Invoke processes the input and returns a response
-func (m *mockReactAgent) Invoke(ctx context.Context, input []*mock.Message) (*mock.Message, error) {
-	return m.model.Generate(ctx, input)
+func (b *BaseAgent) CreateReactAgent(ctx context.Context, systemPrompt string, opts ...ReactOption) (*ReactAgent, error) {
+	opts = append([]ReactOption{
+		WithOptions(b.options),
+		WithBudget(b.Budget(ctx), nil),
+	}, opts...)
+	r := NewReactAgent(b.model, b.tools, systemPrompt, opts...)
+	r.streamHandler = b.streamHandler
+	return r, nil
 }
@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultContextLength is assumed when neither the options nor the model
+// tell the context length. It matches Ollama's default num_ctx.
+const DefaultContextLength = 4096
+
+// DefaultOutputReserve is the number of tokens kept free for the reply when
+// NumPredict is not set
+const DefaultOutputReserve = 1024
+
+const (
+	// messageTokens approximates the role markers around every message
+	messageTokens = 4
+	// imageTokens approximates the cost of one image attachment
+	imageTokens = 768
+	// summaryTokens is the room kept for the summary of dropped messages
+	summaryTokens = 256
+)
+
+// EstimateTokens approximates the number of tokens of text without a
+// tokenizer: one per CJK character and one per four other characters. It
+// errs on the high side for English prose.
+func EstimateTokens(text string) int {
+	var cjk, other int
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// EstimateMessageTokens approximates the number of tokens a message takes in
+// the prompt, including tool calls and image attachments
+func EstimateMessageTokens(msg *mock.Message) int {
+	tokens := messageTokens + EstimateTokens(msg.Content)
+	for _, call := range msg.ToolCalls {
+		tokens += EstimateTokens(call.Name) + EstimateTokens(fmt.Sprint(call.Args))
+	}
+	return tokens + imageTokens*len(msg.Images)
+}
+
+// EstimateMessagesTokens approximates the number of tokens of a conversation
+func EstimateMessagesTokens(messages []*mock.Message) int {
+	var tokens int
+	for _, msg := range messages {
+		tokens += EstimateMessageTokens(msg)
+	}
+	return tokens
+}
+
+// Truncation reports that a prompt section was cut to fit the context
+type Truncation struct {
+	// Section names what was cut, e.g. "hotels" or "messages"
+	Section string
+	// Kept and Total count the items of the section
+	Kept, Total int
+}
+
+func (t Truncation) String() string {
+	return fmt.Sprintf("%s truncated to %d of %d to fit the context", t.Section, t.Kept, t.Total)
+}
+
+// TruncationHandler is called whenever a Budget truncates a prompt section
+type TruncationHandler func(Truncation)
+
+type truncationHandlerKey struct{}
+
+// WithTruncationHandler returns a context whose budgets report truncations
+// to handler, so that callers learn about prompts that did not fit
+func WithTruncationHandler(ctx context.Context, handler TruncationHandler) context.Context {
+	return context.WithValue(ctx, truncationHandlerKey{}, handler)
+}
+
+// Budget is the number of prompt tokens available to an agent: the model's
+// context length minus the room kept for the reply. Prompt sections are fit
+// into it with FitList and conversations with FitMessages.
+type Budget struct {
+	contextLength int
+	outputReserve int
+
+	mu          sync.Mutex
+	truncations []Truncation
+}
+
+// NewBudget creates a budget for a context of contextLength tokens, keeping
+// outputReserve tokens free for the reply
+func NewBudget(contextLength, outputReserve int) *Budget {
+	if contextLength <= 0 {
+		contextLength = DefaultContextLength
+	}
+	if outputReserve < 0 || outputReserve > contextLength/2 {
+		outputReserve = contextLength / 2
+	}
+	return &Budget{contextLength: contextLength, outputReserve: outputReserve}
+}
+
+// BudgetFor creates the budget of model called with opts and the options
+// carried by ctx. The context length is NumCtx when set, otherwise the
+// model's own when it has a ContextLength method, e.g. an Ollama model after
+// its preflight, otherwise DefaultContextLength. The reply reserve is
+// NumPredict when set. Decorators such as the response cache forward both
+// the default options and the context length of the model they wrap.
+func BudgetFor(ctx context.Context, model mock.ChatModel, opts mock.Options) *Budget {
+	if defaults, ok := model.(interface{ Options() mock.Options }); ok {
+		opts = defaults.Options().Merge(opts)
+	}
+	opts = opts.Merge(mock.OptionsFromContext(ctx))
+
+	contextLength := opts.NumCtx
+	if m, ok := model.(interface{ ContextLength() int }); ok && contextLength == 0 {
+		contextLength = m.ContextLength()
+	}
+
+	outputReserve := DefaultOutputReserve
+	if opts.NumPredict > 0 {
+		outputReserve = opts.NumPredict
+	}
+	return NewBudget(contextLength, outputReserve)
+}
+
+// ContextLength returns the context length of the model
+func (b *Budget) ContextLength() int {
+	return b.contextLength
+}
+
+// Available returns the number of tokens available to the prompt
+func (b *Budget) Available() int {
+	return b.contextLength - b.outputReserve
+}
+
+// Remaining returns the prompt tokens left once texts are in the prompt
+func (b *Budget) Remaining(texts ...string) int {
+	remaining := b.Available()
+	for _, text := range texts {
+		remaining -= EstimateTokens(text)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Truncations returns the truncations made so far
+func (b *Budget) Truncations() []Truncation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Truncation(nil), b.truncations...)
+}
+
+// report records a truncation and passes it to the handler of ctx
+func (b *Budget) report(ctx context.Context, t Truncation) {
+	b.mu.Lock()
+	b.truncations = append(b.truncations, t)
+	b.mu.Unlock()
+
+	if handler, ok := ctx.Value(truncationHandlerKey{}).(TruncationHandler); ok && handler != nil {
+		handler(t)
+	}
+}
+
+// FitList formats items one per line, in order, stopping before the list
+// exceeds maxTokens. Dropped items are reported as a truncation of section
+// and announced in the list, so the model knows it is incomplete. Sort the
+// items by relevance first.
+func FitList[T any](ctx context.Context, b *Budget, section string, items []T, maxTokens int, format func(T) string) string {
+	var list strings.Builder
+	tokens := 0
+	kept := 0
+	for _, item := range items {
+		line := format(item) + "\n"
+		lineTokens := EstimateTokens(line)
+		if tokens+lineTokens > maxTokens {
+			break
+		}
+		list.WriteString(line)
+		tokens += lineTokens
+		kept++
+	}
+
+	if kept < len(items) {
+		b.report(ctx, Truncation{Section: section, Kept: kept, Total: len(items)})
+		fmt.Fprintf(&list, "(%d more %s omitted)\n", len(items)-kept, section)
+	}
+	return list.String()
+}
+
+// Summarizer condenses messages dropped from a conversation into a text
+type Summarizer func(ctx context.Context, messages []*mock.Message) (string, error)
+
+// ExcerptSummarizer summarizes messages without a model call, keeping the
+// first maxChars characters of every message
+func ExcerptSummarizer(maxChars int) Summarizer {
+	return func(ctx context.Context, messages []*mock.Message) (string, error) {
+		var summary strings.Builder
+		for _, msg := range messages {
+			content := strings.Join(strings.Fields(msg.Content), " ")
+			if runes := []rune(content); len(runes) > maxChars {
+				content = string(runes[:maxChars]) + "..."
+			}
+			if content == "" && len(msg.ToolCalls) > 0 {
+				var names []string
+				for _, call := range msg.ToolCalls {
+					names = append(names, call.Name)
+				}
+				content = "called " + strings.Join(names, ", ")
+			}
+			fmt.Fprintf(&summary, "- %s: %s\n", msg.Role, content)
+		}
+		return summary.String(), nil
+	}
+}
+
+// ModelSummarizer summarizes messages with a model call. Tools bound to the
+// model stay bound; a reply without text is an error.
+func ModelSummarizer(model mock.ChatModel) Summarizer {
+	return func(ctx context.Context, messages []*mock.Message) (string, error) {
+		var transcript strings.Builder
+		for _, msg := range messages {
+			fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+		}
+		reply, err := model.Generate(ctx, []*mock.Message{
+			{
+				Role: "user",
+				Content: "Summarize the following conversation in a few sentences, " +
+					"keeping every fact, number and decision needed to continue it:\n\n" + transcript.String(),
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(reply.Content) == "" {
+			return "", fmt.Errorf("model returned an empty summary")
+		}
+		return reply.Content, nil
+	}
+}
+
+// FitMessages returns messages unchanged when they fit the budget. Otherwise
+// it keeps the leading system messages, the first user message and the most
+// recent messages that fit, and replaces the messages in between with a
+// system message holding their summary. A conversation is never cut between
+// a tool call and its results: a cut block of results goes into the summary,
+// unless it ends the conversation and is kept whole with its call. The
+// truncation is reported under "messages".
+func (b *Budget) FitMessages(ctx context.Context, messages []*mock.Message, summarize Summarizer) ([]*mock.Message, error) {
+	if EstimateMessagesTokens(messages) <= b.Available() {
+		return messages, nil
+	}
+
+	// Keep the system prompt and the user's request
+	head := 0
+	for head < len(messages) && messages[head].Role == "system" {
+		head++
+	}
+	if head < len(messages)-1 && messages[head].Role == "user" {
+		head++
+	}
+
+	// Keep the most recent messages, always including the last one
+	remaining := b.Available() - EstimateMessagesTokens(messages[:head]) - summaryTokens
+	tail := len(messages)
+	for tail > head {
+		tokens := EstimateMessageTokens(messages[tail-1])
+		if tokens > remaining && tail < len(messages) {
+			break
+		}
+		remaining -= tokens
+		tail--
+	}
+	// Tool results must follow the assistant message that requested them
+	end := tail
+	for end < len(messages) && messages[end].Role == "tool" {
+		end++
+	}
+	if end < len(messages) {
+		tail = end
+	} else {
+		for tail > head && messages[tail].Role == "tool" {
+			tail--
+		}
+	}
+	if tail == head {
+		return messages, nil
+	}
+
+	dropped := messages[head:tail]
+	summary, err := summarize(ctx, dropped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize earlier messages: %v", err)
+	}
+
+	fitted := make([]*mock.Message, 0, head+1+len(messages)-tail)
+	fitted = append(fitted, messages[:head]...)
+	fitted = append(fitted, &mock.Message{
+		Role:    "system",
+		Content: "Summary of the earlier conversation:\n" + summary,
+	})
+	fitted = append(fitted, messages[tail:]...)
+
+	b.report(ctx, Truncation{Section: "messages", Kept: len(fitted), Total: len(messages)})
+	return fitted, nil
+}
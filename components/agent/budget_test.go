@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/cache"
+	"deepllm/components/cassette"
+	"deepllm/components/mock"
+	"deepllm/components/ollama"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkToolResults fails unless every tool message answers a tool call of
+// the assistant message before its block of results
+func checkToolResults(t *testing.T, messages []*mock.Message) {
+	t.Helper()
+	var calls map[string]bool
+	for i, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			if !calls[msg.ToolCallID] {
+				t.Errorf("message %d answers %q, which no preceding assistant message called", i, msg.ToolCallID)
+			}
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			calls = map[string]bool{}
+			for _, call := range msg.ToolCalls {
+				calls[call.ID] = true
+			}
+		default:
+			calls = nil
+		}
+	}
+}
+
+// roles returns the roles of messages joined by commas
+func roles(messages []*mock.Message) string {
+	out := make([]string, len(messages))
+	for i, msg := range messages {
+		out[i] = msg.Role
+	}
+	return strings.Join(out, ",")
+}
+
+func toolCall(id string) *mock.Message {
+	return &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{{
+		ID:   id,
+		Name: "get_weather",
+		Args: map[string]interface{}{"city": "杭州"},
+	}}}
+}
+
+func toolResult(id string, chars int) *mock.Message {
+	return &mock.Message{Role: "tool", Name: "get_weather", ToolCallID: id, Content: strings.Repeat("晴", chars)}
+}
+
+func TestFitMessagesUnchanged(t *testing.T) {
+	messages := []*mock.Message{{Role: "system", Content: "你是助手"}, {Role: "user", Content: "hi"}}
+	fitted, err := NewBudget(4096, 1024).FitMessages(context.Background(), messages, ExcerptSummarizer(10))
+	if err != nil {
+		t.Fatalf("FitMessages: %v", err)
+	}
+	if len(fitted) != 2 || fitted[0] != messages[0] || fitted[1] != messages[1] {
+		t.Errorf("fitted = %s, want the messages unchanged", roles(fitted))
+	}
+}
+
+func TestFitMessagesKeepsLastToolBlock(t *testing.T) {
+	messages := []*mock.Message{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "q"},
+		toolCall("call_a"),
+		toolResult("call_a", 300),
+		{Role: "assistant", Content: "ok"},
+		{Role: "user", Content: "next"},
+		toolCall("call_b"),
+		toolResult("call_b", 400),
+	}
+	// Room for the last tool result but not for the call before it
+	b := NewBudget(1000, 321)
+
+	fitted, err := b.FitMessages(context.Background(), messages, ExcerptSummarizer(10))
+	if err != nil {
+		t.Fatalf("FitMessages: %v", err)
+	}
+	if got := roles(fitted); got != "system,user,system,assistant,tool" {
+		t.Fatalf("fitted roles = %s", got)
+	}
+	checkToolResults(t, fitted)
+	if fitted[3] != messages[6] || fitted[4] != messages[7] {
+		t.Error("the last tool call and its result were not kept")
+	}
+	if truncations := b.Truncations(); len(truncations) != 1 || truncations[0].Section != "messages" || truncations[0].Total != len(messages) {
+		t.Errorf("truncations = %+v", truncations)
+	}
+}
+
+func TestFitMessagesSummarizesCutToolBlock(t *testing.T) {
+	call := &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{
+		{ID: "call_a", Name: "get_weather"},
+		{ID: "call_b", Name: "get_weather"},
+	}}
+	messages := []*mock.Message{
+		{Role: "system", Content: "你是助手"},
+		{Role: "user", Content: "q"},
+		call,
+		toolResult("call_a", 300),
+		toolResult("call_b", 300),
+		{Role: "assistant", Content: strings.Repeat("好", 300)},
+	}
+	// Room for the answer and the second result, not for the first one
+	b := NewBudget(1800, 900)
+
+	fitted, err := b.FitMessages(context.Background(), messages, ExcerptSummarizer(10))
+	if err != nil {
+		t.Fatalf("FitMessages: %v", err)
+	}
+	if got := roles(fitted); got != "system,user,system,assistant" {
+		t.Fatalf("fitted roles = %s", got)
+	}
+	checkToolResults(t, fitted)
+	summary := fitted[2].Content
+	if !strings.Contains(summary, "assistant: called get_weather, get_weather") || strings.Count(summary, "- tool:") != 2 {
+		t.Errorf("summary misses the tool block:\n%s", summary)
+	}
+}
+
+func TestBudgetForThroughDecorators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			fmt.Fprint(w, `{"version": "0.6.2"}`)
+		case "/api/show":
+			fmt.Fprint(w, `{"model_info": {"general.architecture": "qwen2", "qwen2.context_length": 32768}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	model := ollama.NewChatModel(server.URL, "qwen2.5:7b", ollama.WithDefaultOptions(mock.Options{NumPredict: 512}))
+	store, err := cache.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	decorated := cache.NewChatModel(cassette.NewRecorder(model, filepath.Join(t.TempDir(), "cassette.json")), store)
+
+	// The context length is unknown until the preflight
+	if b := BudgetFor(ctx, decorated, mock.Options{}); b.ContextLength() != DefaultContextLength || b.Available() != DefaultContextLength-512 {
+		t.Errorf("budget before preflight = %d, %d available", b.ContextLength(), b.Available())
+	}
+	if _, err := model.Preflight(ctx, false, nil); err != nil {
+		t.Fatalf("Preflight: %v", err)
+	}
+	if b := BudgetFor(ctx, decorated, mock.Options{}); b.ContextLength() != 32768 || b.Available() != 32768-512 {
+		t.Errorf("budget after preflight = %d, %d available", b.ContextLength(), b.Available())
+	}
+
+	// NumCtx takes precedence, from the model defaults or the call
+	withNumCtx := ollama.NewChatModel(server.URL, "qwen2.5:7b", ollama.WithDefaultOptions(mock.Options{NumCtx: 8192}))
+	if b := BudgetFor(ctx, cache.NewChatModel(withNumCtx, store), mock.Options{}); b.ContextLength() != 8192 {
+		t.Errorf("budget with the default NumCtx = %d, want 8192", b.ContextLength())
+	}
+	if b := BudgetFor(mock.WithOptions(ctx, mock.Options{NumCtx: 16384}), decorated, mock.Options{}); b.ContextLength() != 16384 {
+		t.Errorf("budget with NumCtx in the context = %d, want 16384", b.ContextLength())
+	}
+}
@@ -14,6 +14,7 @@ import (
 type CoordinatorAgent struct {
 	*agent.BaseAgent
 	plannerAgent *planner.PlannerAgent
+	structured   bool
 }
 
 // NewCoordinatorAgent creates a new coordinator agent
@@ -24,6 +25,12 @@ func NewCoordinatorAgent(model mock.ChatModel, tools []mock.Tool, dataQuery *dat
 	}
 }
 
+// SetStructuredOutput switches Process between a prose answer and a
+// data.TripPlan encoded as JSON
+func (c *CoordinatorAgent) SetStructuredOutput(enabled bool) {
+	c.structured = enabled
+}
+
 // Process processes the trip planning request
 func (c *CoordinatorAgent) Process(ctx context.Context, input interface{}) (interface{}, error) {
 	request, ok := input.(*data.TripPlanRequest)
@@ -31,6 +38,18 @@ func (c *CoordinatorAgent) Process(ctx context.Context, input interface{}) (inte
 		return nil, fmt.Errorf("invalid input type for coordinator agent")
 	}
 
+	if c.structured {
+		plan, err := c.PlanTrip(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		content, err := c.formatTripPlan(plan)
+		if err != nil {
+			return nil, err
+		}
+		return &mock.Message{Role: "assistant", Content: content}, nil
+	}
+
 	// Validate request
 	if err := c.validateRequest(request); err != nil {
 		return nil, fmt.Errorf("invalid request: %v", err)
@@ -79,6 +98,46 @@ func (c *CoordinatorAgent) Process(ctx context.Context, input interface{}) (inte
 	return result, nil
 }
 
+// PlanTrip creates, reviews and refines the trip plan as a data.TripPlan
+func (c *CoordinatorAgent) PlanTrip(ctx context.Context, request *data.TripPlanRequest) (*data.TripPlan, error) {
+	if err := c.validateRequest(request); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+
+	draft, err := c.plannerAgent.Plan(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trip plan: %v", err)
+	}
+	draftJSON, err := c.formatTripPlan(draft)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := c.BuildPrompt(
+		"Trip Plan Reviewer",
+		"review and refine the trip plan to ensure it meets all requirements and constraints",
+	)
+	messages := []*mock.Message{
+		{
+			Role:    "system",
+			Content: systemPrompt + "\n\n" + planner.StructuredInstructions,
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("Please review and refine the following trip plan and return the complete refined plan:\n\n%s",
+				draftJSON,
+			),
+		},
+	}
+
+	plan := &data.TripPlan{}
+	if err := c.GenerateStructured(ctx, messages, planner.TripPlanSchema(), plan); err != nil {
+		return nil, fmt.Errorf("failed to refine trip plan: %v", err)
+	}
+	plan.Request = *request
+	return plan, nil
+}
+
 // validateRequest validates the trip planning request
 func (c *CoordinatorAgent) validateRequest(request *data.TripPlanRequest) error {
 	// Check dates
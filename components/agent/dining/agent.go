@@ -27,31 +27,17 @@ func (d *DiningAgent) Process(ctx context.Context, input interface{}) (interface
 		return nil, fmt.Errorf("invalid input type for dining agent")
 	}
 
-	// Load restaurants data
-	restaurants, err := d.DataQuery.Loader.LoadRestaurants()
+	// Find the restaurants within 3km serving the preferred cuisines, best
+	// rated first so truncation drops the least appealing
+	query, err := d.DataQuery.Restaurants()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load restaurants: %v", err)
 	}
-
-	// Find nearby restaurants
-	nearbyRestaurants := data.FindNearbyRestaurants(restaurants, request.Location, 3.0) // Within 3km
-
-	// Filter by cuisine preferences
-	filteredRestaurants := d.DataQuery.FilterRestaurantsByPreferences(
-		nearbyRestaurants,
-		request.Preferences.Cuisine,
-	)
-
-	// Sort restaurants by rating
-	sortedRestaurants := make([]data.Restaurant, len(filteredRestaurants))
-	copy(sortedRestaurants, filteredRestaurants)
-	for i := 0; i < len(sortedRestaurants)-1; i++ {
-		for j := i + 1; j < len(sortedRestaurants); j++ {
-			if sortedRestaurants[j].Rating > sortedRestaurants[i].Rating {
-				sortedRestaurants[i], sortedRestaurants[j] = sortedRestaurants[j], sortedRestaurants[i]
-			}
-		}
-	}
+	restaurants := query.
+		Near(request.Location, 3.0).
+		Category(request.Preferences.Cuisine...).
+		SortBy(data.Desc(data.SortByRating), data.Asc(data.SortByDistance)).
+		Run()
 
 	// Use LLM to analyze and recommend restaurants
 	systemPrompt := d.BuildPrompt(
@@ -59,6 +45,25 @@ func (d *DiningAgent) Process(ctx context.Context, input interface{}) (interface
 		"analyze restaurant options and provide personalized dining recommendations based on user preferences and requirements",
 	)
 
+	criteria := fmt.Sprintf("Please recommend restaurants based on the following criteria:\n"+
+		"Daily food budget: %.2f\n"+
+		"Preferred cuisines: %v\n"+
+		"Number of people: %d\n"+
+		"Special requirements: %v\n\n",
+		request.Budget.Food,
+		request.Preferences.Cuisine,
+		request.PartySize,
+		request.Requirements,
+	)
+
+	// Give the restaurant list half of the remaining budget, leaving room
+	// for tool calls and their results
+	budget := d.Budget(ctx)
+	restaurantList := agent.FitList(ctx, budget, "restaurants", restaurants.Hits, budget.Remaining(systemPrompt, criteria)/2,
+		func(r data.Nearby[data.Restaurant]) string {
+			return fmt.Sprintf("%+v (%s away)", r.Item, r.DistanceText())
+		})
+
 	agent, err := d.CreateReactAgent(ctx, systemPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %v", err)
@@ -71,19 +76,8 @@ func (d *DiningAgent) Process(ctx context.Context, input interface{}) (interface
 			Content: systemPrompt,
 		},
 		{
-			Role: "user",
-			Content: fmt.Sprintf("Please recommend restaurants based on the following criteria:\n"+
-				"Daily food budget: %.2f\n"+
-				"Preferred cuisines: %v\n"+
-				"Number of people: %d\n"+
-				"Special requirements: %v\n\n"+
-				"Available restaurants: %+v",
-				request.Budget.Food,
-				request.Preferences.Cuisine,
-				request.PartySize,
-				request.Requirements,
-				sortedRestaurants,
-			),
+			Role:    "user",
+			Content: criteria + "Available restaurants:\n" + restaurantList,
 		},
 	}
 
@@ -22,12 +22,17 @@ type PlannerAgent struct {
 
 // NewPlannerAgent creates a new planner agent
 func NewPlannerAgent(model mock.ChatModel, tools []mock.Tool, dataQuery *data.DataQuery) *PlannerAgent {
-	return &PlannerAgent{
+	p := &PlannerAgent{
 		BaseAgent:          agent.NewBaseAgent("planner", model, tools, dataQuery),
 		weatherAgent:       weather.NewWeatherAgent(model, tools, dataQuery),
 		accommodationAgent: accommodation.NewAccommodationAgent(model, tools, dataQuery),
 		diningAgent:        dining.NewDiningAgent(model, tools, dataQuery),
 	}
+	// Itineraries benefit from some variety
+	p.SetOptions(mock.Options{
+		Temperature: mock.Float64(0.8),
+	})
+	return p
 }
 
 // Process processes the trip planning request
@@ -37,109 +42,174 @@ func (p *PlannerAgent) Process(ctx context.Context, input interface{}) (interfac
 		return nil, fmt.Errorf("invalid input type for planner agent")
 	}
 
-	// Get weather recommendations
-	weatherResult, err := p.weatherAgent.Process(ctx, request)
+	// Use LLM to create the final trip plan
+	systemPrompt := p.BuildPrompt(
+		"Trip Planning Specialist",
+		"create a comprehensive trip plan that combines weather conditions, accommodations, dining options, and attractions",
+	)
+
+	briefing, err := p.gatherBriefing(ctx, request, systemPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get weather recommendations: %v", err)
+		return nil, err
 	}
 
-	// Get accommodation recommendations
-	accommodationResult, err := p.accommodationAgent.Process(ctx, request)
+	agent, err := p.CreateReactAgent(ctx, systemPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get accommodation recommendations: %v", err)
+		return nil, fmt.Errorf("failed to create agent: %v", err)
 	}
 
-	// Get dining recommendations
-	diningResult, err := p.diningAgent.Process(ctx, request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get dining recommendations: %v", err)
+	// Prepare input for LLM
+	messages := []*mock.Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+		{
+			Role:    "user",
+			Content: briefing,
+		},
 	}
 
-	// Load attractions data
-	attractions, err := p.DataQuery.Loader.LoadAttractions()
+	// Get the final trip plan from LLM
+	result, err := agent.Invoke(ctx, messages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load attractions: %v", err)
+		return nil, fmt.Errorf("failed to create trip plan: %v", err)
 	}
 
-	// Filter attractions by preferences and budget
-	nearbyAttractions := data.FindNearbyAttractions(attractions, request.Location, 10.0) // Within 10km
-	filteredAttractions := p.DataQuery.FilterAttractionsByPreferences(
-		nearbyAttractions,
-		request.Preferences.Activities,
-	)
-	budgetedAttractions := p.DataQuery.FilterByBudget(
-		filteredAttractions,
-		request.Budget.Activity,
-	)
+	return result, nil
+}
 
-	// Use LLM to create the final trip plan
+// Plan creates the trip plan as a data.TripPlan. The model is constrained to
+// the TripPlan schema and its reply is decoded, and retried when it does not
+// decode, instead of being returned as prose.
+func (p *PlannerAgent) Plan(ctx context.Context, request *data.TripPlanRequest) (*data.TripPlan, error) {
 	systemPrompt := p.BuildPrompt(
 		"Trip Planning Specialist",
 		"create a comprehensive trip plan that combines weather conditions, accommodations, dining options, and attractions",
-	)
+	) + "\n\n" + StructuredInstructions
 
-	agent, err := p.CreateReactAgent(ctx, systemPrompt)
+	briefing, err := p.gatherBriefing(ctx, request, systemPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create agent: %v", err)
+		return nil, err
 	}
 
-	// Calculate trip duration
-	duration := int(request.EndDate.Sub(request.StartDate).Hours() / 24)
-
-	// Prepare input for LLM
 	messages := []*mock.Message{
 		{
 			Role:    "system",
 			Content: systemPrompt,
 		},
 		{
-			Role: "user",
-			Content: fmt.Sprintf("Please create a comprehensive trip plan for %d days based on the following information:\n"+
-				"Trip dates: %s to %s\n"+
-				"Location: %s\n"+
-				"Party size: %d\n"+
-				"Budget:\n"+
-				"  - Total: %.2f\n"+
-				"  - Hotel per night: %.2f\n"+
-				"  - Food per day: %.2f\n"+
-				"  - Activities per day: %.2f\n"+
-				"Preferences:\n"+
-				"  - Activities: %v\n"+
-				"  - Cuisine: %v\n"+
-				"  - Hotel: %v\n"+
-				"Special requirements: %v\n\n"+
-				"Weather recommendations: %s\n"+
-				"Accommodation recommendations: %s\n"+
-				"Dining recommendations: %s\n"+
-				"Available attractions: %+v",
-				duration,
-				request.StartDate.Format("2006-01-02"),
-				request.EndDate.Format("2006-01-02"),
-				request.Location.Name,
-				request.PartySize,
-				request.Budget.Total,
-				request.Budget.Hotel,
-				request.Budget.Food,
-				request.Budget.Activity,
-				request.Preferences.Activities,
-				request.Preferences.Cuisine,
-				request.Preferences.Hotel,
-				request.Requirements,
-				weatherResult.(*mock.Message).Content,
-				accommodationResult.(*mock.Message).Content,
-				diningResult.(*mock.Message).Content,
-				budgetedAttractions,
-			),
+			Role:    "user",
+			Content: briefing,
 		},
 	}
 
-	// Get the final trip plan from LLM
-	result, err := agent.Invoke(ctx, messages)
-	if err != nil {
+	plan := &data.TripPlan{}
+	if err := p.GenerateStructured(ctx, messages, TripPlanSchema(), plan); err != nil {
 		return nil, fmt.Errorf("failed to create trip plan: %v", err)
 	}
+	plan.Request = *request
+	return plan, nil
+}
 
-	return result, nil
+// StructuredInstructions tells the model how to answer in structured mode
+const StructuredInstructions = "Answer with a single JSON object describing the trip plan and nothing else. " +
+	"Write dates and times in RFC 3339 format, e.g. 2025-04-01T09:00:00+08:00, " +
+	"use one entry in daily_plans per day and give all costs in CNY."
+
+// TripPlanSchema returns the JSON schema of the trip plan the model fills in.
+// The request is echoed by the caller, so it is not part of the schema.
+func TripPlanSchema() *mock.Schema {
+	return mock.SchemaOf(&data.TripPlan{}).Without("request")
+}
+
+// gatherBriefing collects the recommendations of the specialist agents and
+// the matching attractions into the prompt used to plan the trip. The
+// attraction list is cut to fit the budget left by systemPrompt.
+func (p *PlannerAgent) gatherBriefing(ctx context.Context, request *data.TripPlanRequest, systemPrompt string) (string, error) {
+	// Get weather recommendations
+	weatherResult, err := p.weatherAgent.Process(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to get weather recommendations: %v", err)
+	}
+
+	// Get accommodation recommendations
+	accommodationResult, err := p.accommodationAgent.Process(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to get accommodation recommendations: %v", err)
+	}
+
+	// Get dining recommendations
+	diningResult, err := p.diningAgent.Process(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dining recommendations: %v", err)
+	}
+
+	// Find the attractions within 10km matching the activity preferences, by
+	// category or tag, and the activity budget. Best rated first, so
+	// truncation drops the least appealing.
+	query, err := p.DataQuery.Attractions()
+	if err != nil {
+		return "", fmt.Errorf("failed to load attractions: %v", err)
+	}
+	fields := query.Fields()
+	attractions := query.
+		Near(request.Location, 10.0).
+		Where(data.Or(
+			fields.HasCategory(request.Preferences.Activities...),
+			fields.HasTag(request.Preferences.Activities...),
+		)).
+		MaxPrice(request.Budget.Activity).
+		SortBy(data.Desc(data.SortByRating), data.Asc(data.SortByDistance)).
+		Run()
+
+	// Calculate trip duration
+	duration := int(request.EndDate.Sub(request.StartDate).Hours() / 24)
+
+	briefing := fmt.Sprintf("Please create a comprehensive trip plan for %d days based on the following information:\n"+
+		"Trip dates: %s to %s\n"+
+		"Location: %s\n"+
+		"Party size: %d\n"+
+		"Budget:\n"+
+		"  - Total: %.2f\n"+
+		"  - Hotel per night: %.2f\n"+
+		"  - Food per day: %.2f\n"+
+		"  - Activities per day: %.2f\n"+
+		"Preferences:\n"+
+		"  - Activities: %v\n"+
+		"  - Cuisine: %v\n"+
+		"  - Hotel: %v\n"+
+		"Special requirements: %v\n\n"+
+		"Weather recommendations: %s\n"+
+		"Accommodation recommendations: %s\n"+
+		"Dining recommendations: %s\n",
+		duration,
+		request.StartDate.Format("2006-01-02"),
+		request.EndDate.Format("2006-01-02"),
+		request.Location.Name,
+		request.PartySize,
+		request.Budget.Total,
+		request.Budget.Hotel,
+		request.Budget.Food,
+		request.Budget.Activity,
+		request.Preferences.Activities,
+		request.Preferences.Cuisine,
+		request.Preferences.Hotel,
+		request.Requirements,
+		weatherResult.(*mock.Message).Content,
+		accommodationResult.(*mock.Message).Content,
+		diningResult.(*mock.Message).Content,
+	)
+
+	// Give the attraction list half of the remaining budget, leaving room
+	// for tool calls and their results
+	budget := p.Budget(ctx)
+	attractionList := agent.FitList(ctx, budget, "attractions", attractions.Hits, budget.Remaining(systemPrompt, briefing)/2,
+		func(a data.Nearby[data.Attraction]) string {
+			return fmt.Sprintf("%+v (%s away)", a.Item, a.DistanceText())
+		})
+
+	return briefing + "Available attractions:\n" + attractionList, nil
 }
 
 // createDailySchedule creates a schedule for a single day
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxSteps is the default number of model calls a ReAct agent may make
+const DefaultMaxSteps = 6
+
+// defaultExcerptChars is the excerpt length used to summarize messages that
+// no longer fit the budget
+const defaultExcerptChars = 200
+
+// ErrMaxStepsExceeded is returned when the model keeps requesting tools after the step limit
+var ErrMaxStepsExceeded = errors.New("react agent exceeded the maximum number of steps")
+
+// ReactOption configures a ReactAgent
+type ReactOption func(*ReactAgent)
+
+// WithMaxSteps limits the number of model calls made by the agent
+func WithMaxSteps(steps int) ReactOption {
+	return func(r *ReactAgent) {
+		if steps > 0 {
+			r.maxSteps = steps
+		}
+	}
+}
+
+// WithOptions sets the generation options used for every model call. Options
+// already carried by the call context take precedence.
+func WithOptions(opts mock.Options) ReactOption {
+	return func(r *ReactAgent) {
+		r.options = opts
+	}
+}
+
+// WithBudget fits the conversation into budget before every model call,
+// replacing the earliest messages with their summary when it grows too long
+func WithBudget(budget *Budget, summarize Summarizer) ReactOption {
+	return func(r *ReactAgent) {
+		r.budget = budget
+		r.summarize = summarize
+	}
+}
+
+// ReactAgent runs a reason–act loop: it calls the model, executes the tools
+// the model requests, feeds the results back and repeats until the model
+// produces a final answer.
+type ReactAgent struct {
+	model         mock.ChatModel
+	tools         map[string]mock.Tool
+	toolList      []mock.Tool
+	systemPrompt  string
+	maxSteps      int
+	options       mock.Options
+	streamHandler StreamHandler
+	budget        *Budget
+	summarize     Summarizer
+}
+
+// NewReactAgent creates a ReAct agent on top of the given model and tools
+func NewReactAgent(model mock.ChatModel, tools []mock.Tool, systemPrompt string, opts ...ReactOption) *ReactAgent {
+	r := &ReactAgent{
+		model:        model,
+		tools:        make(map[string]mock.Tool, len(tools)),
+		toolList:     tools,
+		systemPrompt: systemPrompt,
+		maxSteps:     DefaultMaxSteps,
+	}
+	for _, tool := range tools {
+		r.tools[tool.Name()] = tool
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Invoke runs the loop and returns the final answer
+func (r *ReactAgent) Invoke(ctx context.Context, input []*mock.Message) (*mock.Message, error) {
+	transcript, err := r.Run(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return transcript[len(transcript)-1], nil
+}
+
+// Run runs the loop and returns the full transcript, including the input
+// messages, every assistant turn and every tool result. The last message is
+// the final answer. When the step limit is reached the transcript so far is
+// returned together with ErrMaxStepsExceeded.
+func (r *ReactAgent) Run(ctx context.Context, input []*mock.Message) ([]*mock.Message, error) {
+	ctx = mock.WithDefaultOptions(ctx, r.options)
+
+	transcript := make([]*mock.Message, 0, len(input)+1)
+	if r.systemPrompt != "" && (len(input) == 0 || input[0].Role != "system") {
+		transcript = append(transcript, &mock.Message{Role: "system", Content: r.systemPrompt})
+	}
+	transcript = append(transcript, input...)
+
+	// An agent without tools must not offer those another agent bound to a
+	// shared model, so its calls go out without any
+	if len(r.toolList) == 0 {
+		ctx = mock.WithoutTools(ctx)
+	} else if err := r.model.BindTools(r.toolList); err != nil {
+		return nil, fmt.Errorf("failed to bind tools: %v", err)
+	}
+
+	for step := 0; step < r.maxSteps; step++ {
+		prompt, err := r.fit(ctx, transcript)
+		if err != nil {
+			return transcript, err
+		}
+		reply, err := r.generate(ctx, prompt)
+		if err != nil {
+			return transcript, err
+		}
+		transcript = append(transcript, reply)
+
+		if len(reply.ToolCalls) == 0 {
+			return transcript, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			transcript = append(transcript, r.executeTool(ctx, call))
+		}
+	}
+
+	return transcript, ErrMaxStepsExceeded
+}
+
+// fit returns the messages sent to the model for the transcript. The
+// transcript itself is kept whole.
+func (r *ReactAgent) fit(ctx context.Context, transcript []*mock.Message) ([]*mock.Message, error) {
+	if r.budget == nil {
+		return transcript, nil
+	}
+	summarize := r.summarize
+	if summarize == nil {
+		summarize = ExcerptSummarizer(defaultExcerptChars)
+	}
+	return r.budget.FitMessages(ctx, transcript, summarize)
+}
+
+// generate makes a single model call, streaming it when a handler is set
+func (r *ReactAgent) generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	streamer, ok := r.model.(mock.StreamingChatModel)
+	if !ok || r.streamHandler == nil {
+		return r.model.Generate(ctx, messages)
+	}
+
+	stream, err := streamer.Stream(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return mock.CollectStream(&observedStream{MessageStream: stream, handler: r.streamHandler})
+}
+
+// executeTool runs a single tool call and wraps the outcome in a tool message.
+// Failures are reported to the model instead of aborting the loop.
+func (r *ReactAgent) executeTool(ctx context.Context, call mock.ToolCall) *mock.Message {
+	var result map[string]interface{}
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		result = map[string]interface{}{"error": fmt.Sprintf("unknown tool: %s", call.Name)}
+	} else if out, err := tool.Execute(ctx, call.Args); err != nil {
+		result = map[string]interface{}{"error": err.Error()}
+	} else {
+		result = out
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		content = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+
+	return &mock.Message{
+		Role:       "tool",
+		Name:       call.Name,
+		ToolCallID: call.ID,
+		Content:    string(content),
+		ToolResult: result,
+	}
+}
+
+// observedStream forwards every received chunk to a StreamHandler
+type observedStream struct {
+	mock.MessageStream
+	handler StreamHandler
+}
+
+// Recv receives the next chunk and passes it to the handler
+func (s *observedStream) Recv() (*mock.Message, error) {
+	chunk, err := s.MessageStream.Recv()
+	if err == nil {
+		s.handler(chunk)
+	}
+	return chunk, err
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// weatherTool returns a tool reporting sunny weather for any city and the
+// cities it was asked about
+func weatherTool() (*mock.MockTool, *[]string) {
+	var cities []string
+	tool := mock.NewMockTool("get_weather", "查询天气", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		city, _ := args["city"].(string)
+		cities = append(cities, city)
+		return map[string]interface{}{"city": city, "condition": "晴"}, nil
+	})
+	return tool, &cities
+}
+
+func TestReactAgentRunsToolCalls(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().WhenRole("user").ReplyToolCalls(mock.ToolCall{Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}})
+	fake.On().WhenToolResult("get_weather").ReplyText("杭州明天晴，适合游西湖")
+	tool, cities := weatherTool()
+
+	var streamed strings.Builder
+	react := NewReactAgent(fake, []mock.Tool{tool}, "你是杭州旅游助手", WithOptions(mock.Options{Temperature: mock.Float64(0.2)}))
+	react.streamHandler = func(chunk *mock.Message) { streamed.WriteString(chunk.Content) }
+
+	transcript, err := react.Run(context.Background(), []*mock.Message{{Role: "user", Content: "明天杭州天气如何？"}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	roles := make([]string, len(transcript))
+	for i, msg := range transcript {
+		roles[i] = msg.Role
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,tool,assistant" {
+		t.Fatalf("transcript roles = %s", got)
+	}
+	if len(*cities) != 1 || (*cities)[0] != "杭州" {
+		t.Errorf("tool called for %v, want [杭州]", *cities)
+	}
+
+	result := transcript[3]
+	if result.Name != "get_weather" || result.ToolCallID != "call_0" || result.ToolResult["condition"] != "晴" {
+		t.Errorf("tool result = %+v", result)
+	}
+	if answer := transcript[4].Content; answer != "杭州明天晴，适合游西湖" {
+		t.Errorf("answer = %q", answer)
+	}
+	if streamed.String() != "杭州明天晴，适合游西湖" {
+		t.Errorf("streamed %q", streamed.String())
+	}
+
+	// The model saw the tools and the options on both steps, and the
+	// second step carried the tool result
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("got %d model calls, want 2", len(calls))
+	}
+	for i, call := range calls {
+		if len(call.Tools) != 1 || call.Tools[0] != "get_weather" {
+			t.Errorf("call %d offered tools %v", i, call.Tools)
+		}
+		if call.Options.Temperature == nil || *call.Options.Temperature != 0.2 {
+			t.Errorf("call %d options = %+v", i, call.Options)
+		}
+	}
+	if len(calls[1].Messages) != 4 || calls[1].LastMessage().Role != "tool" {
+		t.Errorf("second call messages = %+v", calls[1].Messages)
+	}
+}
+
+func TestReactAgentReportsToolErrors(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().WhenRole("user").ReplyToolCalls(mock.ToolCall{Name: "book_hotel"})
+	fake.On().WhenToolResult("book_hotel").ReplyText("暂时无法预订")
+
+	answer, err := NewReactAgent(fake, nil, "").Invoke(context.Background(), []*mock.Message{{Role: "user", Content: "订酒店"}})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if answer.Content != "暂时无法预订" {
+		t.Errorf("answer = %q", answer.Content)
+	}
+	if result := fake.Calls()[1].LastMessage(); !strings.Contains(result.Content, "unknown tool: book_hotel") {
+		t.Errorf("tool result = %q, want an unknown tool error", result.Content)
+	}
+}
+
+func TestReactAgentStepLimit(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyToolCalls(mock.ToolCall{Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}})
+	tool, _ := weatherTool()
+
+	transcript, err := NewReactAgent(fake, []mock.Tool{tool}, "", WithMaxSteps(2)).Run(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}})
+	if !errors.Is(err, ErrMaxStepsExceeded) {
+		t.Fatalf("error = %v, want ErrMaxStepsExceeded", err)
+	}
+	if fake.CallCount() != 2 || len(transcript) != 5 {
+		t.Errorf("made %d calls with a transcript of %d messages, want 2 and 5", fake.CallCount(), len(transcript))
+	}
+}
+
+func TestReactAgentWithoutTools(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().WhenContent("天气").ReplyToolCalls(mock.ToolCall{Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}})
+	fake.On().ReplyText("好的")
+	tool, _ := weatherTool()
+
+	// Two agents share the model, only the first one has tools
+	weather := NewReactAgent(fake, []mock.Tool{tool}, "你是天气助手")
+	chat := NewReactAgent(fake, nil, "你是导游")
+	ctx := context.Background()
+	if _, err := weather.Invoke(ctx, []*mock.Message{{Role: "user", Content: "明天杭州天气如何？"}}); err != nil {
+		t.Fatalf("weather agent: %v", err)
+	}
+	if _, err := chat.Invoke(ctx, []*mock.Message{{Role: "user", Content: "介绍一下西湖"}}); err != nil {
+		t.Fatalf("agent without tools: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 3 || len(calls[0].Tools) != 1 {
+		t.Fatalf("calls = %+v, want 2 by the weather agent offering its tool and 1 without", calls)
+	}
+	if tools := calls[2].Tools; len(tools) != 0 {
+		t.Errorf("the agent without tools offered %v", tools)
+	}
+}
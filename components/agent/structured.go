@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultStructuredRetries is the number of times a structured reply is
+// requested again after it failed to decode
+const DefaultStructuredRetries = 2
+
+// Validator is implemented by structured output targets that check their
+// own content after decoding
+type Validator interface {
+	Validate() error
+}
+
+// GenerateStructured asks the model for a JSON reply matching schema and
+// decodes it into target. The schema is sent as the format option, the reply
+// is repaired with RepairJSON before decoding and, when decoding or
+// validation fails, the error is fed back to the model and the call is
+// retried up to retries times. The bound tools are not offered to the model
+// and stay bound.
+func GenerateStructured(ctx context.Context, model mock.ChatModel, messages []*mock.Message, schema *mock.Schema, target interface{}, retries int) error {
+	// Tools bound by earlier runs would let the model answer with a tool
+	// call. They are left out of these calls only: the model may be shared
+	// with agents running concurrently.
+	ctx = mock.WithoutTools(mock.WithOptions(ctx, mock.Options{Format: schema}))
+
+	conversation := append([]*mock.Message(nil), messages...)
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		reply, err := model.Generate(ctx, conversation)
+		if err != nil {
+			return err
+		}
+
+		lastErr = decodeStructured(reply.Content, target)
+		if lastErr == nil {
+			return nil
+		}
+
+		conversation = append(conversation,
+			&mock.Message{Role: "assistant", Content: reply.Content},
+			&mock.Message{
+				Role: "user",
+				Content: fmt.Sprintf("Your reply could not be used: %v\n"+
+					"Reply again with only a JSON document that matches the requested schema.", lastErr),
+			},
+		)
+	}
+	return fmt.Errorf("failed to decode structured output after %d attempts: %v", retries+1, lastErr)
+}
+
+// GenerateStructured asks the agent's model for a JSON reply matching schema
+// and decodes it into target, using the agent's generation options
+func (b *BaseAgent) GenerateStructured(ctx context.Context, messages []*mock.Message, schema *mock.Schema, target interface{}) error {
+	ctx = mock.WithDefaultOptions(ctx, b.options)
+	return GenerateStructured(ctx, b.model, messages, schema, target, DefaultStructuredRetries)
+}
+
+// decodeStructured repairs and decodes a reply, then validates the result
+func decodeStructured(content string, target interface{}) error {
+	repaired := RepairJSON(content)
+	if repaired == "" {
+		return fmt.Errorf("reply contains no JSON document")
+	}
+	if err := json.Unmarshal([]byte(repaired), target); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if v, ok := target.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("invalid content: %v", err)
+		}
+	}
+	return nil
+}
+
+// RepairJSON extracts the JSON document from a model reply. It removes
+// Markdown code fences and text around the outermost object or array, and
+// drops trailing commas before closing brackets.
+func RepairJSON(content string) string {
+	content = stripCodeFence(strings.TrimSpace(content))
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return ""
+	}
+	closer := "}"
+	if content[start] == '[' {
+		closer = "]"
+	}
+	end := strings.LastIndex(content, closer)
+	if end < start {
+		return ""
+	}
+
+	return removeTrailingCommas(content[start : end+1])
+}
+
+// stripCodeFence returns the body of the first ``` fenced block, if any
+func stripCodeFence(content string) string {
+	open := strings.Index(content, "```")
+	if open < 0 {
+		return content
+	}
+	body := content[open+3:]
+	// Skip the language tag, e.g. ```json
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:]
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return body
+}
+
+// removeTrailingCommas drops commas followed only by whitespace and a closing
+// bracket, leaving string literals untouched
+func removeTrailingCommas(s string) string {
+	var out strings.Builder
+	out.Grow(len(s))
+
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out.WriteByte(c)
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			next := strings.TrimLeft(s[i+1:], " \t\r\n")
+			if next != "" && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
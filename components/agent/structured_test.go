@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"testing"
+)
+
+func TestGenerateStructuredKeepsToolsBound(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().WhenContent("plan").Once().ReplyText("```json\n{\"days\": 2,}\n```")
+	fake.On().ReplyText("done")
+	tool := mock.NewMockTool("get_weather", "查询天气", nil)
+	if err := fake.BindTools([]mock.Tool{tool}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+
+	schema := &mock.Schema{Type: "object", Properties: map[string]*mock.Schema{"days": {Type: "integer"}}}
+	var plan struct {
+		Days int `json:"days"`
+	}
+	messages := []*mock.Message{{Role: "user", Content: "plan a trip"}}
+	if err := GenerateStructured(context.Background(), fake, messages, schema, &plan, 0); err != nil {
+		t.Fatalf("GenerateStructured: %v", err)
+	}
+	if plan.Days != 2 {
+		t.Errorf("days = %d, want 2", plan.Days)
+	}
+
+	// A later call on the shared model still offers the tools
+	if _, err := fake.Generate(context.Background(), []*mock.Message{{Role: "user", Content: "next"}}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if len(calls[0].Tools) != 0 {
+		t.Errorf("structured call offered tools %v", calls[0].Tools)
+	}
+	if calls[0].Options.Format != schema {
+		t.Errorf("structured call format = %v, want the schema", calls[0].Options.Format)
+	}
+	if len(calls[1].Tools) != 1 || calls[1].Tools[0] != "get_weather" {
+		t.Errorf("later call offered tools %v, want [get_weather]", calls[1].Tools)
+	}
+}
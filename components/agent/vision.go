@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"deepllm/internal/data"
+	"fmt"
+	"strings"
+)
+
+// MessageImages converts dataset images into message attachments
+func MessageImages(images []data.Image) []mock.Image {
+	out := make([]mock.Image, len(images))
+	for i, image := range images {
+		out[i] = mock.Image{Name: image.Name, MIMEType: image.MIMEType, Data: image.Data}
+	}
+	return out
+}
+
+// DescribeImages sends prompt together with the named dataset images to the
+// agent's model, which must be multimodal (e.g. llava or qwen2.5vl). The
+// bound tools are not offered to the model and stay bound.
+func (b *BaseAgent) DescribeImages(ctx context.Context, prompt string, imageNames []string) (*mock.Message, error) {
+	if len(imageNames) == 0 {
+		return nil, fmt.Errorf("no images to describe")
+	}
+	images, err := b.DataQuery.Loader.LoadImages(imageNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load images: %v", err)
+	}
+
+	// The model may be shared, so its bound tools are left out of this call
+	// rather than unbound
+	ctx = mock.WithoutTools(mock.WithDefaultOptions(ctx, b.options))
+	return b.model.Generate(ctx, []*mock.Message{
+		{
+			Role:    "user",
+			Content: prompt,
+			Images:  MessageImages(images),
+		},
+	})
+}
+
+// DescribeAttraction asks the model to describe an attraction from its images
+func (b *BaseAgent) DescribeAttraction(ctx context.Context, attraction data.Attraction) (*mock.Message, error) {
+	prompt := fmt.Sprintf("These photos show %s in Hangzhou (%s). "+
+		"Describe what a visitor can expect to see there, the atmosphere and the best time to visit.",
+		attraction.Name, attraction.Description)
+	return b.DescribeImages(ctx, prompt, attraction.Images)
+}
+
+// CompareHotels asks the model to compare hotels from their images. The
+// images are sent in the order of the hotels, which the prompt lists.
+func (b *BaseAgent) CompareHotels(ctx context.Context, hotels []data.Hotel) (*mock.Message, error) {
+	var listing strings.Builder
+	var names []string
+	for _, hotel := range hotels {
+		fmt.Fprintf(&listing, "- %s: %d images\n", hotel.Name, len(hotel.Images))
+		names = append(names, hotel.Images...)
+	}
+
+	prompt := "The attached photos show the following hotels, in this order:\n" + listing.String() +
+		"Compare the rooms, facilities and surroundings visible in the photos and say which hotel suits which kind of traveller."
+	return b.DescribeImages(ctx, prompt, names)
+}
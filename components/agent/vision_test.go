@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"deepllm/internal/data"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDescribeImagesKeepsToolsBound(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A minimal PNG header is enough for the MIME type
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := os.WriteFile(filepath.Join(dir, "images", "west_lake_1.png"), png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText("湖光山色")
+	tool := mock.NewMockTool("get_weather", "查询天气", nil)
+	if err := fake.BindTools([]mock.Tool{tool}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	base := NewBaseAgent("vision", fake, nil, data.NewDataQuery(data.NewDataLoader(dir)))
+
+	reply, err := base.DescribeImages(context.Background(), "describe", []string{"west_lake_1.png"})
+	if err != nil {
+		t.Fatalf("DescribeImages: %v", err)
+	}
+	if reply.Content != "湖光山色" {
+		t.Errorf("reply = %q", reply.Content)
+	}
+	// A later call on the shared model still offers the tools
+	if _, err := fake.Generate(context.Background(), []*mock.Message{{Role: "user", Content: "next"}}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if len(calls[0].Tools) != 0 {
+		t.Errorf("image call offered tools %v", calls[0].Tools)
+	}
+	if images := calls[0].LastMessage().Images; len(images) != 1 || images[0].MIMEType != "image/png" {
+		t.Errorf("image call attachments = %+v", images)
+	}
+	if len(calls[1].Tools) != 1 {
+		t.Errorf("later call offered tools %v, want [get_weather]", calls[1].Tools)
+	}
+}
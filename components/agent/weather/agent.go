@@ -5,7 +5,9 @@ import (
 	"deepllm/components/agent"
 	"deepllm/components/mock"
 	"deepllm/internal/data"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 )
 
@@ -16,9 +18,15 @@ type WeatherAgent struct {
 
 // NewWeatherAgent creates a new weather agent
 func NewWeatherAgent(model mock.ChatModel, tools []mock.Tool, dataQuery *data.DataQuery) *WeatherAgent {
-	return &WeatherAgent{
+	a := &WeatherAgent{
 		BaseAgent: agent.NewBaseAgent("weather", model, tools, dataQuery),
 	}
+	// Weather advice should be factual and reproducible
+	a.SetOptions(mock.Options{
+		Temperature: mock.Float64(0),
+		Seed:        mock.Int(42),
+	})
+	return a
 }
 
 // Process processes the weather request
@@ -29,17 +37,38 @@ func (w *WeatherAgent) Process(ctx context.Context, input interface{}) (interfac
 	}
 
 	// Load weather data
-	weatherData, err := w.DataQuery.Loader.LoadWeather()
+	weatherData, err := w.DataQuery.LoadWeather()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load weather data: %v", err)
 	}
 
-	// Get weather forecasts for each day of the trip
-	var forecasts []data.Weather
+	// The detailed city forecast is optional; it adds air quality, travel
+	// suggestions and notices, and fills in days missing from weatherData
+	forecast, err := w.DataQuery.LoadForecast()
+	if errors.Is(err, os.ErrNotExist) {
+		forecast = &data.Forecast{}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load weather forecast: %v", err)
+	}
+
+	// Get the weather of each day of the trip
+	var days []tripDay
+	var detailedDays int
 	for date := request.StartDate; !date.After(request.EndDate); date = date.Add(24 * time.Hour) {
+		detail, detailed := forecast.ForDate(date)
+		day := tripDay{}
 		if weather, found := w.DataQuery.GetWeatherForDate(weatherData, date, request.Location); found {
-			forecasts = append(forecasts, weather)
+			day.weather = weather
+		} else if detailed {
+			day.weather = detail.ToWeather(request.Location)
+		} else {
+			continue
 		}
+		if detailed {
+			day.forecast = &detail
+			detailedDays++
+		}
+		days = append(days, day)
 	}
 
 	// Use LLM to analyze weather and provide recommendations
@@ -48,30 +77,48 @@ func (w *WeatherAgent) Process(ctx context.Context, input interface{}) (interfac
 		"analyze weather conditions and provide recommendations for activities and necessary preparations",
 	)
 
+	criteria := fmt.Sprintf("Please analyze the weather conditions and provide recommendations for the trip:\n"+
+		"Trip dates: %s to %s\n"+
+		"Location: %s\n"+
+		"Planned activities: %v\n\n",
+		request.StartDate.Format("2006-01-02"),
+		request.EndDate.Format("2006-01-02"),
+		request.Location.Name,
+		request.Preferences.Activities,
+	)
+
+	// Give the forecasts half of the remaining budget and the notices half
+	// of what is left, leaving room for tool calls and their results
+	budget := w.Budget(ctx)
+	forecastList := agent.FitList(ctx, budget, "forecast days", days, budget.Remaining(systemPrompt, criteria)/2, formatDay)
+	// The notices concern the forecast period only
+	var notices []data.Notice
+	if detailedDays > 0 {
+		notices = forecast.SpecialNotices
+	}
+	noticeList := agent.FitList(ctx, budget, "notices", notices, budget.Remaining(systemPrompt, criteria, forecastList)/2,
+		func(n data.Notice) string {
+			return fmt.Sprintf("%s: %s", n.Type, n.Content)
+		})
+
 	agent, err := w.CreateReactAgent(ctx, systemPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %v", err)
 	}
 
 	// Prepare input for LLM
+	content := criteria + "Weather forecasts:\n" + forecastList
+	if noticeList != "" {
+		content += "\nNotices:\n" + noticeList
+	}
 	messages := []*mock.Message{
 		{
 			Role:    "system",
 			Content: systemPrompt,
 		},
 		{
-			Role: "user",
-			Content: fmt.Sprintf("Please analyze the weather conditions and provide recommendations for the trip:\n"+
-				"Trip dates: %s to %s\n"+
-				"Location: %s\n"+
-				"Planned activities: %v\n"+
-				"Weather forecasts:\n%+v",
-				request.StartDate.Format("2006-01-02"),
-				request.EndDate.Format("2006-01-02"),
-				request.Location.Name,
-				request.Preferences.Activities,
-				forecasts,
-			),
+			Role:    "user",
+			Content: content,
 		},
 	}
 
@@ -84,6 +131,28 @@ func (w *WeatherAgent) Process(ctx context.Context, input interface{}) (interfac
 	return result, nil
 }
 
+// tripDay is the weather of one day of a trip, with the detailed city
+// forecast when there is one
+type tripDay struct {
+	weather  data.Weather
+	forecast *data.DailyForecast
+}
+
+// formatDay describes the weather of a day on one line
+func formatDay(day tripDay) string {
+	w := day.weather
+	line := fmt.Sprintf("%s: %s, %.0f-%.0f°C, humidity %.0f%%, wind %.0f km/h, precipitation %.1f mm",
+		w.Date.Format("2006-01-02"), w.Condition, w.Temperature.Min, w.Temperature.Max,
+		w.Humidity, w.WindSpeed, w.Precipitation)
+	if f := day.forecast; f != nil {
+		line += fmt.Sprintf("; 白天%s，夜间%s，降水概率%g%%，%s，空气质量%s（AQI %d），旅游%s：%s",
+			f.Weather.Day, f.Weather.Night, f.Precipitation.Probability,
+			f.Wind.Direction, f.AirQuality.Level, f.AirQuality.AQI,
+			f.Suggestion.Tourism, f.Suggestion.Notes)
+	}
+	return line
+}
+
 // GetWeatherSuitability determines if the weather is suitable for outdoor activities
 func (w *WeatherAgent) GetWeatherSuitability(weather data.Weather) (bool, string) {
 	// Check for severe weather conditions
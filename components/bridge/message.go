@@ -0,0 +1,174 @@
+package bridge
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// reasoningExtraKey is the schema.Message.Extra key holding a reasoning trace
+const reasoningExtraKey = "reasoning_content"
+
+// ToEinoMessage converts a mock.Message into an eino schema.Message. Images
+// are sent as MultiContent image parts holding data URLs.
+func ToEinoMessage(msg *mock.Message) *schema.Message {
+	out := &schema.Message{
+		Role:       schema.RoleType(msg.Role),
+		Content:    msg.Content,
+		Name:       msg.Name,
+		ToolCallID: msg.ToolCallID,
+	}
+
+	if len(msg.Images) > 0 {
+		out.MultiContent = append(out.MultiContent, schema.ChatMessagePart{
+			Type: schema.ChatMessagePartTypeText,
+			Text: msg.Content,
+		})
+		for _, image := range msg.Images {
+			out.MultiContent = append(out.MultiContent, schema.ChatMessagePart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				ImageURL: &schema.ChatMessageImageURL{
+					URL:      image.DataURL(),
+					MIMEType: image.MIMEType,
+				},
+			})
+		}
+	}
+
+	if msg.Role == "tool" && out.Content == "" && msg.ToolResult != nil {
+		if result, err := json.Marshal(msg.ToolResult); err == nil {
+			out.Content = string(result)
+		}
+	}
+
+	for i, call := range msg.ToolCalls {
+		args, err := json.Marshal(call.Args)
+		if err != nil {
+			args = []byte("{}")
+		}
+		index := i
+		out.ToolCalls = append(out.ToolCalls, schema.ToolCall{
+			Index: &index,
+			ID:    call.ID,
+			Type:  "function",
+			Function: schema.FunctionCall{
+				Name:      call.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+
+	if msg.Meta != nil {
+		out.ResponseMeta = &schema.ResponseMeta{
+			FinishReason: msg.Meta.FinishReason,
+			Usage: &schema.TokenUsage{
+				PromptTokens:     msg.Meta.Usage.PromptTokens,
+				CompletionTokens: msg.Meta.Usage.CompletionTokens,
+				TotalTokens:      msg.Meta.Usage.TotalTokens,
+			},
+		}
+	}
+
+	if msg.Reasoning != "" {
+		out.Extra = map[string]any{reasoningExtraKey: msg.Reasoning}
+	}
+
+	return out
+}
+
+// FromEinoMessage converts an eino schema.Message into a mock.Message.
+// Tool call arguments are decoded from their JSON encoding and inline image
+// parts of MultiContent become Images.
+func FromEinoMessage(msg *schema.Message) (*mock.Message, error) {
+	out := &mock.Message{
+		Role:       string(msg.Role),
+		Content:    msg.Content,
+		Name:       msg.Name,
+		ToolCallID: msg.ToolCallID,
+	}
+
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case schema.ChatMessagePartTypeText:
+			if msg.Content == "" {
+				out.Content += part.Text
+			}
+		case schema.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				continue
+			}
+			image, err := mock.ImageFromDataURL(part.ImageURL.URL)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported image %q: only inline data URLs can be converted: %v", truncate(part.ImageURL.URL, 64), err)
+			}
+			out.Images = append(out.Images, image)
+		}
+	}
+
+	for i, call := range msg.ToolCalls {
+		args := map[string]interface{}{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("failed to decode arguments of tool call %s: %v", call.Function.Name, err)
+			}
+		}
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		out.ToolCalls = append(out.ToolCalls, mock.ToolCall{
+			ID:   id,
+			Name: call.Function.Name,
+			Args: args,
+		})
+	}
+
+	if msg.ResponseMeta != nil {
+		out.Meta = &mock.ResponseMeta{FinishReason: msg.ResponseMeta.FinishReason}
+		if usage := msg.ResponseMeta.Usage; usage != nil {
+			out.Meta.Usage = mock.TokenUsage{
+				PromptTokens:     usage.PromptTokens,
+				CompletionTokens: usage.CompletionTokens,
+				TotalTokens:      usage.TotalTokens,
+			}
+		}
+	}
+
+	if reasoning, ok := msg.Extra[reasoningExtraKey].(string); ok {
+		out.Reasoning = reasoning
+	}
+
+	return out, nil
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// ToEinoMessages converts a slice of mock messages
+func ToEinoMessages(msgs []*mock.Message) []*schema.Message {
+	out := make([]*schema.Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = ToEinoMessage(msg)
+	}
+	return out
+}
+
+// FromEinoMessages converts a slice of eino messages
+func FromEinoMessages(msgs []*schema.Message) ([]*mock.Message, error) {
+	out := make([]*mock.Message, len(msgs))
+	for i, msg := range msgs {
+		converted, err := FromEinoMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = converted
+	}
+	return out, nil
+}
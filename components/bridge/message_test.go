@@ -0,0 +1,92 @@
+package bridge
+
+import (
+	"deepllm/components/mock"
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	messages := []*mock.Message{
+		{Role: "system", Content: "你是杭州旅游助手"},
+		{Role: "user", Content: "这是哪里？", Images: []mock.Image{{MIMEType: "image/png", Data: png}}},
+		{
+			Role:      "assistant",
+			Reasoning: "需要查询天气",
+			ToolCalls: []mock.ToolCall{
+				{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州", "days": float64(3)}},
+				{ID: "call_1", Name: "search_attractions", Args: map[string]interface{}{}},
+			},
+			Meta: &mock.ResponseMeta{FinishReason: "tool_calls", Usage: mock.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}},
+		},
+		{Role: "tool", Name: "get_weather", ToolCallID: "call_0", Content: `{"condition":"晴"}`},
+	}
+
+	back, err := FromEinoMessages(ToEinoMessages(messages))
+	if err != nil {
+		t.Fatalf("FromEinoMessages: %v", err)
+	}
+	if !reflect.DeepEqual(back, messages) {
+		for i := range messages {
+			if !reflect.DeepEqual(back[i], messages[i]) {
+				t.Errorf("message %d:\n got %+v\nwant %+v", i, back[i], messages[i])
+			}
+		}
+	}
+}
+
+func TestToEinoMessage(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	user := ToEinoMessage(&mock.Message{Role: "user", Content: "这是哪里？", Images: []mock.Image{mock.NewImage("lake.png", png)}})
+	if len(user.MultiContent) != 2 || user.MultiContent[0].Text != "这是哪里？" ||
+		user.MultiContent[1].ImageURL.URL != "data:image/png;base64,iVBORw0KGgowMDAw" {
+		t.Errorf("multi content = %+v", user.MultiContent)
+	}
+
+	call := ToEinoMessage(&mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{
+		{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}},
+	}}).ToolCalls[0]
+	if call.Index == nil || *call.Index != 0 || call.Type != "function" || call.Function.Arguments != `{"city":"杭州"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+
+	// A tool result without content is sent as JSON
+	result := ToEinoMessage(&mock.Message{Role: "tool", ToolCallID: "call_0", ToolResult: map[string]interface{}{"condition": "晴"}})
+	if result.Content != `{"condition":"晴"}` {
+		t.Errorf("tool content = %q", result.Content)
+	}
+}
+
+func TestFromEinoMessage(t *testing.T) {
+	msg, err := FromEinoMessage(&schema.Message{
+		Role: schema.Assistant,
+		MultiContent: []schema.ChatMessagePart{
+			{Type: schema.ChatMessagePartTypeText, Text: "西湖"},
+			{Type: schema.ChatMessagePartTypeText, Text: "断桥"},
+		},
+		ToolCalls: []schema.ToolCall{{Function: schema.FunctionCall{Name: "get_weather"}}},
+	})
+	if err != nil {
+		t.Fatalf("FromEinoMessage: %v", err)
+	}
+	if msg.Content != "西湖断桥" {
+		t.Errorf("content = %q, want the text parts", msg.Content)
+	}
+	if call := msg.ToolCalls[0]; call.ID != "call_0" || call.Args == nil || len(call.Args) != 0 {
+		t.Errorf("tool call = %+v, want a numbered ID and empty arguments", call)
+	}
+
+	// Arguments that are not JSON and remote images cannot be converted
+	if _, err := FromEinoMessage(&schema.Message{ToolCalls: []schema.ToolCall{{Function: schema.FunctionCall{Name: "get_weather", Arguments: "{city"}}}}); err == nil {
+		t.Error("broken tool call arguments did not fail")
+	}
+	remote := &schema.Message{MultiContent: []schema.ChatMessagePart{
+		{Type: schema.ChatMessagePartTypeImageURL, ImageURL: &schema.ChatMessageImageURL{URL: "https://example.com/lake.png"}},
+	}}
+	if _, err := FromEinoMessage(remote); err == nil {
+		t.Error("a remote image URL did not fail")
+	}
+}
@@ -0,0 +1,257 @@
+// Package bridge adapts our mock.ChatModel and mock.Tool interfaces to the
+// eino model.ChatModel, schema.Message and tool.InvokableTool types and back,
+// so agents can run on eino models and our tools can be used in eino graphs.
+package bridge
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// einoModel exposes a mock.ChatModel as an eino model.ChatModel
+type einoModel struct {
+	model mock.ChatModel
+
+	mu    sync.Mutex
+	bound string // sorted names of the tools bound with BindTools
+}
+
+// NewEinoChatModel wraps a mock.ChatModel so it can be used wherever eino
+// expects a model.ChatModel. Models without streaming support answer Stream
+// with a single chunk.
+func NewEinoChatModel(m mock.ChatModel) model.ChatModel {
+	return &einoModel{model: m}
+}
+
+// Generate converts the input, calls the wrapped model and converts the answer
+func (e *einoModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	ctx, err := e.prepare(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := FromEinoMessages(input)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := e.model.Generate(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+	return ToEinoMessage(out), nil
+}
+
+// Stream streams the wrapped model's answer as eino messages
+func (e *einoModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	ctx, err := e.prepare(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := FromEinoMessages(input)
+	if err != nil {
+		return nil, err
+	}
+
+	streamer, ok := e.model.(mock.StreamingChatModel)
+	if !ok {
+		out, err := e.model.Generate(ctx, msgs)
+		if err != nil {
+			return nil, err
+		}
+		return schema.StreamReaderFromArray([]*schema.Message{ToEinoMessage(out)}), nil
+	}
+
+	stream, err := streamer.Stream(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := schema.Pipe[*schema.Message](1)
+	go func() {
+		defer writer.Close()
+		defer stream.Close()
+		for {
+			chunk, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				writer.Send(nil, err)
+				return
+			}
+			if closed := writer.Send(ToEinoMessage(chunk), nil); closed {
+				return
+			}
+		}
+	}()
+	return reader, nil
+}
+
+// BindTools declares the tools to the wrapped model. Tool execution is left
+// to the eino caller, e.g. a ToolsNode.
+func (e *einoModel) BindTools(tools []*schema.ToolInfo) error {
+	declared, err := declaredTools(tools)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.model.BindTools(declared); err != nil {
+		return err
+	}
+	e.bound = toolNames(tools)
+	return nil
+}
+
+// prepare applies eino call options to this call only, through the context.
+// The wrapped model may be shared, so per-call tools never rebind it: an
+// empty list leaves the bound tools out of the call, and any other list must
+// be the bound set.
+func (e *einoModel) prepare(ctx context.Context, opts []model.Option) (context.Context, error) {
+	common := model.GetCommonOptions(&model.Options{}, opts...)
+	if common.Tools != nil {
+		if len(common.Tools) == 0 {
+			ctx = mock.WithoutTools(ctx)
+		} else {
+			e.mu.Lock()
+			bound := e.bound
+			e.mu.Unlock()
+			if names := toolNames(common.Tools); names != bound {
+				return ctx, fmt.Errorf("per-call tools [%s] differ from the bound tools [%s], bind them with BindTools", names, bound)
+			}
+		}
+	}
+
+	var callOpts mock.Options
+	if common.Temperature != nil {
+		callOpts.Temperature = mock.Float64(float64(*common.Temperature))
+	}
+	if common.TopP != nil {
+		callOpts.TopP = mock.Float64(float64(*common.TopP))
+	}
+	if common.MaxTokens != nil {
+		callOpts.NumPredict = *common.MaxTokens
+	}
+	callOpts.Stop = common.Stop
+	return mock.WithOptions(ctx, callOpts), nil
+}
+
+// toolNames returns the sorted, comma separated names of tools
+func toolNames(tools []*schema.ToolInfo) string {
+	names := make([]string, len(tools))
+	for i, info := range tools {
+		names[i] = info.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// declaredTools converts eino ToolInfos into description-only mock tools
+func declaredTools(tools []*schema.ToolInfo) ([]mock.Tool, error) {
+	declared := make([]mock.Tool, len(tools))
+	for i, info := range tools {
+		params, err := schemaFromToolInfo(info)
+		if err != nil {
+			return nil, err
+		}
+		declared[i] = &declaredTool{info: info, parameters: params}
+	}
+	return declared, nil
+}
+
+// ChatModel exposes an eino model.ChatModel as a mock.StreamingChatModel
+type ChatModel struct {
+	model model.ChatModel
+}
+
+// NewChatModel wraps an eino model.ChatModel, e.g. the eino-ext Ollama model,
+// so our agents can run on it
+func NewChatModel(m model.ChatModel) *ChatModel {
+	return &ChatModel{model: m}
+}
+
+// Generate converts the messages, calls the eino model and converts the answer
+func (c *ChatModel) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	out, err := c.model.Generate(ctx, ToEinoMessages(messages), callOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return FromEinoMessage(out)
+}
+
+// Stream streams the eino model's answer as mock messages
+func (c *ChatModel) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	reader, err := c.model.Stream(ctx, ToEinoMessages(messages), callOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return &messageStream{reader: reader}, nil
+}
+
+// BindTools describes the tools to the eino model
+func (c *ChatModel) BindTools(tools []mock.Tool) error {
+	infos := make([]*schema.ToolInfo, len(tools))
+	for i, t := range tools {
+		info, err := ToolInfo(t)
+		if err != nil {
+			return err
+		}
+		infos[i] = info
+	}
+	return c.model.BindTools(infos)
+}
+
+// callOptions converts the options carried by ctx into eino call options
+func callOptions(ctx context.Context) []model.Option {
+	opts := mock.OptionsFromContext(ctx)
+
+	var out []model.Option
+	if opts.Temperature != nil {
+		out = append(out, model.WithTemperature(float32(*opts.Temperature)))
+	}
+	if opts.TopP != nil {
+		out = append(out, model.WithTopP(float32(*opts.TopP)))
+	}
+	if opts.NumPredict > 0 {
+		out = append(out, model.WithMaxTokens(opts.NumPredict))
+	}
+	if len(opts.Stop) > 0 {
+		out = append(out, model.WithStop(opts.Stop))
+	}
+	if mock.ToolsDisabled(ctx) {
+		// An empty list overrides the bound tools for this call
+		out = append(out, model.WithTools(nil))
+	}
+	return out
+}
+
+// messageStream adapts an eino StreamReader to mock.MessageStream
+type messageStream struct {
+	reader *schema.StreamReader[*schema.Message]
+}
+
+// Recv returns the next chunk, or io.EOF at the end of the stream
+func (s *messageStream) Recv() (*mock.Message, error) {
+	chunk, err := s.reader.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return FromEinoMessage(chunk)
+}
+
+// Close closes the underlying reader
+func (s *messageStream) Close() error {
+	s.reader.Close()
+	return nil
+}
@@ -0,0 +1,185 @@
+package bridge
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// generateOnly hides the Stream method of the wrapped model
+type generateOnly struct {
+	mock.ChatModel
+}
+
+// readAll collects the contents of the chunks of reader
+func readAll(t *testing.T, reader *schema.StreamReader[*schema.Message]) []string {
+	t.Helper()
+	defer reader.Close()
+	var chunks []string
+	for {
+		chunk, err := reader.Recv()
+		if errors.Is(err, io.EOF) {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		chunks = append(chunks, chunk.Content)
+	}
+}
+
+func TestEinoModelStream(t *testing.T) {
+	ctx := context.Background()
+	input := []*schema.Message{schema.UserMessage("推荐景点")}
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText("西湖 灵隐寺 千岛湖")
+
+	reader, err := NewEinoChatModel(fake).Stream(ctx, input)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if chunks := readAll(t, reader); len(chunks) != 3 || strings.Join(chunks, "") != "西湖 灵隐寺 千岛湖" {
+		t.Errorf("chunks = %q, want one per word", chunks)
+	}
+
+	// Models that cannot stream answer with a single chunk
+	reader, err = NewEinoChatModel(generateOnly{fake}).Stream(ctx, input)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if chunks := readAll(t, reader); !reflect.DeepEqual(chunks, []string{"西湖 灵隐寺 千岛湖"}) {
+		t.Errorf("fallback chunks = %q, want the whole answer", chunks)
+	}
+
+	failing := mock.NewFakeChatModel()
+	failing.On().ReplyError(errors.New("model unavailable"))
+	if _, err := NewEinoChatModel(generateOnly{failing}).Stream(ctx, input); err == nil || err.Error() != "model unavailable" {
+		t.Errorf("fallback error = %v", err)
+	}
+}
+
+func TestEinoModelOptions(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText("好的")
+	m := NewEinoChatModel(fake)
+
+	_, err := m.Generate(context.Background(), []*schema.Message{schema.UserMessage("hi")},
+		model.WithTemperature(0.5), model.WithTopP(0.9), model.WithMaxTokens(128), model.WithStop([]string{"\n\n"}))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := mock.Options{Temperature: mock.Float64(0.5), TopP: mock.Float64(float64(float32(0.9))), NumPredict: 128, Stop: []string{"\n\n"}}
+	if got := fake.Calls()[0].Options; !reflect.DeepEqual(got, want) {
+		t.Errorf("options = %+v, want %+v", got, want)
+	}
+}
+
+func TestEinoModelPerCallTools(t *testing.T) {
+	ctx := context.Background()
+	input := []*schema.Message{schema.UserMessage("明天天气如何？")}
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText("晴")
+	m := NewEinoChatModel(fake)
+
+	weather, err := ToolInfo(weatherTool())
+	if err != nil {
+		t.Fatalf("ToolInfo: %v", err)
+	}
+	if err := m.BindTools([]*schema.ToolInfo{weather}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+
+	// An empty list leaves the tools out of that call only
+	if _, err := m.Generate(ctx, input, model.WithTools(nil)); err != nil {
+		t.Fatalf("Generate without tools: %v", err)
+	}
+	if _, err := m.Generate(ctx, input); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	// The bound set may be repeated per call
+	if _, err := m.Generate(ctx, input, model.WithTools([]*schema.ToolInfo{weather})); err != nil {
+		t.Fatalf("Generate with the bound tools: %v", err)
+	}
+	calls := fake.Calls()
+	if len(calls[0].Tools) != 0 || !reflect.DeepEqual(calls[1].Tools, []string{"get_weather"}) || !reflect.DeepEqual(calls[2].Tools, []string{"get_weather"}) {
+		t.Errorf("offered tools = %v, %v, %v", calls[0].Tools, calls[1].Tools, calls[2].Tools)
+	}
+
+	// Other tools would rebind the shared model and are refused
+	other := &schema.ToolInfo{Name: "book_hotel", Desc: "预订酒店"}
+	if _, err := m.Stream(ctx, input, model.WithTools([]*schema.ToolInfo{other})); err == nil {
+		t.Error("per-call tools differing from the bound ones did not fail")
+	}
+	if _, err := m.Generate(ctx, input); err != nil || !reflect.DeepEqual(fake.Calls()[3].Tools, []string{"get_weather"}) {
+		t.Errorf("after a refused call: offered %v, %v", fake.Calls()[3].Tools, err)
+	}
+}
+
+// recordingModel is an eino model.ChatModel answering "好的" and recording
+// the common options and bound tools of its calls
+type recordingModel struct {
+	options []*model.Options
+	tools   []*schema.ToolInfo
+}
+
+func (m *recordingModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.options = append(m.options, model.GetCommonOptions(&model.Options{}, opts...))
+	return schema.AssistantMessage("好的", nil), nil
+}
+
+func (m *recordingModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.options = append(m.options, model.GetCommonOptions(&model.Options{}, opts...))
+	return schema.StreamReaderFromArray([]*schema.Message{schema.AssistantMessage("好", nil), schema.AssistantMessage("的", nil)}), nil
+}
+
+func (m *recordingModel) BindTools(tools []*schema.ToolInfo) error {
+	m.tools = tools
+	return nil
+}
+
+func TestChatModel(t *testing.T) {
+	recorder := &recordingModel{}
+	c := NewChatModel(recorder)
+	if err := c.BindTools([]mock.Tool{weatherTool()}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	if len(recorder.tools) != 1 || recorder.tools[0].Name != "get_weather" {
+		t.Errorf("bound tools = %+v", recorder.tools)
+	}
+
+	ctx := mock.WithOptions(context.Background(), mock.Options{
+		Temperature: mock.Float64(0.2),
+		TopP:        mock.Float64(0.8),
+		NumPredict:  256,
+		Stop:        []string{"Observation:"},
+		Seed:        mock.Int(42),
+	})
+	answer, err := c.Generate(ctx, []*mock.Message{{Role: "user", Content: "hi"}})
+	if err != nil || answer.Content != "好的" {
+		t.Fatalf("Generate = %+v, %v", answer, err)
+	}
+	got := recorder.options[0]
+	if *got.Temperature != 0.2 || *got.TopP != 0.8 || *got.MaxTokens != 256 || !reflect.DeepEqual(got.Stop, []string{"Observation:"}) || got.Tools != nil {
+		t.Errorf("call options = %+v", got)
+	}
+
+	stream, err := c.Stream(mock.WithoutTools(context.Background()), []*mock.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	answer, err = mock.CollectStream(stream)
+	if err != nil || answer.Content != "好的" {
+		t.Errorf("streamed answer = %+v, %v", answer, err)
+	}
+	got = recorder.options[1]
+	if got.Temperature != nil || got.MaxTokens != nil || got.Tools == nil || len(got.Tools) != 0 {
+		t.Errorf("call options without tools = %+v, want only an empty tool list", got)
+	}
+}
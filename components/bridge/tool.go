@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ToolInfo describes a mock.Tool as an eino ToolInfo
+func ToolInfo(t mock.Tool) (*schema.ToolInfo, error) {
+	info := &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: t.Description(),
+	}
+
+	params := t.Parameters()
+	if params == nil || (params.Type == "object" && len(params.Properties) == 0) {
+		return info, nil
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters of %s: %v", t.Name(), err)
+	}
+	openAPI := &openapi3.Schema{}
+	if err := json.Unmarshal(raw, openAPI); err != nil {
+		return nil, fmt.Errorf("failed to convert parameters of %s: %v", t.Name(), err)
+	}
+	info.ParamsOneOf = schema.NewParamsOneOfByOpenAPIV3(openAPI)
+	return info, nil
+}
+
+// schemaFromToolInfo converts the parameters of an eino ToolInfo into a mock.Schema
+func schemaFromToolInfo(info *schema.ToolInfo) (*mock.Schema, error) {
+	openAPI, err := info.ToOpenAPIV3()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters of %s: %v", info.Name, err)
+	}
+	if openAPI == nil {
+		return mock.EmptyObjectSchema(), nil
+	}
+
+	raw, err := json.Marshal(openAPI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters of %s: %v", info.Name, err)
+	}
+	params := &mock.Schema{}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return nil, fmt.Errorf("failed to convert parameters of %s: %v", info.Name, err)
+	}
+	return params, nil
+}
+
+// einoTool exposes a mock.Tool as an eino InvokableTool
+type einoTool struct {
+	tool mock.Tool
+}
+
+// NewEinoTool wraps a mock.Tool so it can be used in eino graphs and ReAct agents
+func NewEinoTool(t mock.Tool) tool.InvokableTool {
+	return &einoTool{tool: t}
+}
+
+// NewEinoTools wraps a set of mock tools
+func NewEinoTools(tools []mock.Tool) []tool.BaseTool {
+	out := make([]tool.BaseTool, len(tools))
+	for i, t := range tools {
+		out[i] = NewEinoTool(t)
+	}
+	return out
+}
+
+// Info returns the tool description
+func (t *einoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return ToolInfo(t.tool)
+}
+
+// InvokableRun decodes the JSON arguments, executes the tool and encodes its result
+func (t *einoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	args := map[string]interface{}{}
+	if argumentsInJSON != "" {
+		if err := json.Unmarshal([]byte(argumentsInJSON), &args); err != nil {
+			return "", fmt.Errorf("failed to decode arguments of %s: %v", t.tool.Name(), err)
+		}
+	}
+
+	result, err := t.tool.Execute(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result of %s: %v", t.tool.Name(), err)
+	}
+	return string(out), nil
+}
+
+// mockTool exposes an eino InvokableTool as a mock.Tool
+type mockTool struct {
+	info       *schema.ToolInfo
+	parameters *mock.Schema
+	tool       tool.InvokableTool
+}
+
+// NewTool wraps an eino InvokableTool so it can be used by our agents. The
+// tool description is read once, when wrapping.
+func NewTool(ctx context.Context, t tool.InvokableTool) (mock.Tool, error) {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool info: %v", err)
+	}
+	params, err := schemaFromToolInfo(info)
+	if err != nil {
+		return nil, err
+	}
+	return &mockTool{info: info, parameters: params, tool: t}, nil
+}
+
+// Name returns the tool's name
+func (t *mockTool) Name() string {
+	return t.info.Name
+}
+
+// Description returns the tool's description
+func (t *mockTool) Description() string {
+	return t.info.Desc
+}
+
+// Parameters returns the tool's parameter schema
+func (t *mockTool) Parameters() *mock.Schema {
+	return t.parameters
+}
+
+// Execute encodes the arguments, runs the eino tool and decodes its result.
+// Results that are not JSON objects are returned under the "result" key.
+func (t *mockTool) Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %v", t.info.Name, err)
+	}
+
+	out, err := t.tool.InvokableRun(ctx, string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return map[string]interface{}{"result": out}, nil
+	}
+	return result, nil
+}
+
+// declaredTool is a tool known only by its description. It is used when an
+// eino caller binds ToolInfos to a mock.ChatModel: the model may request it,
+// but executing it is the caller's job.
+type declaredTool struct {
+	info       *schema.ToolInfo
+	parameters *mock.Schema
+}
+
+// Name returns the tool's name
+func (t *declaredTool) Name() string {
+	return t.info.Name
+}
+
+// Description returns the tool's description
+func (t *declaredTool) Description() string {
+	return t.info.Desc
+}
+
+// Parameters returns the tool's parameter schema
+func (t *declaredTool) Parameters() *mock.Schema {
+	return t.parameters
+}
+
+// Execute always fails, declared tools are executed by the eino graph
+func (t *declaredTool) Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	return nil, fmt.Errorf("tool %s is declared only and must be executed by the caller", t.info.Name)
+}
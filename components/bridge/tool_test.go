@@ -0,0 +1,98 @@
+package bridge
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// weatherParameters is the parameter schema of the weather tool
+func weatherParameters() *mock.Schema {
+	return &mock.Schema{
+		Type: "object",
+		Properties: map[string]*mock.Schema{
+			"city": {Type: "string", Description: "城市名称"},
+			"days": {Type: "integer", Minimum: mock.Float64(1), Maximum: mock.Float64(7)},
+			"unit": {Type: "string", Enum: []interface{}{"celsius", "fahrenheit"}},
+		},
+		Required: []string{"city"},
+	}
+}
+
+func weatherTool() *mock.MockTool {
+	return mock.NewMockTool("get_weather", "查询天气", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"city": args["city"], "condition": "晴"}, nil
+	}).WithParameters(weatherParameters())
+}
+
+func TestToolInfo(t *testing.T) {
+	info, err := ToolInfo(weatherTool())
+	if err != nil {
+		t.Fatalf("ToolInfo: %v", err)
+	}
+	if info.Name != "get_weather" || info.Desc != "查询天气" {
+		t.Errorf("info = %+v", info)
+	}
+	openAPI, err := info.ToOpenAPIV3()
+	if err != nil {
+		t.Fatalf("ToOpenAPIV3: %v", err)
+	}
+	days := openAPI.Properties["days"].Value
+	if !reflect.DeepEqual(openAPI.Required, []string{"city"}) || openAPI.Properties["city"].Value.Description != "城市名称" ||
+		days.Type != "integer" || *days.Min != 1 || *days.Max != 7 || len(openAPI.Properties["unit"].Value.Enum) != 2 {
+		t.Errorf("parameters = %+v", openAPI)
+	}
+
+	// Tools without parameters have no parameter schema
+	empty, err := ToolInfo(mock.NewMockTool("list_cities", "列出城市", nil).WithParameters(mock.EmptyObjectSchema()))
+	if err != nil || empty.ParamsOneOf != nil {
+		t.Errorf("parameterless info = %+v, %v", empty, err)
+	}
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	info, err := ToolInfo(weatherTool())
+	if err != nil {
+		t.Fatalf("ToolInfo: %v", err)
+	}
+	params, err := schemaFromToolInfo(info)
+	if err != nil {
+		t.Fatalf("schemaFromToolInfo: %v", err)
+	}
+	if want := weatherParameters(); !reflect.DeepEqual(params, want) {
+		t.Errorf("parameters:\n got %+v\nwant %+v", params, want)
+	}
+
+	params, err = schemaFromToolInfo(&schema.ToolInfo{Name: "list_cities"})
+	if err != nil || !reflect.DeepEqual(params, mock.EmptyObjectSchema()) {
+		t.Errorf("parameterless schema = %+v, %v", params, err)
+	}
+}
+
+func TestToolsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	einoTool := NewEinoTool(weatherTool())
+
+	out, err := einoTool.InvokableRun(ctx, `{"city": "杭州"}`)
+	if err != nil || out != `{"city":"杭州","condition":"晴"}` {
+		t.Errorf("InvokableRun = %s, %v", out, err)
+	}
+	if _, err := einoTool.InvokableRun(ctx, `{city`); err == nil {
+		t.Error("broken arguments did not fail")
+	}
+
+	back, err := NewTool(ctx, einoTool)
+	if err != nil {
+		t.Fatalf("NewTool: %v", err)
+	}
+	if back.Name() != "get_weather" || back.Description() != "查询天气" || !reflect.DeepEqual(back.Parameters(), weatherParameters()) {
+		t.Errorf("tool = %s, %s, %+v", back.Name(), back.Description(), back.Parameters())
+	}
+	result, err := back.Execute(ctx, map[string]interface{}{"city": "杭州"})
+	if err != nil || !reflect.DeepEqual(result, map[string]interface{}{"city": "杭州", "condition": "晴"}) {
+		t.Errorf("Execute = %v, %v", result, err)
+	}
+}
@@ -0,0 +1,296 @@
+// Package cache provides a caching decorator for mock.ChatModel, so that
+// identical calls made during prompt development and repeated demos are
+// answered from disk instead of the model.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"deepllm/components/mock"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats reports how the cache has been used
+type Stats struct {
+	Hits   int64
+	Misses int64
+	// Errors counts store failures; the model is called as on a miss
+	Errors int64
+}
+
+// HitRate returns the fraction of lookups answered from the cache
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// ChatModel answers calls from a Store when an identical call was made
+// before and forwards them to the wrapped model otherwise. Calls are
+// identical when the model name, the generation options, the messages and
+// the bound tools are. Replies are cached regardless of the temperature, so
+// a cached model always gives the same answer to the same call.
+type ChatModel struct {
+	model mock.ChatModel
+	store Store
+
+	mu    sync.RWMutex
+	tools []mock.Tool
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// NewChatModel wraps model with a cache backed by store
+func NewChatModel(model mock.ChatModel, store Store) *ChatModel {
+	return &ChatModel{model: model, store: store}
+}
+
+// Unwrap returns the wrapped model
+func (c *ChatModel) Unwrap() mock.ChatModel {
+	return c.model
+}
+
+// ModelName returns the name of the wrapped model, if it reports one
+func (c *ChatModel) ModelName() string {
+	if named, ok := c.model.(interface{ ModelName() string }); ok {
+		return named.ModelName()
+	}
+	return ""
+}
+
+// Options returns the default generation options of the wrapped model, if
+// it reports them
+func (c *ChatModel) Options() mock.Options {
+	if m, ok := c.model.(interface{ Options() mock.Options }); ok {
+		return m.Options()
+	}
+	return mock.Options{}
+}
+
+// ContextLength returns the context length of the wrapped model, if it
+// reports one
+func (c *ChatModel) ContextLength() int {
+	if m, ok := c.model.(interface{ ContextLength() int }); ok {
+		return m.ContextLength()
+	}
+	return 0
+}
+
+// Stats returns the hit and miss counters
+func (c *ChatModel) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+// Generate returns the cached reply for the call or calls the wrapped model
+// and caches its reply
+func (c *ChatModel) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	key, cached := c.lookup(ctx, messages)
+	if cached != nil {
+		return cached, nil
+	}
+
+	reply, err := c.model.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, reply)
+	return reply, nil
+}
+
+// Stream replays a cached reply as a single chunk, or streams the wrapped
+// model's answer and caches it once the stream is complete
+func (c *ChatModel) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	key, cached := c.lookup(ctx, messages)
+	if cached != nil {
+		return &replayStream{msg: cached}, nil
+	}
+
+	streamer, ok := c.model.(mock.StreamingChatModel)
+	if !ok {
+		reply, err := c.model.Generate(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, reply)
+		return &replayStream{msg: reply}, nil
+	}
+
+	stream, err := streamer.Stream(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStream{stream: stream, cache: c, key: key}, nil
+}
+
+// BindTools binds tools to the wrapped model and includes them in the keys
+// of subsequent calls
+func (c *ChatModel) BindTools(tools []mock.Tool) error {
+	if err := c.model.BindTools(tools); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.tools = tools
+	c.mu.Unlock()
+	return nil
+}
+
+// lookup computes the key of a call and returns the cached reply, if any
+func (c *ChatModel) lookup(ctx context.Context, messages []*mock.Message) (string, *mock.Message) {
+	key, err := c.key(ctx, messages)
+	if err != nil {
+		c.errors.Add(1)
+		log.Printf("cache: %v", err)
+		return "", nil
+	}
+
+	reply, ok, err := c.store.Get(key)
+	if err != nil {
+		c.errors.Add(1)
+		log.Printf("cache: %v", err)
+	}
+	if !ok {
+		c.misses.Add(1)
+		return key, nil
+	}
+	c.hits.Add(1)
+	return key, reply
+}
+
+// put caches a reply, logging failures since the reply itself is valid
+func (c *ChatModel) put(key string, reply *mock.Message) {
+	if key == "" {
+		return
+	}
+	if err := c.store.Put(key, reply); err != nil {
+		c.errors.Add(1)
+		log.Printf("cache: %v", err)
+	}
+}
+
+// keyMessage holds the parts of a message that are sent to the model
+type keyMessage struct {
+	Role       string                 `json:"role"`
+	Content    string                 `json:"content"`
+	Images     []mock.Image           `json:"images,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
+	ToolCalls  []mock.ToolCall        `json:"tool_calls,omitempty"`
+	ToolResult map[string]interface{} `json:"tool_result,omitempty"`
+}
+
+// keyTool holds the parts of a tool that are advertised to the model
+type keyTool struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Parameters  *mock.Schema `json:"parameters,omitempty"`
+}
+
+// key hashes everything that influences the reply to a call
+func (c *ChatModel) key(ctx context.Context, messages []*mock.Message) (string, error) {
+	var defaults mock.Options
+	if configured, ok := c.model.(interface{ Options() mock.Options }); ok {
+		defaults = configured.Options()
+	}
+
+	c.mu.RLock()
+	bound := c.tools
+	c.mu.RUnlock()
+	if mock.ToolsDisabled(ctx) {
+		bound = nil
+	}
+	tools := make([]keyTool, len(bound))
+	for i, t := range bound {
+		tools[i] = keyTool{Name: t.Name(), Description: t.Description(), Parameters: t.Parameters()}
+	}
+
+	msgs := make([]keyMessage, len(messages))
+	for i, msg := range messages {
+		msgs[i] = keyMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Images:     msg.Images,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+			ToolCalls:  msg.ToolCalls,
+			ToolResult: msg.ToolResult,
+		}
+	}
+
+	bytes, err := json.Marshal(struct {
+		Model    string       `json:"model"`
+		Options  mock.Options `json:"options"`
+		Messages []keyMessage `json:"messages"`
+		Tools    []keyTool    `json:"tools"`
+	}{
+		Model:    c.ModelName(),
+		Options:  defaults.Merge(mock.OptionsFromContext(ctx)),
+		Messages: msgs,
+		Tools:    tools,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cache key: %v", err)
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replayStream returns a cached reply as a single chunk
+type replayStream struct {
+	msg  *mock.Message
+	done bool
+}
+
+func (s *replayStream) Recv() (*mock.Message, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	s.done = true
+	return s.msg, nil
+}
+
+func (s *replayStream) Close() error {
+	return nil
+}
+
+// recordingStream passes chunks through and caches the merged reply when the
+// stream completes. Streams closed early or failing are not cached.
+type recordingStream struct {
+	stream    mock.MessageStream
+	cache     *ChatModel
+	key       string
+	collector mock.StreamCollector
+}
+
+func (s *recordingStream) Recv() (*mock.Message, error) {
+	chunk, err := s.stream.Recv()
+	if errors.Is(err, io.EOF) {
+		s.cache.put(s.key, s.collector.Message())
+		s.key = ""
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.collector.Add(chunk)
+	return chunk, nil
+}
+
+func (s *recordingStream) Close() error {
+	return s.stream.Close()
+}
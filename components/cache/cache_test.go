@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// newTestCache returns a fake model answering "西湖 灵隐寺" and a cache of
+// it in a temporary directory
+func newTestCache(t *testing.T) (*mock.FakeChatModel, *ChatModel, *DiskStore) {
+	t.Helper()
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText("西湖 灵隐寺")
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	return fake, NewChatModel(fake, store), store
+}
+
+func weatherTool(description string) mock.Tool {
+	return mock.NewMockTool("get_weather", description, nil)
+}
+
+func TestCacheKey(t *testing.T) {
+	question := []*mock.Message{{Role: "system", Content: "你是助手"}, {Role: "user", Content: "推荐景点"}}
+
+	tests := []struct {
+		name     string
+		tools    []mock.Tool
+		ctx      func(context.Context) context.Context
+		messages []*mock.Message
+		hit      bool
+	}{
+		{name: "same call", messages: question, hit: true},
+		{name: "other content", messages: []*mock.Message{question[0], {Role: "user", Content: "推荐餐厅"}}},
+		{name: "other role", messages: []*mock.Message{{Role: "user", Content: "你是助手"}, question[1]}},
+		{name: "extra message", messages: append(append([]*mock.Message(nil), question...), &mock.Message{Role: "user", Content: "谢谢"})},
+		{
+			name:     "reasoning and meta are not sent",
+			messages: []*mock.Message{question[0], {Role: "user", Content: "推荐景点", Reasoning: "...", Meta: &mock.ResponseMeta{Model: "x"}}},
+			hit:      true,
+		},
+		{
+			name:     "image",
+			messages: []*mock.Message{question[0], {Role: "user", Content: "推荐景点", Images: []mock.Image{{MIMEType: "image/png", Data: []byte("png")}}}},
+		},
+		{
+			name: "temperature",
+			ctx: func(ctx context.Context) context.Context {
+				return mock.WithOptions(ctx, mock.Options{Temperature: mock.Float64(0.2)})
+			},
+			messages: question,
+		},
+		{
+			name: "seed",
+			ctx: func(ctx context.Context) context.Context {
+				return mock.WithOptions(ctx, mock.Options{Seed: mock.Int(42)})
+			},
+			messages: question,
+		},
+		{name: "bound tool", tools: []mock.Tool{weatherTool("查询天气")}, messages: question},
+		{
+			name:     "tools left out of the call",
+			tools:    []mock.Tool{weatherTool("查询天气")},
+			ctx:      mock.WithoutTools,
+			messages: question,
+			hit:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, cached, _ := newTestCache(t)
+			ctx := context.Background()
+			if _, err := cached.Generate(ctx, question); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+
+			if tt.tools != nil {
+				if err := cached.BindTools(tt.tools); err != nil {
+					t.Fatalf("BindTools: %v", err)
+				}
+			}
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+			reply, err := cached.Generate(ctx, tt.messages)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if reply.Content != "西湖 灵隐寺" {
+				t.Errorf("reply = %q", reply.Content)
+			}
+
+			wantCalls, wantStats := 2, Stats{Misses: 2}
+			if tt.hit {
+				wantCalls, wantStats = 1, Stats{Hits: 1, Misses: 1}
+			}
+			if fake.CallCount() != wantCalls || cached.Stats() != wantStats {
+				t.Errorf("model called %d times with stats %+v, want %d and %+v", fake.CallCount(), cached.Stats(), wantCalls, wantStats)
+			}
+		})
+	}
+}
+
+func TestCacheKeyToolDefinition(t *testing.T) {
+	fake, cached, _ := newTestCache(t)
+	ctx := context.Background()
+	messages := []*mock.Message{{Role: "user", Content: "明天天气如何？"}}
+
+	for _, tools := range [][]mock.Tool{
+		{weatherTool("查询天气")},
+		{weatherTool("查询天气")},
+		{weatherTool("查询未来三天的天气")},
+		{weatherTool("查询天气").(*mock.MockTool).WithParameters(&mock.Schema{Type: "object", Properties: map[string]*mock.Schema{"city": {Type: "string"}}})},
+	} {
+		if err := cached.BindTools(tools); err != nil {
+			t.Fatalf("BindTools: %v", err)
+		}
+		if _, err := cached.Generate(ctx, messages); err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+	}
+	// Only rebinding the same definition hits
+	if fake.CallCount() != 3 || cached.Stats().Hits != 1 {
+		t.Errorf("model called %d times with stats %+v, want 3 calls and a hit", fake.CallCount(), cached.Stats())
+	}
+}
+
+func TestCacheCorruptEntry(t *testing.T) {
+	fake, cached, store := newTestCache(t)
+	ctx := context.Background()
+	messages := []*mock.Message{{Role: "user", Content: "推荐景点"}}
+	key, err := cached.key(ctx, messages)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	if err := os.WriteFile(store.path(key), []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The corrupt entry is a miss and is replaced by the new reply
+	for i := 0; i < 2; i++ {
+		if reply, err := cached.Generate(ctx, messages); err != nil || reply.Content != "西湖 灵隐寺" {
+			t.Fatalf("Generate = %+v, %v", reply, err)
+		}
+	}
+	if fake.CallCount() != 1 || cached.Stats() != (Stats{Hits: 1, Misses: 1}) {
+		t.Errorf("model called %d times with stats %+v", fake.CallCount(), cached.Stats())
+	}
+}
+
+func TestCacheErrorsAreNotCached(t *testing.T) {
+	fake := mock.NewFakeChatModel()
+	fake.On().Once().ReplyError(errors.New("model unavailable"))
+	fake.On().ReplyText("西湖")
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	cached := NewChatModel(fake, store)
+	messages := []*mock.Message{{Role: "user", Content: "推荐景点"}}
+
+	if _, err := cached.Generate(context.Background(), messages); err == nil {
+		t.Fatal("the model error was not returned")
+	}
+	if reply, err := cached.Generate(context.Background(), messages); err != nil || reply.Content != "西湖" {
+		t.Errorf("Generate after an error = %+v, %v", reply, err)
+	}
+	if n, _ := store.Len(); n != 1 {
+		t.Errorf("stored %d entries, want 1", n)
+	}
+}
+
+// readChunks reads stream to the end and returns its chunks
+func readChunks(t *testing.T, stream mock.MessageStream) []*mock.Message {
+	t.Helper()
+	defer stream.Close()
+	var chunks []*mock.Message
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return chunks
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+// generateOnly hides the Stream method of the wrapped model
+type generateOnly struct {
+	mock.ChatModel
+}
+
+func TestCacheStream(t *testing.T) {
+	ctx := context.Background()
+	messages := []*mock.Message{{Role: "user", Content: "明天天气如何？"}}
+	toolCall := mock.ToolCall{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}}
+
+	tests := []struct {
+		name  string
+		reply *mock.Message
+		// stream is false for models without streaming support
+		stream bool
+		// chunks is the number of chunks streamed on a miss
+		chunks int
+	}{
+		{"text", &mock.Message{Role: "assistant", Content: "杭州 明天 晴"}, true, 3},
+		{"tool calls", &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{toolCall}}, true, 1},
+		{"text without streaming", &mock.Message{Role: "assistant", Content: "杭州 明天 晴"}, false, 1},
+		{"tool calls without streaming", &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{toolCall}}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mock.NewFakeChatModel()
+			fake.On().ReplyMessage(tt.reply)
+			var model mock.ChatModel = fake
+			if !tt.stream {
+				model = generateOnly{fake}
+			}
+			store, err := NewDiskStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewDiskStore: %v", err)
+			}
+			cached := NewChatModel(model, store)
+
+			stream, err := cached.Stream(ctx, messages)
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			if chunks := readChunks(t, stream); len(chunks) != tt.chunks {
+				t.Errorf("streamed %d chunks on a miss, want %d", len(chunks), tt.chunks)
+			}
+
+			// The merged reply is replayed as one chunk and answers Generate
+			stream, err = cached.Stream(ctx, messages)
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			chunks := readChunks(t, stream)
+			if len(chunks) != 1 {
+				t.Fatalf("replayed %d chunks, want 1", len(chunks))
+			}
+			reply, err := cached.Generate(ctx, messages)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			for _, got := range []*mock.Message{chunks[0], reply} {
+				if got.Content != tt.reply.Content || !reflect.DeepEqual(got.ToolCalls, tt.reply.ToolCalls) {
+					t.Errorf("replayed %+v, want %+v", got, tt.reply)
+				}
+			}
+			if fake.CallCount() != 1 {
+				t.Errorf("model called %d times, want 1", fake.CallCount())
+			}
+		})
+	}
+}
+
+func TestCacheStreamClosedEarly(t *testing.T) {
+	fake, cached, store := newTestCache(t)
+	ctx := context.Background()
+	messages := []*mock.Message{{Role: "user", Content: "推荐景点"}}
+
+	stream, err := cached.Stream(ctx, messages)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	stream.Close()
+	if n, _ := store.Len(); n != 0 {
+		t.Errorf("an interrupted stream stored %d entries", n)
+	}
+
+	// The next call streams from the model again
+	stream, err = cached.Stream(ctx, messages)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if chunks := readChunks(t, stream); len(chunks) != 2 || fake.CallCount() != 2 {
+		t.Errorf("streamed %d chunks with %d model calls, want 2 and 2", len(chunks), fake.CallCount())
+	}
+	if n, _ := store.Len(); n != 1 {
+		t.Errorf("a complete stream stored %d entries, want 1", n)
+	}
+}
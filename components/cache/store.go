@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store persists cached replies by key
+type Store interface {
+	// Get returns the reply stored under key, or false when there is none
+	Get(key string) (*mock.Message, bool, error)
+	// Put stores a reply under key
+	Put(key string, msg *mock.Message) error
+}
+
+// entry is the on-disk representation of a cached reply
+type entry struct {
+	CreatedAt time.Time     `json:"created_at"`
+	Message   *mock.Message `json:"message"`
+}
+
+// DiskStore keeps one JSON file per reply in a directory. Entries older than
+// the TTL are treated as missing, and the oldest entries are evicted once the
+// directory holds more than MaxEntries files or MaxBytes bytes.
+type DiskStore struct {
+	dir        string
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+
+	mu sync.Mutex
+}
+
+// DiskOption configures a DiskStore
+type DiskOption func(*DiskStore)
+
+// WithTTL expires entries after ttl. Zero keeps entries forever.
+func WithTTL(ttl time.Duration) DiskOption {
+	return func(s *DiskStore) {
+		s.ttl = ttl
+	}
+}
+
+// WithMaxEntries limits the number of stored replies. Zero means no limit.
+func WithMaxEntries(n int) DiskOption {
+	return func(s *DiskStore) {
+		s.maxEntries = n
+	}
+}
+
+// WithMaxBytes limits the total size of the stored replies. Zero means no limit.
+func WithMaxBytes(n int64) DiskOption {
+	return func(s *DiskStore) {
+		s.maxBytes = n
+	}
+}
+
+// NewDiskStore creates a store in dir, creating the directory if needed
+func NewDiskStore(dir string, opts ...DiskOption) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	s := &DiskStore{dir: dir}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Get returns the reply stored under key. Expired entries are removed.
+func (s *DiskStore) Get(key string) (*mock.Message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %v", err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(bytes, &e); err != nil || e.Message == nil {
+		// A corrupt entry is as good as a missing one
+		os.Remove(path)
+		return nil, false, nil
+	}
+	if s.ttl > 0 && time.Since(e.CreatedAt) > s.ttl {
+		os.Remove(path)
+		return nil, false, nil
+	}
+	return e.Message, true, nil
+}
+
+// Put stores a reply under key and evicts old entries beyond the limits
+func (s *DiskStore) Put(key string, msg *mock.Message) error {
+	bytes, err := json.Marshal(entry{CreatedAt: time.Now(), Message: msg})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Write to a temporary file first so readers never see partial entries
+	tmp, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+
+	return s.evict()
+}
+
+// Len returns the number of stored entries
+func (s *DiskStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.entries()
+	return len(files), err
+}
+
+// evict removes the oldest entries until the store is within its limits
+func (s *DiskStore) evict() error {
+	if s.maxEntries <= 0 && s.maxBytes <= 0 {
+		return nil
+	}
+
+	files, err := s.entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	for len(files) > 0 &&
+		((s.maxEntries > 0 && len(files) > s.maxEntries) || (s.maxBytes > 0 && total > s.maxBytes)) {
+		if err := os.Remove(filepath.Join(s.dir, files[0].Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to evict cache entry: %v", err)
+		}
+		total -= files[0].Size()
+		files = files[1:]
+	}
+	return nil
+}
+
+// entries lists the entry files of the store
+func (s *DiskStore) entries() ([]os.FileInfo, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache entries: %v", err)
+	}
+
+	var files []os.FileInfo
+	for _, d := range dirEntries {
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// path returns the file holding the entry for key
+func (s *DiskStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskStore(t *testing.T) {
+	store, err := NewDiskStore(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	if _, ok, err := store.Get("missing"); ok || err != nil {
+		t.Errorf("Get of a missing key = %v, %v", ok, err)
+	}
+
+	reply := &mock.Message{Role: "assistant", Content: "西湖", ToolCalls: []mock.ToolCall{{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}}}}
+	if err := store.Put("k", reply); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, ok, err := store.Get("k")
+	if !ok || err != nil || got.Content != "西湖" || len(got.ToolCalls) != 1 || got.ToolCalls[0].Args["city"] != "杭州" {
+		t.Errorf("Get = %+v, %v, %v", got, ok, err)
+	}
+	if n, err := store.Len(); n != 1 || err != nil {
+		t.Errorf("Len = %d, %v, want 1", n, err)
+	}
+}
+
+func TestDiskStoreTTL(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir, WithTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("NewDiskStore: %v", err)
+	}
+	for _, key := range []string{"fresh", "stale"} {
+		if err := store.Put(key, &mock.Message{Role: "assistant", Content: key}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	// Backdate one entry past the TTL
+	stale, err := json.Marshal(entry{CreatedAt: time.Now().Add(-2 * time.Hour), Message: &mock.Message{Role: "assistant", Content: "stale"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.path("stale"), stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := store.Get("fresh"); !ok {
+		t.Error("the fresh entry expired")
+	}
+	if _, ok, err := store.Get("stale"); ok || err != nil {
+		t.Errorf("Get of an expired entry = %v, %v, want a miss", ok, err)
+	}
+	if _, err := os.Stat(store.path("stale")); !os.IsNotExist(err) {
+		t.Error("the expired entry was not removed")
+	}
+}
+
+func TestDiskStoreEviction(t *testing.T) {
+	reply := &mock.Message{Role: "assistant", Content: "杭州西湖"}
+	size := func() int64 {
+		bytes, _ := json.Marshal(entry{CreatedAt: time.Now(), Message: reply})
+		return int64(len(bytes))
+	}()
+
+	tests := []struct {
+		name string
+		opts []DiskOption
+	}{
+		{"max entries", []DiskOption{WithMaxEntries(2)}},
+		{"max bytes", []DiskOption{WithMaxBytes(2*size + size/2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewDiskStore(t.TempDir(), tt.opts...)
+			if err != nil {
+				t.Fatalf("NewDiskStore: %v", err)
+			}
+			// Entries are evicted oldest first, by modification time
+			start := time.Now().Add(-time.Hour)
+			for i, key := range []string{"a", "b"} {
+				if err := store.Put(key, reply); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+				at := start.Add(time.Duration(i) * time.Minute)
+				if err := os.Chtimes(store.path(key), at, at); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.Put("c", reply); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			if n, _ := store.Len(); n != 2 {
+				t.Errorf("Len = %d, want 2", n)
+			}
+			for key, want := range map[string]bool{"a": false, "b": true, "c": true} {
+				if _, ok, _ := store.Get(key); ok != want {
+					t.Errorf("entry %s stored = %v, want %v", key, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiskStoreCorruptEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not JSON", "{\"created_at\": "},
+		{"no message", `{"created_at": "2025-02-18T10:00:00Z"}`},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewDiskStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewDiskStore: %v", err)
+			}
+			if err := os.WriteFile(store.path("k"), []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, err := store.Get("k"); ok || err != nil {
+				t.Errorf("Get = %v, %v, want a miss", ok, err)
+			}
+			if _, err := os.Stat(store.path("k")); !os.IsNotExist(err) {
+				t.Error("the corrupt entry was not removed")
+			}
+		})
+	}
+}
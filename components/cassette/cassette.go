@@ -0,0 +1,109 @@
+// Package cassette records the calls made to a mock.ChatModel into cassette
+// files and replays them, so agents can be exercised without a live model.
+package cassette
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// formatVersion is the version of the cassette file format
+const formatVersion = 1
+
+// Cassette is the content of a cassette file
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded call and its reply
+type Interaction struct {
+	Request  Request       `json:"request"`
+	Response *mock.Message `json:"response"`
+}
+
+// Request holds what was sent to the model in a call. Options are the
+// per-call options carried by the context; the model's own defaults are part
+// of its configuration, like the model name.
+type Request struct {
+	Model    string          `json:"model,omitempty"`
+	Options  mock.Options    `json:"options"`
+	Messages []*mock.Message `json:"messages"`
+	Tools    []ToolSpec      `json:"tools,omitempty"`
+}
+
+// ToolSpec describes a tool bound to the model
+type ToolSpec struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Parameters  *mock.Schema `json:"parameters,omitempty"`
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %v", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode cassette %s: %v", path, err)
+	}
+	if c.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, path)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, replacing any previous content
+func (c *Cassette) Save(path string) error {
+	c.Version = formatVersion
+	bytes, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %v", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %v", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cassette: %v", err)
+	}
+	return nil
+}
+
+// toolSpecs describes the bound tools
+func toolSpecs(tools []mock.Tool) []ToolSpec {
+	if len(tools) == 0 {
+		return nil
+	}
+	specs := make([]ToolSpec, len(tools))
+	for i, t := range tools {
+		specs[i] = ToolSpec{Name: t.Name(), Description: t.Description(), Parameters: t.Parameters()}
+	}
+	return specs
+}
+
+// requestMessages copies the messages of a call without response metadata,
+// which is not sent to the model
+func requestMessages(messages []*mock.Message) []*mock.Message {
+	out := make([]*mock.Message, len(messages))
+	for i, msg := range messages {
+		m := *msg
+		m.Meta = nil
+		m.Reasoning = ""
+		out[i] = &m
+	}
+	return out
+}
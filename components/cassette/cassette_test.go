@@ -0,0 +1,205 @@
+package cassette
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// searchResult is a struct-valued tool result, like the pages of the
+// tourism search tools. Its fields are not in alphabetical order.
+type searchResult struct {
+	Total int    `json:"total"`
+	Name  string `json:"name"`
+	Area  string `json:"area"`
+}
+
+// conversation returns the two calls of a tool-calling run on date: the
+// first asks for hotels, the second carries the search result
+func conversation(date string) (first, second []*mock.Message) {
+	system := &mock.Message{Role: "system", Content: "你是杭州旅游助手。今天是" + date}
+	user := &mock.Message{Role: "user", Content: "西湖附近有什么酒店？"}
+	call := &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{{
+		ID:   "call_0",
+		Name: "search_hotels",
+		Args: map[string]interface{}{"radius": 5},
+	}}}
+	result := &mock.Message{
+		Role:       "tool",
+		Name:       "search_hotels",
+		ToolCallID: "call_0",
+		ToolResult: map[string]interface{}{
+			"hotels": []searchResult{{Total: 1, Name: "西湖国宾馆", Area: "西湖区"}},
+		},
+	}
+	return []*mock.Message{system, user}, []*mock.Message{system, user, call, result}
+}
+
+// record runs the conversation through a recorder and returns the cassette path
+func record(t *testing.T) string {
+	t.Helper()
+	fake := mock.NewFakeChatModel()
+	fake.On().WhenRole("user").ReplyToolCalls(mock.ToolCall{Name: "search_hotels", Args: map[string]interface{}{"radius": 5}})
+	fake.On().WhenToolResult("search_hotels").ReplyText("推荐西湖国宾馆")
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	recorder := NewRecorder(fake, path)
+	tool := mock.NewMockTool("search_hotels", "搜索酒店", nil)
+	if err := recorder.BindTools([]mock.Tool{tool}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+
+	ctx := mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0)})
+	first, second := conversation("2024-02-18")
+	if _, err := recorder.Generate(ctx, first); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	stream, err := recorder.Stream(ctx, second)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if reply, err := mock.CollectStream(stream); err != nil || reply.Content != "推荐西湖国宾馆" {
+		t.Fatalf("recorded stream = %+v, %v", reply, err)
+	}
+	return path
+}
+
+// replayer loads the cassette at path with the tool bound
+func replayer(t *testing.T, path string, mode MatchMode) *Replayer {
+	t.Helper()
+	r, err := NewReplayer(path, WithMatchMode(mode))
+	if err != nil {
+		t.Fatalf("NewReplayer: %v", err)
+	}
+	if err := r.BindTools([]mock.Tool{mock.NewMockTool("search_hotels", "搜索酒店", nil)}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	return r
+}
+
+func TestRecordedCassette(t *testing.T) {
+	c, err := Load(record(t))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(c.Interactions) != 2 {
+		t.Fatalf("recorded %d interactions, want 2", len(c.Interactions))
+	}
+	req := c.Interactions[1].Request
+	if len(req.Messages) != 4 || len(req.Tools) != 1 || req.Tools[0].Name != "search_hotels" {
+		t.Errorf("second request = %+v", req)
+	}
+	if req.Options.Temperature == nil || *req.Options.Temperature != 0 {
+		t.Errorf("options = %+v, want temperature 0", req.Options)
+	}
+}
+
+func TestStrictReplay(t *testing.T) {
+	r := replayer(t, record(t), MatchStrict)
+	ctx := mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0)})
+	first, second := conversation("2024-02-18")
+
+	reply, err := r.Generate(ctx, first)
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if len(reply.ToolCalls) != 1 || reply.ToolCalls[0].Name != "search_hotels" {
+		t.Errorf("first reply = %+v", reply)
+	}
+
+	// The live tool result holds structs, the recorded one decoded maps
+	stream, err := r.Stream(ctx, second)
+	if err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+	reply, err = mock.CollectStream(stream)
+	if err != nil || reply.Content != "推荐西湖国宾馆" {
+		t.Errorf("second reply = %+v, %v", reply, err)
+	}
+	if r.Remaining() != 0 {
+		t.Errorf("%d interactions left, want 0", r.Remaining())
+	}
+}
+
+func TestStrictReplayRejectsChanges(t *testing.T) {
+	path := record(t)
+	first, _ := conversation("2024-02-18")
+	nextDay, _ := conversation("2024-02-19")
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		messages []*mock.Message
+		reason   string
+	}{
+		{
+			name:     "content",
+			ctx:      mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0)}),
+			messages: nextDay,
+			reason:   "differs at message 1: content",
+		},
+		{
+			name:     "options",
+			ctx:      mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0.5)}),
+			messages: first,
+			reason:   "recorded with options",
+		},
+		{
+			name:     "tools",
+			ctx:      mock.WithoutTools(mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0)})),
+			messages: first,
+			reason:   "recorded with tools",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := replayer(t, path, MatchStrict)
+			_, err := r.Generate(tt.ctx, tt.messages)
+			if !errors.Is(err, ErrUnmatched) {
+				t.Fatalf("error = %v, want ErrUnmatched", err)
+			}
+			var unmatched *UnmatchedError
+			if !errors.As(err, &unmatched) || unmatched.Call != 1 {
+				t.Fatalf("error = %#v, want an UnmatchedError for call 1", err)
+			}
+			if !strings.Contains(unmatched.Reason, tt.reason) {
+				t.Errorf("reason = %q, want it to mention %q", unmatched.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestFuzzyReplay(t *testing.T) {
+	r := replayer(t, record(t), MatchFuzzy)
+	// Another date and other options still match
+	ctx := mock.WithOptions(context.Background(), mock.Options{Temperature: mock.Float64(0.9)})
+	first, second := conversation("2025-10-01")
+
+	if _, err := r.Generate(ctx, first); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	reply, err := r.Generate(ctx, second)
+	if err != nil || reply.Content != "推荐西湖国宾馆" {
+		t.Fatalf("second reply = %+v, %v", reply, err)
+	}
+
+	// Every interaction is served once
+	_, err = r.Generate(ctx, first)
+	var unmatched *UnmatchedError
+	if !errors.As(err, &unmatched) || unmatched.Call != 3 || !strings.Contains(unmatched.Reason, "all recorded interactions have been served") {
+		t.Errorf("third call error = %v", err)
+	}
+}
+
+func TestFuzzyReplayRejectsOtherWords(t *testing.T) {
+	r := replayer(t, record(t), MatchFuzzy)
+	_, err := r.Generate(context.Background(), []*mock.Message{
+		{Role: "system", Content: "你是杭州旅游助手。今天是2024-02-18"},
+		{Role: "user", Content: "灵隐寺附近有什么餐厅？"},
+	})
+	if !errors.Is(err, ErrUnmatched) || !strings.Contains(err.Error(), "differs at message 2") {
+		t.Errorf("error = %v, want a mismatch at message 2", err)
+	}
+}
@@ -0,0 +1,177 @@
+package cassette
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Recorder forwards calls to a model and records every completed call into a
+// cassette file. The file is rewritten after each call, so a recording that
+// is interrupted keeps the calls made so far.
+type Recorder struct {
+	model mock.ChatModel
+	tape  *tape
+
+	mu    sync.Mutex
+	tools []mock.Tool
+}
+
+// tape is the cassette being recorded, shared by the recorders of one file
+type tape struct {
+	path string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder writing to path. An existing cassette at
+// path is overwritten by the first recorded call.
+func NewRecorder(model mock.ChatModel, path string) *Recorder {
+	return &Recorder{model: model, tape: &tape{path: path}}
+}
+
+// Wrap creates a recorder of another model that records into the same
+// cassette, in call order, e.g. for the models of a router
+func (r *Recorder) Wrap(model mock.ChatModel) *Recorder {
+	return &Recorder{model: model, tape: r.tape}
+}
+
+// Unwrap returns the recorded model
+func (r *Recorder) Unwrap() mock.ChatModel {
+	return r.model
+}
+
+// ModelName returns the name of the recorded model, if it reports one
+func (r *Recorder) ModelName() string {
+	if named, ok := r.model.(interface{ ModelName() string }); ok {
+		return named.ModelName()
+	}
+	return ""
+}
+
+// Options returns the default generation options of the recorded model, if
+// it reports them
+func (r *Recorder) Options() mock.Options {
+	if m, ok := r.model.(interface{ Options() mock.Options }); ok {
+		return m.Options()
+	}
+	return mock.Options{}
+}
+
+// ContextLength returns the context length of the recorded model, if it
+// reports one
+func (r *Recorder) ContextLength() int {
+	if m, ok := r.model.(interface{ ContextLength() int }); ok {
+		return m.ContextLength()
+	}
+	return 0
+}
+
+// Generate calls the model and records the call and its reply
+func (r *Recorder) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	req := r.request(ctx, messages)
+	reply, err := r.model.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(req, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Stream streams the model's answer and records it once the stream is complete
+func (r *Recorder) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	req := r.request(ctx, messages)
+
+	streamer, ok := r.model.(mock.StreamingChatModel)
+	if !ok {
+		reply, err := r.model.Generate(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.record(req, reply); err != nil {
+			return nil, err
+		}
+		return &replayStream{msg: reply}, nil
+	}
+
+	stream, err := streamer.Stream(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStream{stream: stream, recorder: r, request: req}, nil
+}
+
+// BindTools binds tools to the model and records them with subsequent calls
+func (r *Recorder) BindTools(tools []mock.Tool) error {
+	if err := r.model.BindTools(tools); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.tools = tools
+	r.mu.Unlock()
+	return nil
+}
+
+// request captures what is sent to the model in a call
+func (r *Recorder) request(ctx context.Context, messages []*mock.Message) Request {
+	r.mu.Lock()
+	tools := toolSpecs(r.tools)
+	r.mu.Unlock()
+	if mock.ToolsDisabled(ctx) {
+		tools = nil
+	}
+
+	return Request{
+		Model:    r.ModelName(),
+		Options:  mock.OptionsFromContext(ctx),
+		Messages: requestMessages(messages),
+		Tools:    tools,
+	}
+}
+
+// record appends an interaction and saves the cassette
+func (r *Recorder) record(req Request, reply *mock.Message) error {
+	t := r.tape
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{Request: req, Response: reply})
+	return t.cassette.Save(t.path)
+}
+
+// recordingStream passes chunks through and records the merged reply when
+// the stream completes
+type recordingStream struct {
+	stream    mock.MessageStream
+	recorder  *Recorder
+	request   Request
+	collector mock.StreamCollector
+	recorded  bool
+}
+
+func (s *recordingStream) Recv() (*mock.Message, error) {
+	chunk, err := s.stream.Recv()
+	if errors.Is(err, io.EOF) {
+		if !s.recorded {
+			s.recorded = true
+			if err := s.recorder.record(s.request, s.collector.Message()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.collector.Add(chunk)
+	return chunk, nil
+}
+
+func (s *recordingStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,310 @@
+package cassette
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrUnmatched matches the error returned when no recorded interaction
+// matches a call
+var ErrUnmatched = errors.New("cassette: no recorded interaction matches the request")
+
+// MatchMode controls how calls are matched against recorded interactions
+type MatchMode int
+
+const (
+	// MatchStrict requires identical messages, tool calls, tool results,
+	// bound tools and generation options
+	MatchStrict MatchMode = iota
+	// MatchFuzzy compares the roles and tool call names of the messages and
+	// their content with whitespace collapsed and numbers masked, so prompts
+	// that embed today's date or generated IDs still match. Options and
+	// bound tools are ignored.
+	MatchFuzzy
+)
+
+// UnmatchedError describes a call that matched no recorded interaction
+type UnmatchedError struct {
+	// Call is the 1-based number of the call made to the replayer
+	Call int
+	// Reason explains how the closest recorded interaction differs
+	Reason string
+}
+
+// Error implements the error interface
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("%v (call %d): %s", ErrUnmatched, e.Call, e.Reason)
+}
+
+// Is reports whether target is ErrUnmatched
+func (e *UnmatchedError) Is(target error) bool {
+	return target == ErrUnmatched
+}
+
+// Replayer is a mock.StreamingChatModel answering calls from a cassette.
+// Every recorded interaction is served at most once, in recording order
+// among equally matching interactions.
+type Replayer struct {
+	mode         MatchMode
+	interactions []Interaction
+
+	mu    sync.Mutex
+	used  []bool
+	calls int
+	tools []mock.Tool
+}
+
+// ReplayOption configures a Replayer
+type ReplayOption func(*Replayer)
+
+// WithMatchMode sets how calls are matched, MatchStrict by default
+func WithMatchMode(mode MatchMode) ReplayOption {
+	return func(r *Replayer) {
+		r.mode = mode
+	}
+}
+
+// NewReplayer loads the cassette at path for replay
+func NewReplayer(path string, opts ...ReplayOption) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// ModelName returns the model the cassette was recorded with
+func (r *Replayer) ModelName() string {
+	if len(r.interactions) == 0 {
+		return ""
+	}
+	return r.interactions[0].Request.Model
+}
+
+// Remaining returns the number of recorded interactions not served yet
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, used := range r.used {
+		if !used {
+			n++
+		}
+	}
+	return n
+}
+
+// Generate returns the recorded reply of the first unused matching interaction
+func (r *Replayer) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	req := Request{
+		Options:  mock.OptionsFromContext(ctx),
+		Messages: requestMessages(messages),
+		Tools:    toolSpecs(r.tools),
+	}
+	if mock.ToolsDisabled(ctx) {
+		req.Tools = nil
+	}
+
+	closest, closestReason, closestPrefix := -1, "the cassette is empty", -1
+	for i, recorded := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		prefix, reason := r.compare(recorded.Request, req)
+		if reason == "" {
+			r.used[i] = true
+			reply := *recorded.Response
+			return &reply, nil
+		}
+		if prefix > closestPrefix {
+			closest, closestReason, closestPrefix = i, reason, prefix
+		}
+	}
+
+	if closest < 0 {
+		if len(r.interactions) > 0 {
+			closestReason = "all recorded interactions have been served"
+		}
+		return nil, &UnmatchedError{Call: r.calls, Reason: closestReason}
+	}
+	return nil, &UnmatchedError{
+		Call:   r.calls,
+		Reason: fmt.Sprintf("closest is interaction %d, %s", closest+1, closestReason),
+	}
+}
+
+// Stream returns the recorded reply as a single chunk
+func (r *Replayer) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	reply, err := r.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &replayStream{msg: reply}, nil
+}
+
+// BindTools records the tools for matching; they are never executed
+func (r *Replayer) BindTools(tools []mock.Tool) error {
+	r.mu.Lock()
+	r.tools = tools
+	r.mu.Unlock()
+	return nil
+}
+
+// compare returns the number of leading messages the requests share and,
+// when they do not match, how they differ
+func (r *Replayer) compare(recorded, actual Request) (int, string) {
+	shared := 0
+	for shared < len(recorded.Messages) && shared < len(actual.Messages) {
+		if reason := r.compareMessage(recorded.Messages[shared], actual.Messages[shared]); reason != "" {
+			return shared, fmt.Sprintf("which differs at message %d: %s", shared+1, reason)
+		}
+		shared++
+	}
+	if len(recorded.Messages) != len(actual.Messages) {
+		return shared, fmt.Sprintf("which has %d messages instead of %d", len(recorded.Messages), len(actual.Messages))
+	}
+	if r.mode == MatchFuzzy {
+		return shared, ""
+	}
+
+	if !jsonEqual(recorded.Options, actual.Options) {
+		return shared, fmt.Sprintf("which was recorded with options %s instead of %s", toJSON(recorded.Options), toJSON(actual.Options))
+	}
+	if !jsonEqual(recorded.Tools, actual.Tools) {
+		return shared, fmt.Sprintf("which was recorded with tools %v instead of %v", toolNames(recorded.Tools), toolNames(actual.Tools))
+	}
+	return shared, ""
+}
+
+// compareMessage describes how two messages differ, or returns ""
+func (r *Replayer) compareMessage(recorded, actual *mock.Message) string {
+	if recorded.Role != actual.Role {
+		return fmt.Sprintf("role %q instead of %q", recorded.Role, actual.Role)
+	}
+
+	if r.mode == MatchFuzzy {
+		if normalize(recorded.Content) != normalize(actual.Content) {
+			return fmt.Sprintf("content %q instead of %q", excerpt(recorded.Content), excerpt(actual.Content))
+		}
+		if len(recorded.Images) != len(actual.Images) {
+			return fmt.Sprintf("%d images instead of %d", len(recorded.Images), len(actual.Images))
+		}
+		if !reflect.DeepEqual(callNames(recorded.ToolCalls), callNames(actual.ToolCalls)) {
+			return fmt.Sprintf("tool calls %v instead of %v", callNames(recorded.ToolCalls), callNames(actual.ToolCalls))
+		}
+		return ""
+	}
+
+	if recorded.Content != actual.Content {
+		return fmt.Sprintf("content %q instead of %q", excerpt(recorded.Content), excerpt(actual.Content))
+	}
+	if !jsonEqual(recorded, actual) {
+		return fmt.Sprintf("images, tool calls or results %s instead of %s", excerpt(toJSON(recorded)), excerpt(toJSON(actual)))
+	}
+	return ""
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	numbers    = regexp.MustCompile(`[0-9]+`)
+)
+
+// normalize collapses whitespace and masks numbers for fuzzy matching
+func normalize(s string) string {
+	s = whitespace.ReplaceAllString(strings.TrimSpace(s), " ")
+	return numbers.ReplaceAllString(s, "#")
+}
+
+// excerpt shortens content for error messages
+func excerpt(s string) string {
+	const max = 80
+	if r := []rune(s); len(r) > max {
+		return string(r[:max]) + "…"
+	}
+	return s
+}
+
+// callNames returns the tool names of calls
+func callNames(calls []mock.ToolCall) []string {
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	return names
+}
+
+// toolNames returns the names of tool specs
+func toolNames(tools []ToolSpec) []string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// jsonEqual compares values by their JSON encoding, so that recorded values
+// decoded from a file compare equal to the live values they were encoded from
+func jsonEqual(a, b interface{}) bool {
+	return toJSON(a) == toJSON(b)
+}
+
+// toJSON encodes a value for comparison and error messages. The encoding is
+// decoded and encoded again so that objects have their keys sorted: live
+// tool results hold structs, encoded in field order, while their recorded
+// copies decode into maps.
+func toJSON(v interface{}) string {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(bytes, &generic); err != nil {
+		return string(bytes)
+	}
+	if bytes, err = json.Marshal(generic); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bytes)
+}
+
+// replayStream returns a reply as a single chunk
+type replayStream struct {
+	msg  *mock.Message
+	done bool
+}
+
+func (s *replayStream) Recv() (*mock.Message, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	s.done = true
+	return s.msg, nil
+}
+
+func (s *replayStream) Close() error {
+	return nil
+}
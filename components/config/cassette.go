@@ -0,0 +1,53 @@
+package config
+
+import (
+	"deepllm/components/cassette"
+	"fmt"
+	"strings"
+)
+
+// Cassette modes
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// CassetteConfig configures recording model calls to, or replaying them
+// from, a cassette file
+type CassetteConfig struct {
+	Path  string
+	Mode  string
+	Match cassette.MatchMode
+}
+
+// CassetteConfigFromEnv reads LLM_CASSETTE (the cassette file),
+// LLM_CASSETTE_MODE ("record" or "replay", default replay) and
+// LLM_CASSETTE_MATCH ("strict" or "fuzzy", default strict)
+func CassetteConfigFromEnv() (CassetteConfig, error) {
+	var cfg CassetteConfig
+	var ok bool
+	if cfg.Path, ok = lookupEnv("LLM_CASSETTE"); !ok {
+		return cfg, nil
+	}
+
+	mode, _ := lookupEnv("LLM_CASSETTE_MODE")
+	switch strings.ToLower(mode) {
+	case "", CassetteReplay:
+		cfg.Mode = CassetteReplay
+	case CassetteRecord:
+		cfg.Mode = CassetteRecord
+	default:
+		return cfg, fmt.Errorf("invalid LLM_CASSETTE_MODE %q, expected record or replay", mode)
+	}
+
+	match, _ := lookupEnv("LLM_CASSETTE_MATCH")
+	switch strings.ToLower(match) {
+	case "", "strict":
+		cfg.Match = cassette.MatchStrict
+	case "fuzzy":
+		cfg.Match = cassette.MatchFuzzy
+	default:
+		return cfg, fmt.Errorf("invalid LLM_CASSETTE_MATCH %q, expected strict or fuzzy", match)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"deepllm/components/mock"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ModelOptionsFromEnv reads the global model generation options from the
+// environment (MODEL_TEMPERATURE, MODEL_TOP_P, MODEL_MAX_TOKENS, MODEL_NUM_CTX,
+// MODEL_SEED, MODEL_STOP and MODEL_KEEP_ALIVE). Unset variables are left unset.
+func ModelOptionsFromEnv() (mock.Options, error) {
+	var opts mock.Options
+
+	if v, ok := lookupEnv("MODEL_TEMPERATURE"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid MODEL_TEMPERATURE: %v", err)
+		}
+		opts.Temperature = &f
+	}
+	if v, ok := lookupEnv("MODEL_TOP_P"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid MODEL_TOP_P: %v", err)
+		}
+		opts.TopP = &f
+	}
+	if v, ok := lookupEnv("MODEL_MAX_TOKENS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid MODEL_MAX_TOKENS: %v", err)
+		}
+		opts.NumPredict = n
+	}
+	if v, ok := lookupEnv("MODEL_NUM_CTX"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid MODEL_NUM_CTX: %v", err)
+		}
+		opts.NumCtx = n
+	}
+	if v, ok := lookupEnv("MODEL_SEED"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid MODEL_SEED: %v", err)
+		}
+		opts.Seed = &n
+	}
+	if v, ok := lookupEnv("MODEL_STOP"); ok {
+		for _, stop := range strings.Split(v, ",") {
+			if stop = strings.TrimSpace(stop); stop != "" {
+				opts.Stop = append(opts.Stop, stop)
+			}
+		}
+	}
+	if v, ok := lookupEnv("MODEL_KEEP_ALIVE"); ok {
+		opts.KeepAlive = v
+	}
+
+	return opts, nil
+}
+
+// ReasoningModeFromEnv reads MODEL_REASONING ("keep", "drop" or "log") and
+// defaults to keeping reasoning traces apart from the answer
+func ReasoningModeFromEnv() (mock.ReasoningMode, error) {
+	v, _ := lookupEnv("MODEL_REASONING")
+	switch strings.ToLower(v) {
+	case "", "keep":
+		return mock.ReasoningKeep, nil
+	case "drop":
+		return mock.ReasoningDrop, nil
+	case "log":
+		return mock.ReasoningLog, nil
+	default:
+		return mock.ReasoningKeep, fmt.Errorf("invalid MODEL_REASONING %q, expected keep, drop or log", v)
+	}
+}
+
+// lookupEnv returns the trimmed value of a non-empty environment variable
+func lookupEnv(key string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(key))
+	return v, v != ""
+}
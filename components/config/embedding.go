@@ -0,0 +1,37 @@
+package config
+
+import (
+	"deepllm/components/ollama"
+	"deepllm/internal/data"
+)
+
+// hashEmbedderDim is the dimension of the offline hash embedder
+const hashEmbedderDim = 256
+
+// EmbeddingConfig configures semantic search over the dataset
+type EmbeddingConfig struct {
+	BaseURL string
+	// Model is the Ollama embedding model; empty selects the offline hash embedder
+	Model string
+	// IndexPath persists the embedding index; empty keeps it in memory
+	IndexPath string
+}
+
+// EmbeddingConfigFromEnv reads EMBEDDING_MODEL (e.g. nomic-embed-text or
+// bge-m3, served by OLLAMA_BASE_URL) and EMBEDDING_INDEX
+func EmbeddingConfigFromEnv() EmbeddingConfig {
+	cfg := EmbeddingConfig{
+		BaseURL: envOrDefault("OLLAMA_BASE_URL", "http://localhost:11434"),
+	}
+	cfg.Model, _ = lookupEnv("EMBEDDING_MODEL")
+	cfg.IndexPath, _ = lookupEnv("EMBEDDING_INDEX")
+	return cfg
+}
+
+// NewEmbedder creates the embedder described by cfg
+func NewEmbedder(cfg EmbeddingConfig) data.Embedder {
+	if cfg.Model == "" {
+		return data.NewHashEmbedder(hashEmbedderDim)
+	}
+	return ollama.NewEmbedder(cfg.BaseURL, cfg.Model)
+}
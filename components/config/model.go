@@ -0,0 +1,243 @@
+package config
+
+import (
+	"deepllm/components/cache"
+	"deepllm/components/cassette"
+	"deepllm/components/mock"
+	"deepllm/components/ollama"
+	"deepllm/components/openai"
+	"deepllm/components/router"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported chat model backends
+const (
+	BackendOllama = "ollama"
+	BackendOpenAI = "openai"
+)
+
+// ModelConfig describes which chat model backend to use and how to reach it
+type ModelConfig struct {
+	Backend       string
+	BaseURL       string
+	Model         string
+	APIKey        string
+	Options       mock.Options
+	ReasoningMode mock.ReasoningMode
+	// AutoPull lets Preflight download a missing Ollama model
+	AutoPull bool
+	// MaxConcurrency limits the concurrent Ollama generation requests; 0 is
+	// unlimited
+	MaxConcurrency int
+	// Warmup lets Preflight load the Ollama model into memory
+	Warmup bool
+	// Cache enables the response cache when its Dir is set
+	Cache CacheConfig
+	// Cassette records or replays model calls when its Path is set
+	Cassette CassetteConfig
+	// Routes gives agents their own models
+	Routes RoutesConfig
+}
+
+// CacheConfig configures the on-disk response cache
+type CacheConfig struct {
+	Dir        string
+	TTL        time.Duration
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// ModelConfigFromEnv reads the chat model configuration from the environment.
+// LLM_BACKEND selects "ollama" (default, OLLAMA_BASE_URL and OLLAMA_MODEL) or
+// "openai" for OpenAI-compatible servers such as vLLM and llama.cpp
+// (OPENAI_BASE_URL, OPENAI_MODEL and OPENAI_API_KEY).
+func ModelConfigFromEnv() (ModelConfig, error) {
+	var cfg ModelConfig
+
+	backend, _ := lookupEnv("LLM_BACKEND")
+	cfg.Backend = strings.ToLower(backend)
+	switch cfg.Backend {
+	case "", BackendOllama:
+		cfg.Backend = BackendOllama
+		cfg.BaseURL = envOrDefault("OLLAMA_BASE_URL", "http://localhost:11434")
+		cfg.Model = envOrDefault("OLLAMA_MODEL", "deepseek-r1:14b")
+		if v, ok := lookupEnv("OLLAMA_AUTO_PULL"); ok {
+			autoPull, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid OLLAMA_AUTO_PULL: %v", err)
+			}
+			cfg.AutoPull = autoPull
+		}
+		if v, ok := lookupEnv("OLLAMA_MAX_CONCURRENCY"); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("invalid OLLAMA_MAX_CONCURRENCY %q", v)
+			}
+			cfg.MaxConcurrency = n
+		}
+		if v, ok := lookupEnv("OLLAMA_WARMUP"); ok {
+			warmup, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid OLLAMA_WARMUP: %v", err)
+			}
+			cfg.Warmup = warmup
+		}
+	case BackendOpenAI:
+		cfg.BaseURL = envOrDefault("OPENAI_BASE_URL", "http://localhost:8000/v1")
+		cfg.Model, _ = lookupEnv("OPENAI_MODEL")
+		cfg.APIKey, _ = lookupEnv("OPENAI_API_KEY")
+		if cfg.Model == "" {
+			return cfg, fmt.Errorf("OPENAI_MODEL is required when LLM_BACKEND=openai")
+		}
+	default:
+		return cfg, fmt.Errorf("invalid LLM_BACKEND %q, expected ollama or openai", backend)
+	}
+
+	var err error
+	if cfg.Options, err = ModelOptionsFromEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.ReasoningMode, err = ReasoningModeFromEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.Cache, err = CacheConfigFromEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.Cassette, err = CassetteConfigFromEnv(); err != nil {
+		return cfg, err
+	}
+	if cfg.Routes, err = RoutesConfigFromEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// CacheConfigFromEnv reads the response cache configuration: LLM_CACHE_DIR
+// enables the cache, LLM_CACHE_TTL (a duration such as 24h),
+// LLM_CACHE_MAX_ENTRIES and LLM_CACHE_MAX_BYTES limit it.
+func CacheConfigFromEnv() (CacheConfig, error) {
+	var cfg CacheConfig
+	cfg.Dir, _ = lookupEnv("LLM_CACHE_DIR")
+
+	if v, ok := lookupEnv("LLM_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_CACHE_TTL: %v", err)
+		}
+		cfg.TTL = ttl
+	}
+	if v, ok := lookupEnv("LLM_CACHE_MAX_ENTRIES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_CACHE_MAX_ENTRIES: %v", err)
+		}
+		cfg.MaxEntries = n
+	}
+	if v, ok := lookupEnv("LLM_CACHE_MAX_BYTES"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_CACHE_MAX_BYTES: %v", err)
+		}
+		cfg.MaxBytes = n
+	}
+	return cfg, nil
+}
+
+// NewChatModel creates the chat model described by cfg. A cassette in replay
+// mode replaces the backend entirely, for every agent; otherwise the backend
+// is wrapped in the response cache when cfg.Cache.Dir is set and in a
+// cassette recorder when recording, which sees every call including cache
+// hits. With cfg.Routes the result is a router.Router whose routed models
+// are wrapped the same way and record into the same cassette.
+func NewChatModel(cfg ModelConfig) (mock.ChatModel, error) {
+	if cfg.Cassette.Path != "" && cfg.Cassette.Mode == CassetteReplay {
+		return cassette.NewReplayer(cfg.Cassette.Path, cassette.WithMatchMode(cfg.Cassette.Match))
+	}
+
+	var store cache.Store
+	if cfg.Cache.Dir != "" {
+		var err error
+		store, err = cache.NewDiskStore(cfg.Cache.Dir,
+			cache.WithTTL(cfg.Cache.TTL),
+			cache.WithMaxEntries(cfg.Cache.MaxEntries),
+			cache.WithMaxBytes(cfg.Cache.MaxBytes),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var recorder *cassette.Recorder
+	newModel := func(cfg ModelConfig) (mock.ChatModel, error) {
+		chatModel, err := newBackend(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if store != nil {
+			chatModel = cache.NewChatModel(chatModel, store)
+		}
+		if cfg.Cassette.Path != "" && cfg.Cassette.Mode == CassetteRecord {
+			if recorder == nil {
+				recorder = cassette.NewRecorder(chatModel, cfg.Cassette.Path)
+				return recorder, nil
+			}
+			return recorder.Wrap(chatModel), nil
+		}
+		return chatModel, nil
+	}
+
+	chatModel, err := newModel(cfg)
+	if err != nil || len(cfg.Routes) == 0 {
+		return chatModel, err
+	}
+
+	models := map[string]mock.ChatModel{modelKey(cfg): chatModel}
+	r := router.New(chatModel)
+	for _, agent := range cfg.Routes.Agents() {
+		var chain []mock.ChatModel
+		for _, route := range cfg.Routes[agent] {
+			routeCfg := cfg.WithRoute(route)
+			model, ok := models[modelKey(routeCfg)]
+			if !ok {
+				if model, err = newModel(routeCfg); err != nil {
+					return nil, fmt.Errorf("failed to create model of agent %s: %v", agent, err)
+				}
+				models[modelKey(routeCfg)] = model
+			}
+			chain = append(chain, model)
+		}
+		r.Route(agent, chain...)
+	}
+	return r, nil
+}
+
+// newBackend creates the chat model of the configured backend
+func newBackend(cfg ModelConfig) (mock.ChatModel, error) {
+	switch cfg.Backend {
+	case "", BackendOllama:
+		return ollama.NewChatModel(cfg.BaseURL, cfg.Model,
+			ollama.WithDefaultOptions(cfg.Options),
+			ollama.WithReasoningMode(cfg.ReasoningMode),
+			ollama.WithConcurrencyLimit(cfg.MaxConcurrency),
+		), nil
+	case BackendOpenAI:
+		return openai.NewChatModel(cfg.BaseURL, cfg.Model,
+			openai.WithAPIKey(cfg.APIKey),
+			openai.WithDefaultOptions(cfg.Options),
+			openai.WithReasoningMode(cfg.ReasoningMode),
+		), nil
+	default:
+		return nil, fmt.Errorf("unsupported backend %q", cfg.Backend)
+	}
+}
+
+// envOrDefault returns the value of an environment variable or a default
+func envOrDefault(key, fallback string) string {
+	if v, ok := lookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"deepllm/components/ollama"
+	"deepllm/components/router"
+	"log"
+	"time"
+)
+
+// Preflight checks that the chat model created from cfg is ready to serve
+// requests, so that programs fail at startup with an actionable message
+// instead of deep inside an agent. Only Ollama models are checked; a missing
+// model is pulled when cfg.AutoPull is set, logging the download progress,
+// and the model is loaded into memory when cfg.Warmup is set. With a router
+// every routed model is checked.
+func Preflight(ctx context.Context, cfg ModelConfig, chatModel mock.ChatModel) error {
+	// Check every model of a router
+	if r, ok := chatModel.(*router.Router); ok {
+		for _, model := range r.Models() {
+			if err := Preflight(ctx, cfg, model); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Look through decorators such as the response cache
+	for {
+		wrapper, ok := chatModel.(interface{ Unwrap() mock.ChatModel })
+		if !ok {
+			break
+		}
+		chatModel = wrapper.Unwrap()
+	}
+
+	m, ok := chatModel.(*ollama.ChatModel)
+	if !ok {
+		return nil
+	}
+
+	status, err := m.Preflight(ctx, cfg.AutoPull, pullProgressLogger(m.ModelName()))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Ollama %s, model %s (%s %s), context length %d",
+		status.Version, status.Model, status.Details.ParameterSize,
+		status.Details.QuantizationLevel, status.ContextLength)
+	if cfg.Options.NumCtx > 0 && status.ContextLength > 0 && cfg.Options.NumCtx > status.ContextLength {
+		log.Printf("MODEL_NUM_CTX=%d exceeds the context length %d of %s",
+			cfg.Options.NumCtx, status.ContextLength, status.Model)
+	}
+
+	if cfg.Warmup {
+		start := time.Now()
+		if err := m.Warmup(ctx); err != nil {
+			return err
+		}
+		log.Printf("model %s loaded in %v", status.Model, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// pullProgressLogger logs pull status changes and every 10% of a download
+func pullProgressLogger(model string) func(ollama.PullProgress) {
+	lastStatus, lastPercent := "", -1
+	return func(p ollama.PullProgress) {
+		percent := -1
+		if p.Total > 0 {
+			percent = int(p.Completed * 100 / p.Total)
+		}
+		if p.Status == lastStatus && (percent < 0 || percent/10 == lastPercent/10) {
+			return
+		}
+		if p.Status != lastStatus {
+			lastPercent = -1
+		}
+		lastStatus, lastPercent = p.Status, percent
+
+		if percent >= 0 {
+			log.Printf("pulling %s: %s %d%%", model, p.Status, percent)
+		} else {
+			log.Printf("pulling %s: %s", model, p.Status)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"deepllm/components/router"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RouteConfig is a model an agent is routed to. Empty fields are taken from
+// the default model configuration, or from the environment variables of
+// Backend when it differs from the default backend.
+type RouteConfig struct {
+	Backend string `json:"backend,omitempty"`
+	BaseURL string `json:"base_url,omitempty"`
+	Model   string `json:"model"`
+	APIKey  string `json:"api_key,omitempty"`
+}
+
+// RoutesConfig maps agent names to their model followed by its fallbacks
+type RoutesConfig map[string][]RouteConfig
+
+// routedAgents are the agents whose AGENT_MODEL_* variable is read
+var routedAgents = []string{
+	router.AgentWeather,
+	router.AgentDining,
+	router.AgentAccommodation,
+	router.AgentPlanner,
+	router.AgentCoordinator,
+}
+
+// RoutesConfigFromEnv reads the per-agent models. MODEL_ROUTES names a JSON
+// file such as
+//
+//	{"weather": [{"model": "qwen2.5:3b"}, {"model": "qwen2.5:7b"}],
+//	 "coordinator": [{"backend": "openai", "model": "gpt-4o-mini"}]}
+//
+// and AGENT_MODEL_<AGENT>, e.g. AGENT_MODEL_WEATHER=qwen2.5:3b,qwen2.5:7b,
+// routes an agent to comma separated models of the default backend,
+// replacing its routes from the file.
+func RoutesConfigFromEnv() (RoutesConfig, error) {
+	routes := RoutesConfig{}
+	if path, ok := lookupEnv("MODEL_ROUTES"); ok {
+		var err error
+		if routes, err = LoadRoutesConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, agent := range routedAgents {
+		v, ok := lookupEnv("AGENT_MODEL_" + strings.ToUpper(agent))
+		if !ok {
+			continue
+		}
+		var chain []RouteConfig
+		for _, model := range strings.Split(v, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				chain = append(chain, RouteConfig{Model: model})
+			}
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("invalid AGENT_MODEL_%s: no model", strings.ToUpper(agent))
+		}
+		routes[agent] = chain
+	}
+	return routes, nil
+}
+
+// LoadRoutesConfig reads a routes file, see RoutesConfigFromEnv
+func LoadRoutesConfig(path string) (RoutesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading model routes: %v", err)
+	}
+
+	var routes RoutesConfig
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("error unmarshaling model routes %s: %v", path, err)
+	}
+	for agent, chain := range routes {
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("model routes %s: no model for agent %s", path, agent)
+		}
+		for _, route := range chain {
+			if route.Model == "" {
+				return nil, fmt.Errorf("model routes %s: route of agent %s without model", path, agent)
+			}
+			switch route.Backend {
+			case "", BackendOllama, BackendOpenAI:
+			default:
+				return nil, fmt.Errorf("model routes %s: invalid backend %q for agent %s", path, route.Backend, agent)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// Agents returns the names of the routed agents, sorted
+func (r RoutesConfig) Agents() []string {
+	agents := make([]string, 0, len(r))
+	for agent := range r {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// WithRoute returns the configuration of the model of route
+func (cfg ModelConfig) WithRoute(route RouteConfig) ModelConfig {
+	if route.Backend != "" && route.Backend != cfg.Backend {
+		cfg.Backend = route.Backend
+		switch route.Backend {
+		case BackendOllama:
+			cfg.BaseURL = envOrDefault("OLLAMA_BASE_URL", "http://localhost:11434")
+			cfg.APIKey = ""
+		case BackendOpenAI:
+			cfg.BaseURL = envOrDefault("OPENAI_BASE_URL", "http://localhost:8000/v1")
+			cfg.APIKey, _ = lookupEnv("OPENAI_API_KEY")
+		}
+	}
+	if route.BaseURL != "" {
+		cfg.BaseURL = route.BaseURL
+	}
+	if route.APIKey != "" {
+		cfg.APIKey = route.APIKey
+	}
+	cfg.Model = route.Model
+	cfg.Routes = nil
+	return cfg
+}
+
+// modelKey identifies the backend model of cfg, so that agents routed to
+// the same model share one client
+func modelKey(cfg ModelConfig) string {
+	return strings.Join([]string{cfg.Backend, cfg.BaseURL, cfg.Model, cfg.APIKey}, "|")
+}
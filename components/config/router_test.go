@@ -0,0 +1,148 @@
+package config
+
+import (
+	"deepllm/components/router"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeRoutes writes a routes file and returns its path
+func writeRoutes(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRoutesConfig(t *testing.T) {
+	path := writeRoutes(t, `{
+		"weather": [{"model": "qwen2.5:3b"}, {"model": "qwen2.5:7b"}],
+		"coordinator": [{"backend": "openai", "base_url": "http://vllm:8000/v1", "model": "qwen2.5-14b"}]
+	}`)
+	routes, err := LoadRoutesConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRoutesConfig: %v", err)
+	}
+	want := RoutesConfig{
+		"weather":     {{Model: "qwen2.5:3b"}, {Model: "qwen2.5:7b"}},
+		"coordinator": {{Backend: BackendOpenAI, BaseURL: "http://vllm:8000/v1", Model: "qwen2.5-14b"}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes = %+v, want %+v", routes, want)
+	}
+	if agents := routes.Agents(); !reflect.DeepEqual(agents, []string{"coordinator", "weather"}) {
+		t.Errorf("Agents = %v", agents)
+	}
+}
+
+func TestLoadRoutesConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"malformed JSON", `{"weather": [{"model": "qwen2.5:3b"}`, "error unmarshaling model routes"},
+		{"not a chain", `{"weather": {"model": "qwen2.5:3b"}}`, "error unmarshaling model routes"},
+		{"empty chain", `{"weather": []}`, "no model for agent weather"},
+		{"route without model", `{"dining": [{"model": "qwen2.5:3b"}, {"backend": "ollama"}]}`, "route of agent dining without model"},
+		{"unknown backend", `{"planner": [{"backend": "vertex", "model": "gemini"}]}`, `invalid backend "vertex" for agent planner`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRoutesConfig(writeRoutes(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadRoutesConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "error reading model routes") {
+		t.Errorf("missing file error = %v", err)
+	}
+}
+
+func TestRoutesConfigFromEnv(t *testing.T) {
+	t.Setenv("MODEL_ROUTES", writeRoutes(t, `{
+		"weather": [{"model": "qwen2.5:3b"}],
+		"planner": [{"model": "qwen2.5:14b"}]
+	}`))
+	for _, agent := range routedAgents {
+		t.Setenv("AGENT_MODEL_"+strings.ToUpper(agent), "")
+	}
+	// The variable replaces the route from the file
+	t.Setenv("AGENT_MODEL_WEATHER", " qwen2.5:1.5b, ,qwen2.5:3b ")
+	t.Setenv("AGENT_MODEL_DINING", "qwen2.5:7b")
+
+	routes, err := RoutesConfigFromEnv()
+	if err != nil {
+		t.Fatalf("RoutesConfigFromEnv: %v", err)
+	}
+	want := RoutesConfig{
+		"weather": {{Model: "qwen2.5:1.5b"}, {Model: "qwen2.5:3b"}},
+		"planner": {{Model: "qwen2.5:14b"}},
+		"dining":  {{Model: "qwen2.5:7b"}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes = %+v, want %+v", routes, want)
+	}
+
+	t.Setenv("AGENT_MODEL_DINING", ",")
+	if _, err := RoutesConfigFromEnv(); err == nil || !strings.Contains(err.Error(), "AGENT_MODEL_DINING") {
+		t.Errorf("error = %v, want an invalid AGENT_MODEL_DINING", err)
+	}
+	t.Setenv("MODEL_ROUTES", writeRoutes(t, `{"weather": [`))
+	if _, err := RoutesConfigFromEnv(); err == nil {
+		t.Error("a malformed MODEL_ROUTES file did not fail")
+	}
+}
+
+func TestNewChatModelWithRoutes(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "http://vllm:8000/v1")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	cfg := ModelConfig{
+		Backend: BackendOllama,
+		BaseURL: "http://localhost:11434",
+		Model:   "qwen2.5:14b",
+		Routes: RoutesConfig{
+			"weather":     {{Model: "qwen2.5:3b"}, {Model: "qwen2.5:14b"}},
+			"dining":      {{Model: "qwen2.5:3b"}},
+			"coordinator": {{Backend: BackendOpenAI, Model: "qwen2.5-72b"}},
+		},
+	}
+
+	routeCfg := cfg.WithRoute(cfg.Routes["coordinator"][0])
+	if routeCfg.Backend != BackendOpenAI || routeCfg.BaseURL != "http://vllm:8000/v1" || routeCfg.APIKey != "sk-test" || routeCfg.Routes != nil {
+		t.Errorf("coordinator config = %+v", routeCfg)
+	}
+
+	chatModel, err := NewChatModel(cfg)
+	if err != nil {
+		t.Fatalf("NewChatModel: %v", err)
+	}
+	r, ok := chatModel.(*router.Router)
+	if !ok {
+		t.Fatalf("model = %T, want a router", chatModel)
+	}
+	for agent, want := range map[string][]string{
+		"weather":     {"qwen2.5:3b", "qwen2.5:14b"},
+		"dining":      {"qwen2.5:3b"},
+		"coordinator": {"qwen2.5-72b"},
+		"planner":     {"qwen2.5:14b"},
+	} {
+		if names := r.ModelNames(agent); !reflect.DeepEqual(names, want) {
+			t.Errorf("models of %s = %v, want %v", agent, names, want)
+		}
+	}
+	// Agents routed to the same model share one client
+	if models := r.Models(); len(models) != 3 {
+		t.Errorf("router has %d distinct models, want 3", len(models))
+	}
+	if chain, ok := r.ModelFor("weather").(*router.Fallback); !ok || chain.Models()[0] != r.ModelFor("dining") || chain.Models()[1] != r.Default() {
+		t.Error("the weather chain does not reuse the dining and default models")
+	}
+}
@@ -2,14 +2,39 @@ package mock
 
 import (
 	"context"
+	"time"
 )
 
-// Message represents a chat message
+// Message represents a chat message. Tool results are sent back with the
+// "tool" role, the name of the tool and the ID of the call they answer.
+// Reasoning holds the model's reasoning trace, kept apart from Content so
+// that only the final answer is forwarded to other agents. Images are sent
+// to multimodal models along with Content.
 type Message struct {
 	Role       string                 `json:"role"`
 	Content    string                 `json:"content"`
+	Images     []Image                `json:"images,omitempty"`
+	Reasoning  string                 `json:"reasoning,omitempty"`
+	Name       string                 `json:"name,omitempty"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
 	ToolCalls  []ToolCall             `json:"tool_calls,omitempty"`
 	ToolResult map[string]interface{} `json:"tool_result,omitempty"`
+	Meta       *ResponseMeta          `json:"meta,omitempty"`
+}
+
+// ResponseMeta carries generation metadata reported by the model backend
+type ResponseMeta struct {
+	Model         string        `json:"model,omitempty"`
+	FinishReason  string        `json:"finish_reason,omitempty"`
+	TotalDuration time.Duration `json:"total_duration,omitempty"`
+	Usage         TokenUsage    `json:"usage"`
+}
+
+// TokenUsage reports the number of tokens consumed by a generation
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // ToolCall represents a tool call request
@@ -26,10 +51,25 @@ type ChatModel interface {
 	BindTools(tools []Tool) error
 }
 
-// Tool represents a tool interface
+// MessageStream represents a stream of incremental message chunks.
+// Recv returns io.EOF once the stream has been fully consumed.
+type MessageStream interface {
+	Recv() (*Message, error)
+	Close() error
+}
+
+// StreamingChatModel represents a chat model that can stream its answer
+type StreamingChatModel interface {
+	ChatModel
+	Stream(ctx context.Context, messages []*Message) (MessageStream, error)
+}
+
+// Tool represents a tool interface. Parameters describes the arguments
+// accepted by Execute as a JSON schema.
 type Tool interface {
 	Name() string
 	Description() string
+	Parameters() *Schema
 	Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
 }
 
@@ -42,6 +82,7 @@ type Runnable[I any, O any] interface {
 type MockTool struct {
 	name        string
 	description string
+	parameters  *Schema
 	handler     func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
 }
 
@@ -50,10 +91,17 @@ func NewMockTool(name, description string, handler func(ctx context.Context, arg
 	return &MockTool{
 		name:        name,
 		description: description,
+		parameters:  EmptyObjectSchema(),
 		handler:     handler,
 	}
 }
 
+// WithParameters sets the parameter schema of the tool
+func (t *MockTool) WithParameters(parameters *Schema) *MockTool {
+	t.parameters = parameters
+	return t
+}
+
 // Name returns the tool's name
 func (t *MockTool) Name() string {
 	return t.name
@@ -64,6 +112,11 @@ func (t *MockTool) Description() string {
 	return t.description
 }
 
+// Parameters returns the tool's parameter schema
+func (t *MockTool) Parameters() *Schema {
+	return t.parameters
+}
+
 // Execute executes the tool with the given arguments
 func (t *MockTool) Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
 	return t.handler(ctx, args)
@@ -0,0 +1,311 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRule is returned by a FakeChatModel when no rule matches a call
+var ErrNoRule = errors.New("fake chat model: no rule matches the call")
+
+// FakeCall records a call made to a FakeChatModel
+type FakeCall struct {
+	Messages []*Message
+	Options  Options
+	// Tools holds the names of the tools offered to the model in the call:
+	// those bound at the time, none under WithoutTools
+	Tools []string
+}
+
+// LastMessage returns the last message of the call, or nil
+func (c FakeCall) LastMessage() *Message {
+	if len(c.Messages) == 0 {
+		return nil
+	}
+	return c.Messages[len(c.Messages)-1]
+}
+
+// FakeChatModel is a scriptable StreamingChatModel for tests. It answers each
+// call with the first rule that matches the last message of the call and
+// records every call for assertions. A multi-step tool-calling conversation
+// is scripted with one rule replying with tool calls to the user message and
+// another replying with text to the tool results:
+//
+//	fake := mock.NewFakeChatModel()
+//	fake.On().WhenRole("user").ReplyToolCalls(mock.ToolCall{Name: "get_weather"})
+//	fake.On().WhenRole("tool").ReplyText("Sunny all day")
+type FakeChatModel struct {
+	mu    sync.Mutex
+	rules []*Rule
+	calls []FakeCall
+	tools []string
+}
+
+// NewFakeChatModel creates a fake model without rules
+func NewFakeChatModel() *FakeChatModel {
+	return &FakeChatModel{}
+}
+
+// On adds a rule. Rules are tried in the order they were added.
+func (f *FakeChatModel) On() *Rule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r := &Rule{}
+	f.rules = append(f.rules, r)
+	return r
+}
+
+// Calls returns the calls made so far
+func (f *FakeChatModel) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeCall(nil), f.calls...)
+}
+
+// CallCount returns the number of calls made so far
+func (f *FakeChatModel) CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.calls)
+}
+
+// BindTools records the names of the tools; they are never executed
+func (f *FakeChatModel) BindTools(tools []Tool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tools = make([]string, len(tools))
+	for i, tool := range tools {
+		f.tools[i] = tool.Name()
+	}
+	return nil
+}
+
+// Generate records the call and answers it with the first matching rule
+func (f *FakeChatModel) Generate(ctx context.Context, messages []*Message) (*Message, error) {
+	rule, err := f.match(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return rule.respond(ctx, messages)
+}
+
+// Stream answers like Generate and streams the text word by word; tool calls
+// and metadata are sent with the last chunk
+func (f *FakeChatModel) Stream(ctx context.Context, messages []*Message) (MessageStream, error) {
+	reply, err := f.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return newFakeStream(ctx, reply), nil
+}
+
+// match records the call and selects the rule answering it
+func (f *FakeChatModel) match(ctx context.Context, messages []*Message) (*Rule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call := FakeCall{
+		Messages: append([]*Message(nil), messages...),
+		Options:  OptionsFromContext(ctx),
+	}
+	if !ToolsDisabled(ctx) {
+		call.Tools = append([]string(nil), f.tools...)
+	}
+	f.calls = append(f.calls, call)
+
+	var last *Message
+	if len(messages) > 0 {
+		last = messages[len(messages)-1]
+	}
+	for _, r := range f.rules {
+		if r.matches(last) {
+			r.used++
+			return r, nil
+		}
+	}
+
+	if last == nil {
+		return nil, fmt.Errorf("%w (call %d without messages)", ErrNoRule, len(f.calls))
+	}
+	return nil, fmt.Errorf("%w (call %d, last message %s: %q)", ErrNoRule, len(f.calls), last.Role, last.Content)
+}
+
+// Rule describes when a FakeChatModel answers a call and how. Conditions
+// apply to the last message of the call; a rule without conditions matches
+// every call.
+type Rule struct {
+	role     string
+	content  *regexp.Regexp
+	toolName string
+	times    int
+	used     int
+
+	reply   *Message
+	err     error
+	handler func(ctx context.Context, messages []*Message) (*Message, error)
+	latency time.Duration
+}
+
+// WhenRole matches calls whose last message has the given role
+func (r *Rule) WhenRole(role string) *Rule {
+	r.role = role
+	return r
+}
+
+// WhenContent matches calls whose last message content matches the regular
+// expression. It panics if the expression is invalid.
+func (r *Rule) WhenContent(pattern string) *Rule {
+	r.content = regexp.MustCompile(pattern)
+	return r
+}
+
+// WhenToolResult matches calls whose last message is the result of the named tool
+func (r *Rule) WhenToolResult(name string) *Rule {
+	r.role = "tool"
+	r.toolName = name
+	return r
+}
+
+// Times limits the rule to n matches, after which later rules are tried
+func (r *Rule) Times(n int) *Rule {
+	r.times = n
+	return r
+}
+
+// Once limits the rule to a single match
+func (r *Rule) Once() *Rule {
+	return r.Times(1)
+}
+
+// ReplyText answers with an assistant message
+func (r *Rule) ReplyText(text string) *Rule {
+	r.reply = &Message{Role: "assistant", Content: text}
+	return r
+}
+
+// ReplyToolCalls answers with an assistant message requesting tool calls.
+// Calls without an ID are numbered call_0, call_1, ...
+func (r *Rule) ReplyToolCalls(calls ...ToolCall) *Rule {
+	r.reply = &Message{Role: "assistant", ToolCalls: calls}
+	for i := range r.reply.ToolCalls {
+		if r.reply.ToolCalls[i].ID == "" {
+			r.reply.ToolCalls[i].ID = fmt.Sprintf("call_%d", i)
+		}
+	}
+	return r
+}
+
+// ReplyMessage answers with a copy of msg
+func (r *Rule) ReplyMessage(msg *Message) *Rule {
+	r.reply = msg
+	return r
+}
+
+// ReplyError fails the call with err
+func (r *Rule) ReplyError(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// ReplyFunc answers with the result of fn, for replies that depend on the call
+func (r *Rule) ReplyFunc(fn func(ctx context.Context, messages []*Message) (*Message, error)) *Rule {
+	r.handler = fn
+	return r
+}
+
+// WithLatency delays the answer by d, or until the call context is done
+func (r *Rule) WithLatency(d time.Duration) *Rule {
+	r.latency = d
+	return r
+}
+
+// matches reports whether the rule answers a call ending with last
+func (r *Rule) matches(last *Message) bool {
+	if r.times > 0 && r.used >= r.times {
+		return false
+	}
+	if last == nil {
+		return r.role == "" && r.content == nil
+	}
+	if r.role != "" && last.Role != r.role {
+		return false
+	}
+	if r.toolName != "" && last.Name != r.toolName {
+		return false
+	}
+	if r.content != nil && !r.content.MatchString(last.Content) {
+		return false
+	}
+	return true
+}
+
+// respond waits for the configured latency and produces the answer
+func (r *Rule) respond(ctx context.Context, messages []*Message) (*Message, error) {
+	if r.latency > 0 {
+		timer := time.NewTimer(r.latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case r.err != nil:
+		return nil, r.err
+	case r.handler != nil:
+		return r.handler(ctx, messages)
+	case r.reply != nil:
+		reply := *r.reply
+		reply.ToolCalls = append([]ToolCall(nil), r.reply.ToolCalls...)
+		return &reply, nil
+	default:
+		return &Message{Role: "assistant"}, nil
+	}
+}
+
+// fakeStream streams a reply word by word
+type fakeStream struct {
+	ctx    context.Context
+	reply  *Message
+	chunks []string
+	next   int
+}
+
+func newFakeStream(ctx context.Context, reply *Message) *fakeStream {
+	return &fakeStream{ctx: ctx, reply: reply, chunks: strings.SplitAfter(reply.Content, " ")}
+}
+
+func (s *fakeStream) Recv() (*Message, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.next >= len(s.chunks) {
+		return nil, io.EOF
+	}
+
+	chunk := &Message{Role: s.reply.Role, Content: s.chunks[s.next]}
+	s.next++
+	if s.next == len(s.chunks) {
+		chunk.Reasoning = s.reply.Reasoning
+		chunk.ToolCalls = s.reply.ToolCalls
+		chunk.Meta = s.reply.Meta
+	}
+	return chunk, nil
+}
+
+func (s *fakeStream) Close() error {
+	s.next = len(s.chunks)
+	return nil
+}
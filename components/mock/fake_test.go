@@ -0,0 +1,253 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFakeRulesMatchInOrder(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().WhenContent("天气").ReplyText("晴")
+	fake.On().WhenRole("user").ReplyText("你好")
+	fake.On().ReplyText("fallback")
+
+	tests := []struct {
+		message *Message
+		want    string
+	}{
+		{&Message{Role: "user", Content: "明天天气怎么样？"}, "晴"},
+		{&Message{Role: "user", Content: "在吗"}, "你好"},
+		{&Message{Role: "system", Content: "天气"}, "晴"},
+		{&Message{Role: "system", Content: "hi"}, "fallback"},
+	}
+	for _, tt := range tests {
+		reply, err := fake.Generate(context.Background(), []*Message{tt.message})
+		if err != nil {
+			t.Fatalf("Generate(%q): %v", tt.message.Content, err)
+		}
+		if reply.Content != tt.want {
+			t.Errorf("Generate(%q) = %q, want %q", tt.message.Content, reply.Content, tt.want)
+		}
+	}
+	if fake.CallCount() != len(tests) {
+		t.Errorf("CallCount = %d, want %d", fake.CallCount(), len(tests))
+	}
+}
+
+func TestFakeRuleLimits(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().Once().ReplyText("first")
+	fake.On().Times(2).ReplyText("second")
+
+	messages := []*Message{{Role: "user", Content: "hi"}}
+	for _, want := range []string{"first", "second", "second"} {
+		reply, err := fake.Generate(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if reply.Content != want {
+			t.Errorf("reply = %q, want %q", reply.Content, want)
+		}
+	}
+
+	// Every rule is used up
+	_, err := fake.Generate(context.Background(), messages)
+	if !errors.Is(err, ErrNoRule) {
+		t.Errorf("error = %v, want ErrNoRule", err)
+	}
+}
+
+func TestFakeWhenToolResult(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().WhenToolResult("get_weather").ReplyText("weather")
+	fake.On().WhenRole("tool").ReplyText("other tool")
+
+	tests := []struct {
+		last *Message
+		want string
+	}{
+		{&Message{Role: "tool", Name: "get_weather", Content: "{}"}, "weather"},
+		{&Message{Role: "tool", Name: "search_hotels", Content: "{}"}, "other tool"},
+	}
+	for _, tt := range tests {
+		reply, err := fake.Generate(context.Background(), []*Message{{Role: "user", Content: "hi"}, tt.last})
+		if err != nil {
+			t.Fatalf("Generate(%s): %v", tt.last.Name, err)
+		}
+		if reply.Content != tt.want {
+			t.Errorf("Generate(%s) = %q, want %q", tt.last.Name, reply.Content, tt.want)
+		}
+	}
+
+	// A user message named like the tool is not a tool result
+	_, err := fake.Generate(context.Background(), []*Message{{Role: "user", Name: "get_weather", Content: "hi"}})
+	if !errors.Is(err, ErrNoRule) {
+		t.Errorf("error = %v, want ErrNoRule", err)
+	}
+}
+
+func TestFakeReplyToolCallsNumbersCalls(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().ReplyToolCalls(ToolCall{Name: "a"}, ToolCall{ID: "mine", Name: "b"}, ToolCall{Name: "c"})
+
+	reply, err := fake.Generate(context.Background(), []*Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	ids := make([]string, len(reply.ToolCalls))
+	for i, call := range reply.ToolCalls {
+		ids[i] = call.ID
+	}
+	if len(ids) != 3 || ids[0] != "call_0" || ids[1] != "mine" || ids[2] != "call_2" {
+		t.Errorf("tool call IDs = %v, want [call_0 mine call_2]", ids)
+	}
+
+	// Replies are copies: changing one leaves the rule intact
+	reply.ToolCalls[0].Name = "changed"
+	again, err := fake.Generate(context.Background(), []*Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if again.ToolCalls[0].Name != "a" {
+		t.Errorf("second reply calls %q, want a", again.ToolCalls[0].Name)
+	}
+}
+
+func TestFakeReplyError(t *testing.T) {
+	errDown := errors.New("model is down")
+	fake := NewFakeChatModel()
+	fake.On().ReplyError(errDown)
+
+	_, err := fake.Generate(context.Background(), []*Message{{Role: "user", Content: "hi"}})
+	if !errors.Is(err, errDown) {
+		t.Errorf("error = %v, want %v", err, errDown)
+	}
+}
+
+func TestFakeLatency(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().WithLatency(20 * time.Millisecond).ReplyText("slow")
+	messages := []*Message{{Role: "user", Content: "hi"}}
+
+	start := time.Now()
+	reply, err := fake.Generate(context.Background(), messages)
+	if err != nil || reply.Content != "slow" {
+		t.Fatalf("Generate = %+v, %v", reply, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("answered after %v, want at least 20ms", elapsed)
+	}
+
+	// Cancelling the call context ends the wait
+	fake = NewFakeChatModel()
+	fake.On().WithLatency(time.Hour).ReplyText("never")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = fake.Generate(ctx, messages)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFakeRecordsCalls(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().ReplyText("ok")
+	if err := fake.BindTools([]Tool{NewMockTool("get_weather", "查询天气", nil)}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+
+	ctx := WithOptions(context.Background(), Options{NumPredict: 64})
+	messages := []*Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}}
+	if _, err := fake.Generate(ctx, messages); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := fake.Generate(WithoutTools(ctx), messages); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if last := calls[0].LastMessage(); last == nil || last.Content != "hi" {
+		t.Errorf("LastMessage = %+v, want the user message", last)
+	}
+	if calls[0].Options.NumPredict != 64 {
+		t.Errorf("options = %+v, want num_predict 64", calls[0].Options)
+	}
+	if len(calls[0].Tools) != 1 || calls[0].Tools[0] != "get_weather" {
+		t.Errorf("first call tools = %v, want [get_weather]", calls[0].Tools)
+	}
+	if len(calls[1].Tools) != 0 {
+		t.Errorf("call without tools offered %v", calls[1].Tools)
+	}
+}
+
+func TestFakeStream(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().ReplyMessage(&Message{
+		Role:      "assistant",
+		Content:   "Sunny all day",
+		ToolCalls: []ToolCall{{ID: "call_0", Name: "get_weather"}},
+	})
+
+	stream, err := fake.Stream(context.Background(), []*Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var chunks []*Message
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	want := []string{"Sunny ", "all ", "day"}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, chunk := range chunks {
+		if chunk.Content != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunk.Content, want[i])
+		}
+		if last := i == len(chunks)-1; last != (len(chunk.ToolCalls) == 1) {
+			t.Errorf("chunk %d carries %d tool calls", i, len(chunk.ToolCalls))
+		}
+	}
+
+	// The collected stream equals the reply
+	stream, err = fake.Stream(context.Background(), []*Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	reply, err := CollectStream(stream)
+	if err != nil || reply.Content != "Sunny all day" || len(reply.ToolCalls) != 1 {
+		t.Errorf("collected = %+v, %v", reply, err)
+	}
+}
+
+func TestFakeStreamStopsOnCancel(t *testing.T) {
+	fake := NewFakeChatModel()
+	fake.On().ReplyText("one two three")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := fake.Stream(ctx, []*Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	cancel()
+	if _, err := stream.Recv(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Recv after cancel = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,56 @@
+package mock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Image is an image attached to a message for multimodal models
+type Image struct {
+	Name     string `json:"name,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Data     []byte `json:"data"`
+}
+
+// NewImage creates an image from raw bytes, detecting its MIME type
+func NewImage(name string, data []byte) Image {
+	return Image{
+		Name:     name,
+		MIMEType: http.DetectContentType(data),
+		Data:     data,
+	}
+}
+
+// Base64 returns the image data in standard base64 encoding
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURL returns the image as an RFC 2397 data URL
+func (i Image) DataURL() string {
+	mimeType := i.MIMEType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(i.Data)
+	}
+	return "data:" + mimeType + ";base64," + i.Base64()
+}
+
+// ImageFromDataURL decodes an image from a base64 RFC 2397 data URL
+func ImageFromDataURL(url string) (Image, error) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return Image{}, fmt.Errorf("not a data URL")
+	}
+	header, payload, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return Image{}, fmt.Errorf("data URL is not base64 encoded")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to decode data URL: %v", err)
+	}
+	return Image{MIMEType: strings.TrimSuffix(header, ";base64"), Data: data}, nil
+}
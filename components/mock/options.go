@@ -0,0 +1,92 @@
+package mock
+
+import "context"
+
+// Options holds model generation options. Nil pointers and zero values mean
+// "not set", so options from different levels can be layered with Merge.
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	KeepAlive   string   `json:"keep_alive,omitempty"`
+	// Format constrains the reply to JSON matching the schema
+	Format *Schema `json:"format,omitempty"`
+}
+
+// Float64 returns a pointer to v, for use in Options
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v, for use in Options
+func Int(v int) *int {
+	return &v
+}
+
+// Merge returns a copy of o with every option set in override applied on top
+func (o Options) Merge(override Options) Options {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.NumCtx != 0 {
+		o.NumCtx = override.NumCtx
+	}
+	if override.NumPredict != 0 {
+		o.NumPredict = override.NumPredict
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if len(override.Stop) > 0 {
+		o.Stop = override.Stop
+	}
+	if override.KeepAlive != "" {
+		o.KeepAlive = override.KeepAlive
+	}
+	if override.Format != nil {
+		o.Format = override.Format
+	}
+	return o
+}
+
+type optionsKey struct{}
+
+// WithOptions returns a context carrying per-call options. They take
+// precedence over options already present in ctx and over model defaults.
+func WithOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, OptionsFromContext(ctx).Merge(opts))
+}
+
+// WithDefaultOptions returns a context carrying opts as a fallback: options
+// already present in ctx keep precedence over them.
+func WithDefaultOptions(ctx context.Context, opts Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts.Merge(OptionsFromContext(ctx)))
+}
+
+// OptionsFromContext returns the per-call options carried by ctx
+func OptionsFromContext(ctx context.Context) Options {
+	opts, _ := ctx.Value(optionsKey{}).(Options)
+	return opts
+}
+
+type withoutToolsKey struct{}
+
+// WithoutTools returns a context for calls that must not offer the bound
+// tools to the model, e.g. when a structured reply is expected. The tools
+// stay bound for other calls, so a model shared between agents can be used
+// this way concurrently.
+func WithoutTools(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutToolsKey{}, true)
+}
+
+// ToolsDisabled reports whether ctx comes from WithoutTools
+func ToolsDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(withoutToolsKey{}).(bool)
+	return disabled
+}
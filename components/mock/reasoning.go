@@ -0,0 +1,135 @@
+package mock
+
+import (
+	"strings"
+)
+
+const (
+	reasoningOpenTag  = "<think>"
+	reasoningCloseTag = "</think>"
+)
+
+// ReasoningMode controls what a backend does with reasoning traces such as
+// the <think>...</think> blocks emitted by deepseek-r1
+type ReasoningMode int
+
+const (
+	// ReasoningKeep moves the trace into Message.Reasoning
+	ReasoningKeep ReasoningMode = iota
+	// ReasoningDrop discards the trace
+	ReasoningDrop
+	// ReasoningLog writes the trace to the standard logger and discards it
+	ReasoningLog
+)
+
+// SplitReasoning separates <think>...</think> blocks from the answer. Models
+// whose chat template already opens the block only emit the closing tag, in
+// which case everything before it is treated as reasoning.
+func SplitReasoning(content string) (reasoning, answer string) {
+	if !strings.Contains(content, reasoningOpenTag) && !strings.Contains(content, reasoningCloseTag) {
+		return "", content
+	}
+
+	var splitter ReasoningSplitter
+	r, a := splitter.Push(content)
+	fr, fa := splitter.Flush()
+	return strings.TrimSpace(r + fr), strings.TrimSpace(a + fa)
+}
+
+// ReasoningSplitter incrementally separates reasoning from the answer in a
+// stream of text chunks. Tags split across chunks are handled by holding back
+// any trailing text that could be the start of a tag.
+//
+// Like SplitReasoning, the splitter treats the text before a closing tag as
+// reasoning when no opening tag came first. Until the first tag is seen it
+// cannot tell such a trace from an answer, so that text is held back and a
+// reply without any tag is only returned by Flush.
+type ReasoningSplitter struct {
+	inReasoning   bool
+	answerStarted bool
+	// tagSeen is set once the first tag has shown how the reply starts
+	tagSeen bool
+	pending string
+}
+
+// Push consumes the next chunk and returns the reasoning and answer text
+// that can be emitted so far
+func (s *ReasoningSplitter) Push(chunk string) (reasoning, answer string) {
+	buf := s.pending + chunk
+	s.pending = ""
+
+	var r, a strings.Builder
+	if !s.tagSeen {
+		open, close := strings.Index(buf, reasoningOpenTag), strings.Index(buf, reasoningCloseTag)
+		switch {
+		case close >= 0 && (open < 0 || close < open):
+			// The chat template opened the block, the reply starts in it
+			s.tagSeen = true
+			s.inReasoning = true
+		case open >= 0:
+			s.tagSeen = true
+		default:
+			s.pending = buf
+			return "", ""
+		}
+	}
+	for len(buf) > 0 {
+		tag := reasoningOpenTag
+		if s.inReasoning {
+			tag = reasoningCloseTag
+		}
+
+		if i := strings.Index(buf, tag); i >= 0 {
+			s.emit(buf[:i], &r, &a)
+			buf = buf[i+len(tag):]
+			s.inReasoning = !s.inReasoning
+			continue
+		}
+
+		keep := partialTagSuffix(buf, tag)
+		s.emit(buf[:len(buf)-keep], &r, &a)
+		s.pending = buf[len(buf)-keep:]
+		break
+	}
+	return r.String(), a.String()
+}
+
+// Flush returns any text held back at the end of the stream
+func (s *ReasoningSplitter) Flush() (reasoning, answer string) {
+	var r, a strings.Builder
+	s.emit(s.pending, &r, &a)
+	s.pending = ""
+	return r.String(), a.String()
+}
+
+// emit appends text to the reasoning or answer output, dropping the
+// whitespace models put between the reasoning block and the answer
+func (s *ReasoningSplitter) emit(text string, r, a *strings.Builder) {
+	if s.inReasoning {
+		r.WriteString(text)
+		return
+	}
+	if !s.answerStarted {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return
+		}
+		s.answerStarted = true
+	}
+	a.WriteString(text)
+}
+
+// partialTagSuffix returns the length of the longest suffix of text that is a
+// proper prefix of tag
+func partialTagSuffix(text, tag string) int {
+	max := len(tag) - 1
+	if max > len(text) {
+		max = len(text)
+	}
+	for n := max; n > 0; n-- {
+		if strings.HasSuffix(text, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,104 @@
+package mock
+
+import (
+	"strings"
+	"testing"
+)
+
+// chunked cuts s into chunks of n bytes
+func chunked(s string, n int) []string {
+	var chunks []string
+	for len(s) > n {
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+	return append(chunks, s)
+}
+
+// splitChunks feeds chunks to a splitter and returns the trimmed reasoning
+// and answer it produced
+func splitChunks(chunks []string) (reasoning, answer string) {
+	var s ReasoningSplitter
+	var r, a strings.Builder
+	for _, chunk := range chunks {
+		cr, ca := s.Push(chunk)
+		r.WriteString(cr)
+		a.WriteString(ca)
+	}
+	fr, fa := s.Flush()
+	r.WriteString(fr)
+	a.WriteString(fa)
+	return strings.TrimSpace(r.String()), strings.TrimSpace(a.String())
+}
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		reasoning string
+		answer    string
+	}{
+		{"no tags", "推荐西湖", "", "推荐西湖"},
+		{"tagged", "<think>用户想去西湖</think>\n\n推荐西湖", "用户想去西湖", "推荐西湖"},
+		{"only closing tag", "用户想去西湖\n</think>\n\n推荐西湖", "用户想去西湖", "推荐西湖"},
+		{"empty trace", "<think>\n\n</think>\n\n推荐西湖", "", "推荐西湖"},
+		{"unclosed trace", "<think>用户想去西湖", "用户想去西湖", ""},
+		{"text before the trace", "好的<think>用户想去西湖</think>推荐西湖", "用户想去西湖", "好的推荐西湖"},
+		{"two traces", "<think>先查天气</think>晴天<think>再推荐</think>去西湖", "先查天气再推荐", "晴天去西湖"},
+		{"closing tag then trace", "先查天气</think>晴天<think>再推荐</think>去西湖", "先查天气再推荐", "晴天去西湖"},
+		{"tag-like text", "a < b, <thin air>", "", "a < b, <thin air>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasoning, answer := SplitReasoning(tt.content)
+			if reasoning != tt.reasoning || answer != tt.answer {
+				t.Errorf("SplitReasoning = %q, %q, want %q, %q", reasoning, answer, tt.reasoning, tt.answer)
+			}
+
+			// Streamed in chunks of any size, tags cut anywhere, the split is the same
+			for _, n := range []int{1, 2, 3, 5, 7, len(tt.content)} {
+				reasoning, answer := splitChunks(chunked(tt.content, n))
+				if reasoning != tt.reasoning || answer != tt.answer {
+					t.Errorf("in chunks of %d: %q, %q, want %q, %q", n, reasoning, answer, tt.reasoning, tt.answer)
+				}
+			}
+		})
+	}
+}
+
+func TestReasoningSplitterStreams(t *testing.T) {
+	// Text is held back until a tag shows whether it is reasoning
+	var s ReasoningSplitter
+	if r, a := s.Push("用户想去"); r != "" || a != "" {
+		t.Errorf("Push before any tag = %q, %q, want nothing", r, a)
+	}
+	if r, a := s.Push("西湖</th"); r != "" || a != "" {
+		t.Errorf("Push with a partial tag = %q, %q, want nothing", r, a)
+	}
+	if r, a := s.Push("ink>\n\n推荐"); r != "用户想去西湖" || a != "推荐" {
+		t.Errorf("Push with the closing tag = %q, %q, want the trace and the answer so far", r, a)
+	}
+	if r, a := s.Push("西湖"); r != "" || a != "西湖" {
+		t.Errorf("Push after the tag = %q, %q, want the answer right away", r, a)
+	}
+	if r, a := s.Flush(); r != "" || a != "" {
+		t.Errorf("Flush = %q, %q, want nothing left", r, a)
+	}
+
+	// Once the opening tag is seen, the trace is passed on as it comes
+	s = ReasoningSplitter{}
+	if r, a := s.Push("<think>用户"); r != "用户" || a != "" {
+		t.Errorf("Push = %q, %q, want the trace", r, a)
+	}
+	if r, a := s.Push("想去西湖<"); r != "想去西湖" || a != "" {
+		t.Errorf("Push = %q, %q, want the trace without the partial tag", r, a)
+	}
+
+	// A reply without tags comes out on Flush
+	s = ReasoningSplitter{}
+	s.Push("推荐")
+	s.Push("西湖")
+	if r, a := s.Flush(); r != "" || a != "推荐西湖" {
+		t.Errorf("Flush = %q, %q, want the whole answer", r, a)
+	}
+}
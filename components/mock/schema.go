@@ -0,0 +1,224 @@
+package mock
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema used to describe tool parameters
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+}
+
+// EmptyObjectSchema returns the schema of a tool that takes no parameters
+func EmptyObjectSchema() *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{}}
+}
+
+// Without returns a copy of the schema without the given top-level properties
+func (s *Schema) Without(names ...string) *Schema {
+	out := *s
+	out.Properties = make(map[string]*Schema, len(s.Properties))
+	for name, prop := range s.Properties {
+		out.Properties[name] = prop
+	}
+	out.Required = nil
+
+	removed := make(map[string]bool, len(names))
+	for _, name := range names {
+		delete(out.Properties, name)
+		removed[name] = true
+	}
+	for _, name := range s.Required {
+		if !removed[name] {
+			out.Required = append(out.Required, name)
+		}
+	}
+	return &out
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf generates a JSON schema from a Go value, usually a pointer to a
+// parameter struct. Property names follow the `json` tags; the `jsonschema`
+// tag adds metadata as comma separated entries:
+//
+//	description=text  enum=value (repeatable)  default=value
+//	minimum=n  maximum=n  required
+//
+// Fields without "omitempty" in their json tag are also required. A literal
+// comma inside a value is written as "\,".
+func SchemaOf(v interface{}) *Schema {
+	return schemaForType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// schemaForType builds the schema for a Go type, guarding against recursive types
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: schemaForType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{Type: "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return schemaForStruct(t, visiting)
+	default:
+		return &Schema{}
+	}
+}
+
+// schemaForStruct builds an object schema from the exported fields of a struct
+func schemaForStruct(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := parseJSONTag(field)
+		if skip {
+			continue
+		}
+
+		prop := schemaForType(field.Type, visiting)
+		required := !omitEmpty
+		if tag, ok := field.Tag.Lookup("jsonschema"); ok {
+			if applySchemaTag(prop, tag) {
+				required = true
+			}
+		}
+
+		schema.Properties[name] = prop
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// parseJSONTag returns the property name of a field and whether it is omitempty or skipped
+func parseJSONTag(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// applySchemaTag applies the entries of a `jsonschema` tag to prop and
+// reports whether the field was marked as required
+func applySchemaTag(prop *Schema, tag string) bool {
+	required := false
+	for _, entry := range splitSchemaTag(tag) {
+		key, value, _ := strings.Cut(entry, "=")
+		switch key {
+		case "description":
+			prop.Description = value
+		case "enum":
+			target := prop
+			if prop.Type == "array" && prop.Items != nil {
+				target = prop.Items
+			}
+			target.Enum = append(target.Enum, parseSchemaValue(target.Type, value))
+		case "default":
+			prop.Default = parseSchemaValue(prop.Type, value)
+		case "minimum":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				prop.Minimum = &n
+			}
+		case "maximum":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				prop.Maximum = &n
+			}
+		case "format":
+			prop.Format = value
+		case "required":
+			required = true
+		}
+	}
+	return required
+}
+
+// splitSchemaTag splits a `jsonschema` tag on commas, honouring "\," escapes
+func splitSchemaTag(tag string) []string {
+	var entries []string
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			entries = append(entries, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	return append(entries, current.String())
+}
+
+// parseSchemaValue converts a tag value into the JSON type of the schema
+func parseSchemaValue(schemaType, value string) interface{} {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
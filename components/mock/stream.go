@@ -0,0 +1,63 @@
+package mock
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// CollectStream reads the stream until io.EOF and merges all chunks into a single message
+func CollectStream(stream MessageStream) (*Message, error) {
+	defer stream.Close()
+
+	var collector StreamCollector
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		collector.Add(chunk)
+	}
+	return collector.Message(), nil
+}
+
+// StreamCollector merges stream chunks into a single message as they arrive.
+// The zero value is ready to use.
+type StreamCollector struct {
+	role      string
+	content   strings.Builder
+	reasoning strings.Builder
+	toolCalls []ToolCall
+	meta      *ResponseMeta
+}
+
+// Add merges a chunk into the message
+func (c *StreamCollector) Add(chunk *Message) {
+	if chunk.Role != "" {
+		c.role = chunk.Role
+	}
+	c.content.WriteString(chunk.Content)
+	c.reasoning.WriteString(chunk.Reasoning)
+	c.toolCalls = append(c.toolCalls, chunk.ToolCalls...)
+	if chunk.Meta != nil {
+		c.meta = chunk.Meta
+	}
+}
+
+// Message returns the message merged from the chunks added so far
+func (c *StreamCollector) Message() *Message {
+	role := c.role
+	if role == "" {
+		role = "assistant"
+	}
+	return &Message{
+		Role:      role,
+		Content:   c.content.String(),
+		Reasoning: c.reasoning.String(),
+		ToolCalls: c.toolCalls,
+		Meta:      c.meta,
+	}
+}
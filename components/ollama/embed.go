@@ -0,0 +1,75 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// EmbedRequest represents a request to the /api/embed endpoint
+type EmbedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+// EmbedResponse represents a response from the /api/embed endpoint
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// Embedder computes text embeddings with an Ollama embedding model such as
+// nomic-embed-text or bge-m3. It shares the transport, retries and circuit
+// breaker of ChatModel and accepts the same options.
+type Embedder struct {
+	client *ChatModel
+}
+
+// NewEmbedder creates an embedder for the given embedding model
+func NewEmbedder(baseURL, model string, opts ...Option) *Embedder {
+	return &Embedder{client: NewChatModel(baseURL, model, opts...)}
+}
+
+// ModelName returns the name of the embedding model
+func (e *Embedder) ModelName() string {
+	return e.client.model
+}
+
+// Embed returns one embedding per input text, in input order
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.client.Embed(ctx, texts)
+}
+
+// Embed returns one embedding per input text, computed by the chat model's
+// own model. Prefer an Embedder with a dedicated embedding model.
+func (m *ChatModel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(EmbedRequest{
+		Model:     m.model,
+		Input:     texts,
+		KeepAlive: m.keepAliveFor(m.options),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	resp, err := m.do(ctx, http.MethodPost, "/api/embed", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embedResp EmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings: %v", err)
+	}
+	if len(embedResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embedResp.Embeddings))
+	}
+	return embedResp.Embeddings, nil
+}
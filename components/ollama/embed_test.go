@@ -0,0 +1,100 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// newEmbedServer serves /api/embed with handler after decoding the request
+func newEmbedServer(t *testing.T, handler func(w http.ResponseWriter, req EmbedRequest)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		var req EmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEmbed(t *testing.T) {
+	var got EmbedRequest
+	server := newEmbedServer(t, func(w http.ResponseWriter, req EmbedRequest) {
+		got = req
+		json.NewEncoder(w).Encode(EmbedResponse{
+			Model:      req.Model,
+			Embeddings: [][]float32{{0.1, 0.2}, {0.3, 0.4}},
+		})
+	})
+
+	embedder := NewEmbedder(server.URL, "bge-m3", WithKeepAlive("30m"))
+	if embedder.ModelName() != "bge-m3" {
+		t.Errorf("ModelName = %q, want bge-m3", embedder.ModelName())
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"西湖", "灵隐寺"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if want := [][]float32{{0.1, 0.2}, {0.3, 0.4}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors = %v, want %v", vectors, want)
+	}
+	if want := (EmbedRequest{Model: "bge-m3", Input: []string{"西湖", "灵隐寺"}, KeepAlive: "30m"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+}
+
+func TestEmbedWithoutTexts(t *testing.T) {
+	var requests int32
+	server := newEmbedServer(t, func(w http.ResponseWriter, req EmbedRequest) {
+		atomic.AddInt32(&requests, 1)
+	})
+
+	vectors, err := NewEmbedder(server.URL, "bge-m3").Embed(context.Background(), nil)
+	if err != nil || vectors != nil {
+		t.Errorf("Embed(nil) = %v, %v", vectors, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("sent %d requests, want none", n)
+	}
+}
+
+func TestEmbedCountMismatch(t *testing.T) {
+	server := newEmbedServer(t, func(w http.ResponseWriter, req EmbedRequest) {
+		fmt.Fprint(w, `{"model": "bge-m3", "embeddings": [[0.1, 0.2]]}`)
+	})
+
+	_, err := NewEmbedder(server.URL, "bge-m3").Embed(context.Background(), []string{"西湖", "灵隐寺"})
+	if err == nil || err.Error() != "expected 2 embeddings, got 1" {
+		t.Errorf("error = %v, want a count mismatch", err)
+	}
+}
+
+func TestEmbedModelNotFound(t *testing.T) {
+	server := newEmbedServer(t, func(w http.ResponseWriter, req EmbedRequest) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error": "model %q not found, try pulling it first"}`, req.Model)
+	})
+
+	_, err := NewEmbedder(server.URL, "nomic-embed-text", WithRetryPolicy(RetryPolicy{})).Embed(context.Background(), []string{"西湖"})
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("error = %v, want ErrModelNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("error = %#v, want a 404 APIError", err)
+	}
+}
@@ -0,0 +1,59 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of an error response body is read
+const maxErrorBodySize = 64 * 1024
+
+// ErrModelNotFound matches the APIError returned when the requested model is
+// not installed on the server
+var ErrModelNotFound = errors.New("ollama: model not found")
+
+// APIError is returned when the Ollama server answers with a non-200 status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ollama: unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ollama: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Is reports whether the error is ErrModelNotFound
+func (e *APIError) Is(target error) bool {
+	return target == ErrModelNotFound &&
+		e.StatusCode == http.StatusNotFound &&
+		strings.Contains(e.Message, "not found")
+}
+
+// newAPIError builds an APIError from a failed HTTP response, extracting the
+// server's error text from the {"error": "..."} body when present
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
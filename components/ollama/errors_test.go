@@ -0,0 +1,184 @@
+package ollama
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		message  string
+		text     string
+		notFound bool
+	}{
+		{
+			name:     "model not found",
+			status:   http.StatusNotFound,
+			body:     `{"error": "model \"qwen3:8b\" not found, try pulling it first"}`,
+			message:  `model "qwen3:8b" not found, try pulling it first`,
+			text:     `ollama: model "qwen3:8b" not found, try pulling it first (status 404)`,
+			notFound: true,
+		},
+		{
+			name:    "other 404",
+			status:  http.StatusNotFound,
+			body:    "404 page missing\n",
+			message: "404 page missing",
+			text:    "ollama: 404 page missing (status 404)",
+		},
+		{
+			name:    "plain text body",
+			status:  http.StatusBadGateway,
+			body:    "  upstream connect error\n",
+			message: "upstream connect error",
+			text:    "ollama: upstream connect error (status 502)",
+		},
+		{
+			name:    "JSON without error",
+			status:  http.StatusBadRequest,
+			body:    `{"detail": "invalid options"}`,
+			message: `{"detail": "invalid options"}`,
+			text:    `ollama: {"detail": "invalid options"} (status 400)`,
+		},
+		{
+			name:   "empty body",
+			status: http.StatusServiceUnavailable,
+			text:   "ollama: unexpected status code: 503",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			err := newAPIError(resp)
+			if err.StatusCode != tt.status || err.Message != tt.message {
+				t.Errorf("newAPIError = %+v, want status %d and message %q", err, tt.status, tt.message)
+			}
+			if err.Error() != tt.text {
+				t.Errorf("Error() = %q, want %q", err.Error(), tt.text)
+			}
+			if got := errors.Is(fmt.Errorf("chat failed: %w", err), ErrModelNotFound); got != tt.notFound {
+				t.Errorf("errors.Is(ErrModelNotFound) = %v, want %v", got, tt.notFound)
+			}
+		})
+	}
+
+	// A large body is cut at maxErrorBodySize
+	resp := &http.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(strings.Repeat("x", 2*maxErrorBodySize)))}
+	if err := newAPIError(resp); len(err.Message) != maxErrorBodySize {
+		t.Errorf("message of %d bytes, want %d", len(err.Message), maxErrorBodySize)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var got Request
+	server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+		got = req
+		fmt.Fprint(w, `{
+			"model": "qwen3:8b",
+			"created_at": "2025-03-01T10:00:00Z",
+			"message": {"role": "assistant", "content": "<think>用户想去西湖</think>\n\n推荐西湖", "thinking": "先看天气"},
+			"done": true,
+			"done_reason": "stop",
+			"total_duration": 2000000000,
+			"load_duration": 100000,
+			"prompt_eval_count": 30,
+			"eval_count": 12
+		}`)
+	})
+
+	m := NewChatModel(server.URL, "qwen3:8b", WithDefaultOptions(mock.Options{Temperature: mock.Float64(0.2)}))
+	reply, err := m.Generate(context.Background(), []*mock.Message{
+		{Role: "system", Content: "你是导游"},
+		{Role: "user", Content: "推荐景点"},
+	})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got.Stream || got.Model != "qwen3:8b" || len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Content != "推荐景点" {
+		t.Errorf("request = %+v", got)
+	}
+	if got.Options["temperature"] != 0.2 {
+		t.Errorf("request options = %v, want the default temperature", got.Options)
+	}
+
+	if reply.Role != "assistant" || reply.Content != "推荐西湖" {
+		t.Errorf("reply = %q from %s, want the answer without the trace", reply.Content, reply.Role)
+	}
+	if reply.Reasoning != "先看天气\n用户想去西湖" {
+		t.Errorf("reasoning = %q, want the thinking field then the tagged trace", reply.Reasoning)
+	}
+	want := mock.ResponseMeta{
+		Model:         "qwen3:8b",
+		FinishReason:  "stop",
+		TotalDuration: 2 * time.Second,
+		Usage:         mock.TokenUsage{PromptTokens: 30, CompletionTokens: 12, TotalTokens: 42},
+	}
+	if reply.Meta == nil || *reply.Meta != want {
+		t.Errorf("meta = %+v, want %+v", reply.Meta, want)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply func(w http.ResponseWriter)
+		check func(t *testing.T, err error)
+	}{
+		{
+			name: "error field with status 200",
+			reply: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"error": "model requires more system memory (8.0 GiB) than is available"}`)
+			},
+			check: func(t *testing.T, err error) {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK || !strings.Contains(apiErr.Message, "system memory") {
+					t.Errorf("error = %v, want an APIError with the server message", err)
+				}
+			},
+		},
+		{
+			name: "model not found",
+			reply: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error": "model \"qwen3:8b\" not found, try pulling it first"}`)
+			},
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, ErrModelNotFound) {
+					t.Errorf("error = %v, want ErrModelNotFound", err)
+				}
+			},
+		},
+		{
+			name: "malformed body",
+			reply: func(w http.ResponseWriter) {
+				fmt.Fprint(w, `{"message": {"role": "assistant", "content": "西湖"`)
+			},
+			check: func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "failed to decode response") {
+					t.Errorf("error = %v, want a decoding error", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, func(w http.ResponseWriter, req Request) { tt.reply(w) })
+			m := NewChatModel(server.URL, "qwen3:8b", WithRetryPolicy(RetryPolicy{}))
+			reply, err := ask(context.Background(), m)
+			if reply != nil {
+				t.Errorf("reply = %+v, want none", reply)
+			}
+			tt.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ollama
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Limiter bounds the number of generation requests running at once against
+// one Ollama server. Requests beyond the limit queue in arrival order until
+// a slot frees up or their context is done.
+type Limiter struct {
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// NewLimiter creates a limiter allowing n concurrent requests
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire waits for a free slot or for ctx to be done
+func (l *Limiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *Limiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// InFlight returns the number of requests holding a slot
+func (l *Limiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// Waiting returns the number of requests queued for a slot
+func (l *Limiter) Waiting() int {
+	if l == nil {
+		return 0
+	}
+	return int(l.waiting.Load())
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = map[string]*Limiter{}
+)
+
+// SharedLimiter returns the limiter shared by all chat models and embedders
+// talking to baseURL, creating it with n slots on first use. Later calls
+// return the existing limiter whatever their n.
+func SharedLimiter(baseURL string, n int) *Limiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+
+	if l, ok := sharedLimiters[baseURL]; ok {
+		return l
+	}
+	l := NewLimiter(n)
+	sharedLimiters[baseURL] = l
+	return l
+}
+
+// WithLimiter makes the model take a slot of l for every generation request
+// (chat, embeddings and warm-up). The slot is held until the response body,
+// or the stream, is closed.
+func WithLimiter(l *Limiter) Option {
+	return func(m *ChatModel) {
+		m.limiter = l
+	}
+}
+
+// WithConcurrencyLimit limits the concurrent generation requests of all
+// models using this option with the same server URL to n
+func WithConcurrencyLimit(n int) Option {
+	return func(m *ChatModel) {
+		if n <= 0 {
+			m.limiter = nil
+			return
+		}
+		m.limiter = SharedLimiter(m.baseURL, n)
+	}
+}
+
+// limitedPaths are the endpoints that make the server run a model
+var limitedPaths = map[string]bool{
+	"/api/chat":  true,
+	"/api/embed": true,
+}
+
+// releasingBody releases a limiter slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	limiter *Limiter
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.limiter.Release)
+	return err
+}
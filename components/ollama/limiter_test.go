@@ -0,0 +1,164 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// enter counts a running request and raises peak to the running count
+func enter(running, peak *atomic.Int32) {
+	n := running.Add(1)
+	for {
+		p := peak.Load()
+		if n <= p || peak.CompareAndSwap(p, n) {
+			return
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(2)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Acquire(context.Background()); err != nil {
+				t.Errorf("Acquire: %v", err)
+				return
+			}
+			defer l.Release()
+			enter(&running, &peak)
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p != 2 {
+		t.Errorf("%d requests ran at once, want 2", p)
+	}
+	if l.InFlight() != 0 || l.Waiting() != 0 {
+		t.Errorf("after the requests: %d in flight, %d waiting", l.InFlight(), l.Waiting())
+	}
+	if NewLimiter(0).Acquire(context.Background()) != nil {
+		t.Error("a limiter of zero slots has no slot")
+	}
+
+	// A nil limiter does not limit
+	var none *Limiter
+	if err := none.Acquire(context.Background()); err != nil || none.InFlight() != 0 || none.Waiting() != 0 {
+		t.Errorf("nil limiter: %v", err)
+	}
+	none.Release()
+}
+
+func TestLimiterCancelWhileWaiting(t *testing.T) {
+	l := NewLimiter(1)
+	if err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Acquire(ctx) }()
+	waitFor(t, "the request to queue", func() bool { return l.Waiting() == 1 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire = %v, want context.Canceled", err)
+	}
+	if l.Waiting() != 0 || l.InFlight() != 1 {
+		t.Errorf("after cancellation: %d in flight, %d waiting, want 1 and 0", l.InFlight(), l.Waiting())
+	}
+
+	// The cancelled request did not take the slot
+	l.Release()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Acquire(ctx); err != nil {
+		t.Errorf("Acquire after release = %v", err)
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire on a full limiter = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSharedLimiter(t *testing.T) {
+	a := SharedLimiter("http://limiter-test:11434", 2)
+	if b := SharedLimiter("http://limiter-test:11434", 5); b != a {
+		t.Error("the same server got two limiters")
+	}
+	if c := SharedLimiter("http://other-limiter-test:11434", 2); c == a {
+		t.Error("two servers share a limiter")
+	}
+	if NewChatModel("http://limiter-test:11434", "qwen2.5:7b", WithConcurrencyLimit(3)).limiter != a {
+		t.Error("WithConcurrencyLimit does not use the shared limiter")
+	}
+}
+
+func TestChatModelLimiter(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	server, _ := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if r.URL.Path == "/api/version" {
+			fmt.Fprint(w, `{"version": "0.6.2"}`)
+			return
+		}
+		enter(&running, &peak)
+		<-release
+		running.Add(-1)
+		fmt.Fprint(w, chatReply)
+	})
+
+	l := NewLimiter(2)
+	m := NewChatModel(server.URL, "qwen2.5:7b", WithLimiter(l))
+	ctx := context.Background()
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := ask(ctx, m)
+			errs <- err
+		}()
+	}
+	waitFor(t, "the requests to queue", func() bool { return l.InFlight() == 2 && l.Waiting() == 3 })
+
+	// Requests that do not run the model are not limited
+	if _, err := m.Version(ctx); err != nil {
+		t.Errorf("Version while the limiter is full: %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Generate: %v", err)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("the server ran %d requests at once, want at most 2", p)
+	}
+	if l.InFlight() != 0 {
+		t.Errorf("%d slots still held", l.InFlight())
+	}
+}
@@ -0,0 +1,250 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthCheckTimeout bounds the version request used as a health check
+const healthCheckTimeout = 5 * time.Second
+
+// ModelDetails describes the format and size of an installed model
+type ModelDetails struct {
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ModelInfo describes a model installed on the server, as listed by /api/tags
+type ModelInfo struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ShowResponse holds the metadata returned by /api/show
+type ShowResponse struct {
+	License      string                 `json:"license"`
+	Modelfile    string                 `json:"modelfile"`
+	Parameters   string                 `json:"parameters"`
+	Template     string                 `json:"template"`
+	Details      ModelDetails           `json:"details"`
+	ModelInfo    map[string]interface{} `json:"model_info"`
+	Capabilities []string               `json:"capabilities"`
+}
+
+// ContextLength returns the context window the model was trained with, read
+// from the "<architecture>.context_length" entry of model_info, or 0 when the
+// server does not report it
+func (s *ShowResponse) ContextLength() int {
+	for key, value := range s.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// PullProgress reports the progress of a model download
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ModelStatus is the result of a successful preflight check
+type ModelStatus struct {
+	Version       string
+	Model         string
+	ContextLength int
+	Details       ModelDetails
+}
+
+// Version returns the server version. It makes a single attempt without
+// retries, so it doubles as a quick health check.
+func (m *ChatModel) Version(ctx context.Context) (string, error) {
+	resp, _, err := m.send(ctx, http.MethodGet, "/api/version", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", fmt.Errorf("failed to decode version: %v", err)
+	}
+	return version.Version, nil
+}
+
+// Warmup loads the model into memory so that the first real request does not
+// pay the loading time. The model then stays loaded for the keep-alive
+// duration. Warmup takes a limiter slot like any generation request.
+func (m *ChatModel) Warmup(ctx context.Context) error {
+	// A chat request without messages only loads the model
+	resp, err := m.postChat(ctx, Request{
+		Model:     m.model,
+		Messages:  []Message{},
+		KeepAlive: m.keepAliveFor(m.options),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", err)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// ListModels returns the models installed on the server
+func (m *ChatModel) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := m.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []ModelInfo `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %v", err)
+	}
+	return tags.Models, nil
+}
+
+// ShowModel returns the metadata of an installed model. Errors for models
+// that are not installed match ErrModelNotFound.
+func (m *ChatModel) ShowModel(ctx context.Context, name string) (*ShowResponse, error) {
+	body, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	resp, err := m.do(ctx, http.MethodPost, "/api/show", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var show ShowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("failed to decode model metadata: %v", err)
+	}
+	return &show, nil
+}
+
+// PullModel downloads a model, calling progress (when not nil) for every
+// status update sent by the server
+func (m *ChatModel) PullModel(ctx context.Context, name string, progress func(PullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	resp, err := m.do(ctx, http.MethodPost, "/api/pull", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var update PullProgress
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %v", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("failed to pull model %s: %s", name, update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("failed to read pull progress: %v", err)
+	}
+	return fmt.Errorf("pull of model %s ended without success", name)
+}
+
+// Preflight checks that the server is reachable and the configured model is
+// installed, pulling it first when autoPull is set. The returned errors are
+// meant to be shown to users as they are.
+func (m *ChatModel) Preflight(ctx context.Context, autoPull bool, progress func(PullProgress)) (*ModelStatus, error) {
+	healthCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	version, err := m.Version(healthCtx)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach Ollama at %s (%v); start it with `ollama serve` or set OLLAMA_BASE_URL", m.baseURL, err)
+	}
+
+	show, err := m.ShowModel(ctx, m.model)
+	if errors.Is(err, ErrModelNotFound) {
+		if !autoPull {
+			return nil, m.modelNotFoundError(ctx)
+		}
+		if err := m.PullModel(ctx, m.model, progress); err != nil {
+			return nil, err
+		}
+		show, err = m.ShowModel(ctx, m.model)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect model %s: %v", m.model, err)
+	}
+	m.contextLength.Store(int64(show.ContextLength()))
+
+	return &ModelStatus{
+		Version:       version,
+		Model:         m.model,
+		ContextLength: show.ContextLength(),
+		Details:       show.Details,
+	}, nil
+}
+
+// modelNotFoundError explains how to install the missing model and lists
+// the installed ones, which usually reveals a misspelled OLLAMA_MODEL
+func (m *ChatModel) modelNotFoundError(ctx context.Context) error {
+	msg := fmt.Sprintf("model %q is not installed on %s; run `ollama pull %s` or set OLLAMA_AUTO_PULL=true",
+		m.model, m.baseURL, m.model)
+
+	models, err := m.ListModels(ctx)
+	if err != nil {
+		return errors.New(msg)
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("%s (no models are installed)", msg)
+	}
+	names := make([]string, len(models))
+	for i, model := range models {
+		names[i] = model.Name
+	}
+	return fmt.Errorf("%s (installed models: %s)", msg, strings.Join(names, ", "))
+}
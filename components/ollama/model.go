@@ -1,136 +1,314 @@
 package ollama
 
 import (
-	"bytes"
 	"context"
 	"deepllm/components/mock"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ChatModel is an implementation of mock.ChatModel using Ollama
+//
+// A ChatModel is safe for concurrent use. Tools bound with BindTools apply to
+// every later call, whichever goroutine makes it.
 type ChatModel struct {
 	baseURL string
 	model   string
-	tools   []mock.Tool
+
+	// mu guards the bound tools
+	mu    sync.RWMutex
+	tools []mock.Tool
+	// ollamaTools holds the function definitions sent with every request
+	ollamaTools []Tool
+
+	// options are the default generation options, overridable per call
+	options mock.Options
+	// keepAlive is used when the options do not set KeepAlive
+	keepAlive string
+	// contextLength is the context window reported by the server, set by
+	// Preflight
+	contextLength atomic.Int64
+
+	client  *http.Client
+	retry   RetryPolicy
+	breaker *circuitBreaker
+	limiter *Limiter
+
+	// reasoningMode controls what happens to <think> blocks
+	reasoningMode mock.ReasoningMode
+}
+
+// Option configures a ChatModel
+type Option func(*ChatModel)
+
+// WithDefaultOptions sets the generation options used by every call. Options
+// carried by the call context (see mock.WithOptions) take precedence.
+func WithDefaultOptions(opts mock.Options) Option {
+	return func(m *ChatModel) {
+		m.options = opts
+	}
+}
+
+// WithReasoningMode sets how reasoning traces are handled. By default they
+// are kept in mock.Message.Reasoning, separate from the answer.
+func WithReasoningMode(mode mock.ReasoningMode) Option {
+	return func(m *ChatModel) {
+		m.reasoningMode = mode
+	}
+}
+
+// WithKeepAlive sets how long the server keeps the model loaded after a
+// request, as a duration string such as "30m", "0s" to unload it at once or
+// a negative duration such as "-1m" to keep it loaded. The value is sent as
+// a string, so the bare number "-1" of the Ollama docs is rejected by the
+// server. KeepAlive in the generation options takes precedence.
+func WithKeepAlive(keepAlive string) Option {
+	return func(m *ChatModel) {
+		m.keepAlive = keepAlive
+	}
 }
 
 // NewChatModel creates a new ChatModel instance
-func NewChatModel(baseURL, model string) *ChatModel {
-	return &ChatModel{
+func NewChatModel(baseURL, model string, opts ...Option) *ChatModel {
+	m := &ChatModel{
 		baseURL: baseURL,
 		model:   model,
+		client:  defaultHTTPClient,
+		retry:   DefaultRetryPolicy,
+		breaker: newCircuitBreaker(5, 30*time.Second),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// ModelName returns the name of the model served by the backend
+func (m *ChatModel) ModelName() string {
+	return m.model
+}
+
+// Options returns the default generation options of the model
+func (m *ChatModel) Options() mock.Options {
+	return m.options
+}
+
+// ContextLength returns the context window the model was trained with, as
+// reported by the server to Preflight, or 0 when it is not known
+func (m *ChatModel) ContextLength() int {
+	return int(m.contextLength.Load())
 }
 
 // Request represents a request to the Ollama API
 type Request struct {
-	Model    string                   `json:"model"`
-	Messages []Message                `json:"messages"`
-	Stream   bool                     `json:"stream"`
-	Options  map[string]interface{}   `json:"options,omitempty"`
-	Tools    []map[string]interface{} `json:"tools,omitempty"`
+	Model     string                 `json:"model"`
+	Messages  []Message              `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	Tools     []Tool                 `json:"tools,omitempty"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Format    *mock.Schema           `json:"format,omitempty"`
 }
 
 // Message represents a message in the Ollama API
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Images    []string   `json:"images,omitempty"` // base64 encoded
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+	Thinking  string     `json:"thinking,omitempty"`
 }
 
-// Response represents a response from the Ollama API
+// Response represents a response from the /api/chat endpoint. When streaming,
+// every NDJSON line decodes into a Response and only the final one (Done set)
+// carries the done reason and counters.
 type Response struct {
-	Model     string          `json:"model"`
-	Response  string          `json:"response"`
-	Done      bool            `json:"done"`
-	ToolCalls []mock.ToolCall `json:"tool_calls,omitempty"`
-	Context   []interface{}   `json:"context,omitempty"`
+	Model              string    `json:"model"`
+	CreatedAt          time.Time `json:"created_at"`
+	Message            Message   `json:"message"`
+	Done               bool      `json:"done"`
+	DoneReason         string    `json:"done_reason,omitempty"`
+	TotalDuration      int64     `json:"total_duration,omitempty"`
+	LoadDuration       int64     `json:"load_duration,omitempty"`
+	PromptEvalCount    int       `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64     `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int       `json:"eval_count,omitempty"`
+	EvalDuration       int64     `json:"eval_duration,omitempty"`
+	Error              string    `json:"error,omitempty"`
+}
+
+// toMessage converts the response into a mock.Message, attaching metadata once the reply is done
+func (r *Response) toMessage() *mock.Message {
+	msg := &mock.Message{
+		Role:    "assistant",
+		Content: r.Message.Content,
+	}
+	msg.ToolCalls = fromOllamaToolCalls(r.Message.ToolCalls)
+	if r.Done {
+		msg.Meta = &mock.ResponseMeta{
+			Model:         r.Model,
+			FinishReason:  r.DoneReason,
+			TotalDuration: time.Duration(r.TotalDuration),
+			Usage: mock.TokenUsage{
+				PromptTokens:     r.PromptEvalCount,
+				CompletionTokens: r.EvalCount,
+				TotalTokens:      r.PromptEvalCount + r.EvalCount,
+			},
+		}
+	}
+	return msg
 }
 
 // Generate generates a response from the Ollama model
 func (m *ChatModel) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
-	// Convert messages to Ollama format
-	ollamaMessages := make([]Message, len(messages))
-	for i, msg := range messages {
-		ollamaMessages[i] = Message{
-			Role:    msg.Role,
-			Content: msg.Content,
+	resp, err := m.postChat(ctx, m.newRequest(ctx, messages, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Parse response
+	var ollamaResp Response
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	if ollamaResp.Error != "" {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: ollamaResp.Error}
+	}
+
+	// Convert response to Message format, separating the reasoning trace
+	msg := ollamaResp.toMessage()
+	reasoning, answer := mock.SplitReasoning(msg.Content)
+	if ollamaResp.Message.Thinking != "" {
+		reasoning = strings.TrimSpace(ollamaResp.Message.Thinking + "\n" + reasoning)
+	}
+	msg.Content = answer
+	m.handleReasoning(msg, reasoning)
+	return msg, nil
+}
+
+// handleReasoning applies the configured reasoning mode to a trace
+func (m *ChatModel) handleReasoning(msg *mock.Message, reasoning string) {
+	switch m.reasoningMode {
+	case mock.ReasoningKeep:
+		msg.Reasoning = reasoning
+	case mock.ReasoningLog:
+		if strings.TrimSpace(reasoning) != "" {
+			log.Printf("[%s reasoning] %s", m.model, strings.TrimSpace(reasoning))
 		}
 	}
+}
 
-	// Prepare request
-	reqBody := Request{
-		Model:    m.model,
-		Messages: ollamaMessages,
-		Stream:   false,
+// newRequest converts messages into an Ollama chat request, applying the
+// default options overridden by the options carried by ctx
+func (m *ChatModel) newRequest(ctx context.Context, messages []*mock.Message, stream bool) Request {
+	ollamaMessages := make([]Message, len(messages))
+	for i, msg := range messages {
+		ollamaMessages[i] = toOllamaMessage(msg)
 	}
 
-	// Convert request to JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	opts := m.options.Merge(mock.OptionsFromContext(ctx))
+	m.mu.RLock()
+	tools := m.ollamaTools
+	m.mu.RUnlock()
+	if mock.ToolsDisabled(ctx) {
+		tools = nil
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(
-		ctx,
-		"POST",
-		fmt.Sprintf("%s/api/chat", m.baseURL),
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	return Request{
+		Model:     m.model,
+		Messages:  ollamaMessages,
+		Stream:    stream,
+		Options:   toRequestOptions(opts),
+		Tools:     tools,
+		KeepAlive: m.keepAliveFor(opts),
+		Format:    opts.Format,
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+// keepAliveFor returns the keep_alive to send with a request
+func (m *ChatModel) keepAliveFor(opts mock.Options) string {
+	if opts.KeepAlive != "" {
+		return opts.KeepAlive
 	}
-	defer resp.Body.Close()
+	return m.keepAlive
+}
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// toRequestOptions converts generation options into the Ollama "options" object
+func toRequestOptions(opts mock.Options) map[string]interface{} {
+	options := map[string]interface{}{}
+	if opts.Temperature != nil {
+		options["temperature"] = *opts.Temperature
 	}
+	if opts.TopP != nil {
+		options["top_p"] = *opts.TopP
+	}
+	if opts.NumCtx > 0 {
+		options["num_ctx"] = opts.NumCtx
+	}
+	if opts.NumPredict != 0 {
+		options["num_predict"] = opts.NumPredict
+	}
+	if opts.Seed != nil {
+		options["seed"] = *opts.Seed
+	}
+	if len(opts.Stop) > 0 {
+		options["stop"] = opts.Stop
+	}
+	if len(options) == 0 {
+		return nil
+	}
+	return options
+}
 
-	// Parse response
-	var ollamaResp Response
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// postChat sends a request to the /api/chat endpoint and checks the response status
+func (m *ChatModel) postChat(ctx context.Context, reqBody Request) (*http.Response, error) {
+	// Convert request to JSON
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Convert response to Message format
-	return &mock.Message{
-		Role:      "assistant",
-		Content:   ollamaResp.Response,
-		ToolCalls: ollamaResp.ToolCalls,
-	}, nil
+	resp, err := m.do(ctx, http.MethodPost, "/api/chat", jsonData)
+	if errors.Is(err, ErrModelNotFound) {
+		return nil, fmt.Errorf("model %q is not available on %s, pull it with `ollama pull %s`: %w",
+			m.model, m.baseURL, m.model, err)
+	}
+	return resp, err
 }
 
-// BindTools binds tools to the chat model
+// BindTools binds tools to the chat model. The tools are advertised to the
+// model as function definitions on every subsequent request.
 func (m *ChatModel) BindTools(tools []mock.Tool) error {
 	// Convert tools to Ollama format
-	ollamaTools := make([]map[string]interface{}, len(tools))
+	ollamaTools := make([]Tool, len(tools))
 	for i, tool := range tools {
-		ollamaTools[i] = map[string]interface{}{
-			"name":        tool.Name(),
-			"description": tool.Description(),
-		}
+		ollamaTools[i] = toOllamaTool(tool)
 	}
 
 	// Store tools for later use
+	m.mu.Lock()
 	m.tools = tools
+	m.ollamaTools = ollamaTools
+	m.mu.Unlock()
 	return nil
 }
 
 // ExecuteTool executes a tool by name with the given arguments
 func (m *ChatModel) ExecuteTool(ctx context.Context, name string, args map[string]interface{}) (map[string]interface{}, error) {
 	// Find the tool by name
-	for _, tool := range m.tools {
+	m.mu.RLock()
+	tools := m.tools
+	m.mu.RUnlock()
+	for _, tool := range tools {
 		if tool.Name() == name {
 			return tool.Execute(ctx, args)
 		}
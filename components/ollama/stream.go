@@ -0,0 +1,126 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Stream generates a response from the Ollama model and yields it chunk by chunk
+func (m *ChatModel) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	resp, err := m.postChat(ctx, m.newRequest(ctx, messages, true))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	// Single chunks are small, but leave room for large tool call payloads
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	return &messageStream{
+		ctx:     ctx,
+		model:   m,
+		resp:    resp,
+		scanner: scanner,
+	}, nil
+}
+
+// messageStream reads NDJSON chunks from a streaming /api/chat response
+type messageStream struct {
+	ctx       context.Context
+	model     *ChatModel
+	resp      *http.Response
+	scanner   *bufio.Scanner
+	done      bool
+	closeOnce sync.Once
+
+	// splitter separates <think> blocks spread over several chunks
+	splitter mock.ReasoningSplitter
+	// reasoning accumulates the trace when it is logged at the end
+	reasoning strings.Builder
+}
+
+// Recv returns the next message chunk, or io.EOF when the model has finished
+func (s *messageStream) Recv() (*mock.Message, error) {
+	for {
+		if s.done {
+			return nil, io.EOF
+		}
+		if err := s.ctx.Err(); err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		if !s.scanner.Scan() {
+			s.done = true
+			s.Close()
+			if err := s.scanner.Err(); err != nil {
+				// Prefer the context error when the read failed due to cancellation
+				if ctxErr := s.ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				return nil, fmt.Errorf("failed to read stream: %v", err)
+			}
+			return nil, fmt.Errorf("stream ended before completion")
+		}
+
+		line := s.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk Response
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %v", err)
+		}
+		if chunk.Error != "" {
+			s.done = true
+			s.Close()
+			return nil, &APIError{StatusCode: s.resp.StatusCode, Message: chunk.Error}
+		}
+		if chunk.Done {
+			// Release the connection, and the limiter slot, right away
+			s.done = true
+			s.Close()
+		}
+
+		msg := chunk.toMessage()
+		reasoning, answer := s.splitter.Push(msg.Content)
+		if chunk.Done {
+			r, a := s.splitter.Flush()
+			reasoning, answer = reasoning+r, answer+a
+		}
+		msg.Content = answer
+		s.handleReasoning(msg, chunk.Message.Thinking+reasoning)
+		return msg, nil
+	}
+}
+
+// handleReasoning applies the model's reasoning mode to the trace of a chunk.
+// In log mode the whole trace is logged once the stream is done.
+func (s *messageStream) handleReasoning(msg *mock.Message, reasoning string) {
+	switch s.model.reasoningMode {
+	case mock.ReasoningKeep:
+		msg.Reasoning = reasoning
+	case mock.ReasoningLog:
+		s.reasoning.WriteString(reasoning)
+		if s.done {
+			s.model.handleReasoning(msg, s.reasoning.String())
+		}
+	}
+}
+
+// Close releases the underlying HTTP response
+func (s *messageStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.resp.Body.Close()
+	})
+	return err
+}
@@ -0,0 +1,211 @@
+package ollama
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newChatServer serves /api/chat with handler after decoding the request
+func newChatServer(t *testing.T, handler func(w http.ResponseWriter, req Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeParts writes the parts of a response body, flushing after each one
+// so that the client reads them separately
+func writeParts(w http.ResponseWriter, parts ...string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, part := range parts {
+		io.WriteString(w, part)
+		w.(http.Flusher).Flush()
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// streamChunks reads stream to the end and returns its chunks and the error
+// that ended it, nil for io.EOF
+func streamChunks(stream mock.MessageStream) ([]*mock.Message, error) {
+	defer stream.Close()
+	var chunks []*mock.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestStream(t *testing.T) {
+	var got Request
+	server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+		got = req
+		writeParts(w,
+			`{"model": "qwen2.5:7b", "message": {"role": "assistant", "content": "<think>用户想去西湖</think>西湖"}, "done": false}`+"\n",
+			// A line split over two reads, then an empty line
+			`{"model": "qwen2.5:7b", "message": {"role": "assistant", "con`,
+			`tent": "和灵隐寺"}, "done": false}`+"\n\n",
+			`{"model": "qwen2.5:7b", "message": {"role": "assistant", "content": ""}, "done": true, "done_reason": "stop",`+
+				` "total_duration": 1500000000, "prompt_eval_count": 20, "eval_count": 8}`+"\n",
+		)
+	})
+
+	m := NewChatModel(server.URL, "qwen2.5:7b")
+	stream, err := m.Stream(context.Background(), []*mock.Message{{Role: "user", Content: "推荐景点"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	chunks, err := streamChunks(stream)
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if !got.Stream || got.Model != "qwen2.5:7b" {
+		t.Errorf("request = %+v, want a streamed request", got)
+	}
+
+	if len(chunks) != 3 || chunks[0].Content != "西湖" || chunks[1].Content != "和灵隐寺" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if chunks[0].Reasoning != "用户想去西湖" || chunks[1].Reasoning != "" {
+		t.Errorf("reasoning = %q and %q, want the trace on the first chunk", chunks[0].Reasoning, chunks[1].Reasoning)
+	}
+	if chunks[0].Meta != nil || chunks[1].Meta != nil {
+		t.Error("a chunk before the done chunk carries metadata")
+	}
+	want := &mock.ResponseMeta{
+		Model:         "qwen2.5:7b",
+		FinishReason:  "stop",
+		TotalDuration: 1500 * time.Millisecond,
+		Usage:         mock.TokenUsage{PromptTokens: 20, CompletionTokens: 8, TotalTokens: 28},
+	}
+	if meta := chunks[2].Meta; meta == nil || *meta != *want {
+		t.Errorf("done chunk meta = %+v, want %+v", meta, want)
+	}
+
+	// Recv keeps returning io.EOF after the done chunk
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Recv after the end = %v, want io.EOF", err)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+		// chunks is the number of chunks received before the error
+		chunks int
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name: "error object mid-stream",
+			parts: []string{
+				`{"message": {"role": "assistant", "content": "西湖"}, "done": false}` + "\n",
+				`{"error": "an unknown error was encountered while running the model"}` + "\n",
+			},
+			chunks: 1,
+			check: func(t *testing.T, err error) {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK || !strings.Contains(apiErr.Message, "unknown error") {
+					t.Errorf("error = %#v, want an APIError with the server message", err)
+				}
+			},
+		},
+		{
+			name:   "no done chunk",
+			parts:  []string{`{"message": {"role": "assistant", "content": "西湖"}, "done": false}` + "\n"},
+			chunks: 1,
+			check: func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "stream ended before completion") {
+					t.Errorf("error = %v, want an incomplete stream", err)
+				}
+			},
+		},
+		{
+			name:  "broken line",
+			parts: []string{`{"message": {"role": "assistant", "content": "西湖"}` + "\n"},
+			check: func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "failed to decode stream chunk") {
+					t.Errorf("error = %v, want a decoding error", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+				writeParts(w, tt.parts...)
+			})
+			stream, err := NewChatModel(server.URL, "qwen2.5:7b").Stream(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}})
+			if err != nil {
+				t.Fatalf("Stream: %v", err)
+			}
+			chunks, err := streamChunks(stream)
+			if len(chunks) != tt.chunks {
+				t.Errorf("got %d chunks before the error, want %d", len(chunks), tt.chunks)
+			}
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+		writeParts(w, `{"message": {"role": "assistant", "content": "<think></think>西湖"}, "done": false}`+"\n")
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := NewChatModel(server.URL, "qwen2.5:7b").Stream(ctx, []*mock.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer stream.Close()
+	if chunk, err := stream.Recv(); err != nil || chunk.Content != "西湖" {
+		t.Fatalf("Recv = %+v, %v", chunk, err)
+	}
+
+	// Cancelling unblocks the pending read with the context error
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := stream.Recv(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Recv after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamStatusError(t *testing.T) {
+	server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error": "model %q not found, try pulling it first"}`, req.Model)
+	})
+
+	_, err := NewChatModel(server.URL, "qwen3:8b").Stream(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}})
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("error = %v, want ErrModelNotFound before any chunk", err)
+	}
+}
@@ -0,0 +1,103 @@
+package ollama
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+)
+
+// Tool represents a function definition in the Ollama API
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON-schema parameters
+type ToolFunction struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Parameters  *mock.Schema `json:"parameters"`
+}
+
+// ToolCall represents a tool call in the Ollama API
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the name and arguments of a requested function call
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// toOllamaTool converts a mock.Tool into an Ollama function definition
+func toOllamaTool(tool mock.Tool) Tool {
+	parameters := tool.Parameters()
+	if parameters == nil {
+		parameters = mock.EmptyObjectSchema()
+	}
+
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  parameters,
+		},
+	}
+}
+
+// fromOllamaToolCalls converts tool calls returned by Ollama into mock.ToolCall values.
+// Ollama does not assign call IDs, so they are derived from the call position.
+func fromOllamaToolCalls(calls []ToolCall) []mock.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	toolCalls := make([]mock.ToolCall, len(calls))
+	for i, call := range calls {
+		args := call.Function.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		toolCalls[i] = mock.ToolCall{
+			ID:   fmt.Sprintf("call_%d", i),
+			Name: call.Function.Name,
+			Args: args,
+		}
+	}
+	return toolCalls
+}
+
+// toOllamaMessage converts a mock.Message into the Ollama message format,
+// including attached images, previous tool calls and tool results
+func toOllamaMessage(msg *mock.Message) Message {
+	ollamaMsg := Message{
+		Role:    msg.Role,
+		Content: msg.Content,
+	}
+
+	for _, image := range msg.Images {
+		ollamaMsg.Images = append(ollamaMsg.Images, image.Base64())
+	}
+
+	for _, call := range msg.ToolCalls {
+		ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, ToolCall{
+			Function: ToolCallFunction{
+				Name:      call.Name,
+				Arguments: call.Args,
+			},
+		})
+	}
+
+	if msg.Role == "tool" {
+		ollamaMsg.ToolName = msg.Name
+		if ollamaMsg.Content == "" && msg.ToolResult != nil {
+			if result, err := json.Marshal(msg.ToolResult); err == nil {
+				ollamaMsg.Content = string(result)
+			}
+		}
+	}
+
+	return ollamaMsg
+}
@@ -0,0 +1,165 @@
+package ollama
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// weatherTool is a tool with parameters that reports sunny weather
+func weatherTool() mock.Tool {
+	return mock.NewMockTool("get_weather", "查询城市天气", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"city": args["city"], "condition": "晴"}, nil
+	}).WithParameters(&mock.Schema{
+		Type:       "object",
+		Properties: map[string]*mock.Schema{"city": {Type: "string", Description: "城市名称"}},
+		Required:   []string{"city"},
+	})
+}
+
+// decodeJSON decodes a JSON document into a generic value
+func decodeJSON(t *testing.T, data string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return v
+}
+
+func TestBindTools(t *testing.T) {
+	var bodies []map[string]interface{}
+	server, _ := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		fmt.Fprint(w, chatReply)
+	})
+
+	m := NewChatModel(server.URL, "qwen2.5:7b")
+	ctx := context.Background()
+	ask(ctx, m)
+	if _, ok := bodies[0]["tools"]; ok {
+		t.Errorf("request without bound tools has tools: %v", bodies[0]["tools"])
+	}
+
+	clock := mock.NewMockTool("get_time", "查询当前时间", nil).WithParameters(nil)
+	if err := m.BindTools([]mock.Tool{weatherTool(), clock}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	ask(ctx, m)
+	want := decodeJSON(t, `[
+		{"type": "function", "function": {
+			"name": "get_weather",
+			"description": "查询城市天气",
+			"parameters": {"type": "object", "properties": {"city": {"type": "string", "description": "城市名称"}}, "required": ["city"]}
+		}},
+		{"type": "function", "function": {
+			"name": "get_time",
+			"description": "查询当前时间",
+			"parameters": {"type": "object"}
+		}}
+	]`)
+	if !reflect.DeepEqual(bodies[1]["tools"], want) {
+		t.Errorf("tools = %v, want %v", bodies[1]["tools"], want)
+	}
+
+	// Tools can be left out of a single call
+	ask(mock.WithoutTools(ctx), m)
+	if _, ok := bodies[2]["tools"]; ok {
+		t.Errorf("request with tools disabled has tools: %v", bodies[2]["tools"])
+	}
+
+	result, err := m.ExecuteTool(ctx, "get_weather", map[string]interface{}{"city": "杭州"})
+	if err != nil || result["condition"] != "晴" {
+		t.Errorf("ExecuteTool = %v, %v", result, err)
+	}
+	if _, err := m.ExecuteTool(ctx, "get_news", nil); err == nil {
+		t.Error("an unknown tool was executed")
+	}
+}
+
+func TestToolCallsReply(t *testing.T) {
+	server := newChatServer(t, func(w http.ResponseWriter, req Request) {
+		fmt.Fprint(w, `{"model": "qwen2.5:7b", "message": {"role": "assistant", "content": "", "tool_calls": [
+			{"function": {"name": "get_weather", "arguments": {"city": "杭州", "days": 3}}},
+			{"function": {"name": "get_time"}}
+		]}, "done": true, "done_reason": "stop"}`)
+	})
+
+	reply, err := ask(context.Background(), NewChatModel(server.URL, "qwen2.5:7b"))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := []mock.ToolCall{
+		{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州", "days": float64(3)}},
+		{ID: "call_1", Name: "get_time", Args: map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(reply.ToolCalls, want) {
+		t.Errorf("tool calls = %+v, want %+v", reply.ToolCalls, want)
+	}
+
+	// A streamed reply carries the tool calls on the chunk that has them
+	server = newChatServer(t, func(w http.ResponseWriter, req Request) {
+		writeParts(w,
+			`{"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "杭州"}}}]}, "done": false}`+"\n",
+			`{"message": {"role": "assistant", "content": ""}, "done": true, "done_reason": "stop"}`+"\n",
+		)
+	})
+	stream, err := NewChatModel(server.URL, "qwen2.5:7b").Stream(context.Background(), []*mock.Message{{Role: "user", Content: "杭州天气"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	msg, err := mock.CollectStream(stream)
+	if err != nil || len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Name != "get_weather" || msg.ToolCalls[0].Args["city"] != "杭州" {
+		t.Errorf("streamed %+v, %v, want the get_weather call", msg, err)
+	}
+}
+
+func TestToOllamaMessage(t *testing.T) {
+	image := mock.NewImage("west_lake.png", []byte("\x89PNG\r\n\x1a\n"))
+	tests := []struct {
+		name string
+		msg  *mock.Message
+		want string
+	}{
+		{
+			name: "user with image",
+			msg:  &mock.Message{Role: "user", Content: "描述图片", Images: []mock.Image{image}},
+			want: `{"role": "user", "content": "描述图片", "images": ["iVBORw0KGgo="]}`,
+		},
+		{
+			name: "assistant tool calls",
+			msg: &mock.Message{Role: "assistant", ToolCalls: []mock.ToolCall{
+				{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}},
+			}},
+			want: `{"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "get_weather", "arguments": {"city": "杭州"}}}]}`,
+		},
+		{
+			name: "tool result",
+			msg: &mock.Message{Role: "tool", Name: "get_weather", ToolCallID: "call_0",
+				ToolResult: map[string]interface{}{"condition": "晴"}},
+			want: `{"role": "tool", "content": "{\"condition\":\"晴\"}", "tool_name": "get_weather"}`,
+		},
+		{
+			name: "tool result with content",
+			msg:  &mock.Message{Role: "tool", Name: "get_weather", Content: "晴", ToolResult: map[string]interface{}{"condition": "晴"}},
+			want: `{"role": "tool", "content": "晴", "tool_name": "get_weather"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(toOllamaMessage(tt.msg))
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if got, want := decodeJSON(t, string(data)), decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("message = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
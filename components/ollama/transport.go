@@ -0,0 +1,313 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the circuit
+// breaker considers it down
+var ErrCircuitOpen = errors.New("ollama: circuit breaker is open, server appears to be unavailable")
+
+// defaultHTTPClient is shared by all chat models. It has no overall timeout
+// because streamed answers can take minutes; requests are bounded by their
+// context instead.
+var defaultHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          64,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// RetryPolicy controls how failed requests are retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay, with random jitter.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy is used by chat models unless configured otherwise
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   8 * time.Second,
+}
+
+// backoff returns the jittered delay before the given retry attempt (0-based)
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: half fixed, half random, so retries from concurrent
+	// agents do not hit the server in lockstep
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the server
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *ChatModel) {
+		m.client = client
+	}
+}
+
+// WithRetryPolicy sets the retry policy. A zero MaxRetries disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(m *ChatModel) {
+		m.retry = policy
+	}
+}
+
+// WithCircuitBreaker configures the circuit breaker: after threshold
+// consecutive failures requests fail fast with ErrCircuitOpen for cooldown,
+// then a single trial request decides whether to close it again.
+// A threshold of zero disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(m *ChatModel) {
+		if threshold <= 0 {
+			m.breaker = nil
+			return
+		}
+		m.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// do sends a request to the server, retrying transient failures. On success
+// the caller owns the response body; non-200 answers are returned as *APIError.
+func (m *ChatModel) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := m.breaker.allow(); err != nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%v (last error: %v)", err, lastErr)
+			}
+			return nil, err
+		}
+
+		resp, retryAfter, err := m.send(ctx, method, path, body)
+		if err == nil {
+			m.breaker.success()
+			return resp, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			m.breaker.release()
+			return nil, ctxErr
+		}
+
+		if !isRetryable(err) {
+			// The server answered, so it is up even though the request failed
+			m.breaker.success()
+			return nil, err
+		}
+		m.breaker.failure()
+
+		lastErr = err
+		if attempt >= m.retry.MaxRetries {
+			return nil, lastErr
+		}
+
+		delay := m.retry.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// Waiting would exceed the deadline, give up with the real error
+			return nil, lastErr
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send performs a single HTTP round trip, holding a limiter slot for
+// generation requests. For failed responses it also returns the delay
+// requested by a Retry-After header.
+func (m *ChatModel) send(ctx context.Context, method, path string, body []byte) (*http.Response, time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.baseURL+path, reader)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	limiter := m.limiter
+	if !limitedPaths[path] {
+		limiter = nil
+	}
+	if err := limiter.Acquire(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		limiter.Release()
+		return nil, 0, &transportError{err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		limiter.Release()
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), newAPIError(resp)
+	}
+
+	if limiter != nil {
+		resp.Body = &releasingBody{ReadCloser: resp.Body, limiter: limiter}
+	}
+	return resp, 0, nil
+}
+
+// transportError marks failures that happened before the server answered
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return fmt.Sprintf("failed to send request: %v", e.err)
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// isRetryable reports whether a failed request may succeed when retried:
+// connection failures, rate limiting and the gateway/unavailable statuses
+// Ollama returns while a model is loading
+func isRetryable(err error) bool {
+	var tErr *transportError
+	if errors.As(err, &tErr) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// circuitBreaker fails requests fast after repeated failures. A nil breaker
+// allows everything.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a request may be sent. Once the cooldown has passed
+// a single probe request is let through while others keep failing fast.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// success closes the breaker
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// release gives up a probe whose outcome is unknown, e.g. after cancellation
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// failure records a failed request and opens the breaker at the threshold
+func (b *circuitBreaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
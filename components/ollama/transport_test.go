@@ -0,0 +1,223 @@
+package ollama
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetries retries quickly so that tests do not wait for the backoff
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// chatReply is a complete non-streamed /api/chat answer
+const chatReply = `{"model": "qwen2.5:7b", "message": {"role": "assistant", "content": "西湖"}, "done": true}`
+
+// newCountingServer serves every request with handler, passing the 1-based
+// number of the request, and returns the server and its request counter
+func newCountingServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, int(requests.Add(1)))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// ask sends a one message chat request to m
+func ask(ctx context.Context, m *ChatModel) (*mock.Message, error) {
+	return m.Generate(ctx, []*mock.Message{{Role: "user", Content: "推荐景点"}})
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures is the number of requests answered with status
+		failures int
+		status   int
+		requests int32
+		ok       bool
+	}{
+		{"bad gateway", 2, http.StatusBadGateway, 3, true},
+		{"service unavailable", 1, http.StatusServiceUnavailable, 2, true},
+		{"rate limited", 1, http.StatusTooManyRequests, 2, true},
+		{"retries exhausted", 10, http.StatusServiceUnavailable, 4, false},
+		{"bad request", 10, http.StatusBadRequest, 1, false},
+		{"model not found", 10, http.StatusNotFound, 1, false},
+		{"internal error", 10, http.StatusInternalServerError, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+				if n <= tt.failures {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"error": "model is loading"}`)
+					return
+				}
+				fmt.Fprint(w, chatReply)
+			})
+
+			m := NewChatModel(server.URL, "qwen2.5:7b", WithRetryPolicy(fastRetries), WithCircuitBreaker(0, 0))
+			reply, err := ask(context.Background(), m)
+			if tt.ok && (err != nil || reply.Content != "西湖") {
+				t.Errorf("Generate = %+v, %v", reply, err)
+			}
+			var apiErr *APIError
+			if !tt.ok && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.status) {
+				t.Errorf("error = %v, want an APIError with status %d", err, tt.status)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("sent %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	server, requests := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 1 {
+			// Outlive the client timeout
+			select {
+			case <-r.Context().Done():
+			case <-time.After(300 * time.Millisecond):
+			}
+			return
+		}
+		fmt.Fprint(w, chatReply)
+	})
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	m := NewChatModel(server.URL, "qwen2.5:7b", WithHTTPClient(client), WithRetryPolicy(fastRetries))
+	if reply, err := ask(context.Background(), m); err != nil || reply.Content != "西湖" {
+		t.Errorf("Generate = %+v, %v, want the retried answer", reply, err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestRetryStopsAtContext(t *testing.T) {
+	server, requests := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// The backoff outlasts the deadline, so the error of the only attempt is
+	// returned rather than the deadline
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second}
+	m := NewChatModel(server.URL, "qwen2.5:7b", WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := ask(ctx, m)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("error = %v, want the 503", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{"": 0, "2": 2 * time.Second, "-1": 0, "Wed, 21 Oct 2015 07:28:00 GMT": 0}
+	for value, want := range tests {
+		if got := parseRetryAfter(value); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		if delay := policy.backoff(attempt); delay < max/2 || delay > max {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", attempt, delay, max/2, max)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	release := make(chan struct{})
+	server, requests := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		<-release
+		fmt.Fprint(w, chatReply)
+	})
+
+	cooldown := 50 * time.Millisecond
+	m := NewChatModel(server.URL, "qwen2.5:7b", WithRetryPolicy(RetryPolicy{}), WithCircuitBreaker(2, cooldown))
+	ctx := context.Background()
+
+	// Closed: failures are counted up to the threshold
+	for i := 0; i < 2; i++ {
+		if _, err := ask(ctx, m); errors.Is(err, ErrCircuitOpen) || err == nil {
+			t.Fatalf("call %d error = %v, want the 503", i, err)
+		}
+	}
+
+	// Open: requests fail fast without reaching the server
+	if _, err := ask(ctx, m); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("the open breaker let %d requests through", n-2)
+	}
+
+	// Half-open after the cooldown: a failed probe opens it again
+	time.Sleep(cooldown + 10*time.Millisecond)
+	if _, err := ask(ctx, m); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe error = %v, want the 503", err)
+	}
+	if _, err := ask(ctx, m); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after a failed probe = %v, want ErrCircuitOpen", err)
+	}
+
+	// Half-open again: a single probe is let through while others fail fast
+	time.Sleep(cooldown + 10*time.Millisecond)
+	healthy.Store(true)
+	probe := make(chan error, 1)
+	go func() {
+		_, err := ask(ctx, m)
+		probe <- err
+	}()
+	for requests.Load() != 4 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := ask(ctx, m); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("error during the probe = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-probe; err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+
+	// Recovered: the breaker is closed
+	if reply, err := ask(ctx, m); err != nil || reply.Content != "西湖" {
+		t.Errorf("Generate after recovery = %+v, %v", reply, err)
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	server, requests := newCountingServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid options"}`)
+	})
+
+	m := NewChatModel(server.URL, "qwen2.5:7b", WithRetryPolicy(RetryPolicy{}), WithCircuitBreaker(2, time.Minute))
+	for i := 0; i < 5; i++ {
+		if _, err := ask(context.Background(), m); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: the breaker opened on a client error", i)
+		}
+	}
+	if n := requests.Load(); n != 5 {
+		t.Errorf("sent %d requests, want 5", n)
+	}
+}
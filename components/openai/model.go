@@ -0,0 +1,373 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHTTPClient is shared by all chat models; requests are bounded by their context
+var defaultHTTPClient = &http.Client{}
+
+// ChatModel is an implementation of mock.ChatModel for servers speaking the
+// OpenAI /v1/chat/completions protocol, such as vLLM and llama.cpp. It is
+// safe for concurrent use.
+type ChatModel struct {
+	baseURL string
+	model   string
+	apiKey  string
+
+	// mu guards the bound tools
+	mu    sync.RWMutex
+	tools []Tool
+
+	options       mock.Options
+	reasoningMode mock.ReasoningMode
+	client        *http.Client
+}
+
+// Option configures a ChatModel
+type Option func(*ChatModel)
+
+// WithAPIKey sets the bearer token sent with every request
+func WithAPIKey(apiKey string) Option {
+	return func(m *ChatModel) {
+		m.apiKey = apiKey
+	}
+}
+
+// WithDefaultOptions sets the generation options used by every call. Options
+// carried by the call context (see mock.WithOptions) take precedence.
+// NumCtx and KeepAlive have no equivalent in this protocol and are ignored.
+func WithDefaultOptions(opts mock.Options) Option {
+	return func(m *ChatModel) {
+		m.options = opts
+	}
+}
+
+// WithReasoningMode sets how reasoning traces are handled
+func WithReasoningMode(mode mock.ReasoningMode) Option {
+	return func(m *ChatModel) {
+		m.reasoningMode = mode
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to talk to the server
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *ChatModel) {
+		m.client = client
+	}
+}
+
+// NewChatModel creates a new ChatModel instance. baseURL includes the API
+// version prefix, e.g. http://localhost:8000/v1.
+func NewChatModel(baseURL, model string, opts ...Option) *ChatModel {
+	m := &ChatModel{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		client:  defaultHTTPClient,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Request represents a chat completion request
+type Request struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat constrains the reply to JSON matching a schema
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names the schema of a structured reply
+type JSONSchema struct {
+	Name   string       `json:"name"`
+	Schema *mock.Schema `json:"schema"`
+}
+
+// StreamOptions asks the server to report token usage at the end of a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// Message represents a message in the chat completion API. When Parts is set
+// the message is sent with multimodal content parts instead of Content.
+type Message struct {
+	Role             string        `json:"role"`
+	Content          string        `json:"content"`
+	Parts            []ContentPart `json:"-"`
+	ReasoningContent string        `json:"reasoning_content,omitempty"`
+	Name             string        `json:"name,omitempty"`
+	ToolCallID       string        `json:"tool_call_id,omitempty"`
+	ToolCalls        []ToolCall    `json:"tool_calls,omitempty"`
+}
+
+// ContentPart is a text or image part of a multimodal message
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image, usually inline as a data URL
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON encodes the content as parts when the message has any
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []ContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// Response represents a chat completion response
+type Response struct {
+	ID      string   `json:"id"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Choice represents one completion choice
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// Usage reports the tokens consumed by a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ModelName returns the name of the model served by the backend
+func (m *ChatModel) ModelName() string {
+	return m.model
+}
+
+// Options returns the default generation options of the model
+func (m *ChatModel) Options() mock.Options {
+	return m.options
+}
+
+// Generate generates a response from the model
+func (m *ChatModel) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	start := time.Now()
+	resp, err := m.post(ctx, m.newRequest(ctx, messages, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion Response
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("response contains no choices")
+	}
+
+	choice := completion.Choices[0]
+	toolCalls, err := fromToolCalls(choice.Message.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	reasoning, answer := mock.SplitReasoning(choice.Message.Content)
+	if choice.Message.ReasoningContent != "" {
+		reasoning = strings.TrimSpace(choice.Message.ReasoningContent + "\n" + reasoning)
+	}
+
+	msg := &mock.Message{
+		Role:      "assistant",
+		Content:   answer,
+		ToolCalls: toolCalls,
+		Meta:      newMeta(completion.Model, choice.FinishReason, time.Since(start), completion.Usage),
+	}
+	m.handleReasoning(msg, reasoning)
+	return msg, nil
+}
+
+// BindTools binds tools to the chat model. The tools are advertised to the
+// model as function definitions on every subsequent request.
+func (m *ChatModel) BindTools(tools []mock.Tool) error {
+	defs := make([]Tool, len(tools))
+	for i, tool := range tools {
+		defs[i] = toTool(tool)
+	}
+	m.mu.Lock()
+	m.tools = defs
+	m.mu.Unlock()
+	return nil
+}
+
+// newRequest converts messages into a chat completion request, applying the
+// default options overridden by the options carried by ctx
+func (m *ChatModel) newRequest(ctx context.Context, messages []*mock.Message, stream bool) Request {
+	converted := make([]Message, len(messages))
+	for i, msg := range messages {
+		converted[i] = toMessage(msg)
+	}
+
+	// BindTools replaces the slice, so the one read here stays unchanged
+	m.mu.RLock()
+	tools := m.tools
+	m.mu.RUnlock()
+	if mock.ToolsDisabled(ctx) {
+		tools = nil
+	}
+
+	opts := m.options.Merge(mock.OptionsFromContext(ctx))
+	req := Request{
+		Model:       m.model,
+		Messages:    converted,
+		Stream:      stream,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		Seed:        opts.Seed,
+		Stop:        opts.Stop,
+		Tools:       tools,
+	}
+	if opts.NumPredict > 0 {
+		req.MaxTokens = opts.NumPredict
+	}
+	if opts.Format != nil {
+		req.ResponseFormat = &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &JSONSchema{Name: "response", Schema: opts.Format},
+		}
+	}
+	if stream {
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return req
+}
+
+// post sends a request to the /chat/completions endpoint and checks the response status
+func (m *ChatModel) post(ctx context.Context, reqBody Request) (*http.Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	if reqBody.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+	return resp, nil
+}
+
+// handleReasoning applies the configured reasoning mode to a trace
+func (m *ChatModel) handleReasoning(msg *mock.Message, reasoning string) {
+	switch m.reasoningMode {
+	case mock.ReasoningKeep:
+		msg.Reasoning = reasoning
+	case mock.ReasoningLog:
+		if strings.TrimSpace(reasoning) != "" {
+			log.Printf("[%s reasoning] %s", m.model, strings.TrimSpace(reasoning))
+		}
+	}
+}
+
+// newMeta builds the response metadata from the reported usage
+func newMeta(model, finishReason string, elapsed time.Duration, usage *Usage) *mock.ResponseMeta {
+	meta := &mock.ResponseMeta{
+		Model:         model,
+		FinishReason:  finishReason,
+		TotalDuration: elapsed,
+	}
+	if usage != nil {
+		meta.Usage = mock.TokenUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+		}
+	}
+	return meta
+}
+
+// APIError is returned when the server answers with a non-200 status
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("openai: unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("openai: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// newAPIError builds an APIError from a failed HTTP response
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		apiErr.Message = errResp.Error.Message
+		apiErr.Type = errResp.Error.Type
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
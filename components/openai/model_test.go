@@ -0,0 +1,407 @@
+package openai
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubServer serves a fixed reply on /v1/chat/completions and records the
+// decoded request bodies
+type stubServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []map[string]interface{}
+}
+
+func newStubServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]interface{})) *stubServer {
+	t.Helper()
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, body)
+		s.mu.Unlock()
+		handler(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// lastRequest returns the last request body received
+func (s *stubServer) lastRequest(t *testing.T) map[string]interface{} {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("server received no request")
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+func (s *stubServer) model(opts ...Option) *ChatModel {
+	return NewChatModel(s.URL+"/v1", "test-model", opts...)
+}
+
+func TestGenerateReportsUsage(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, `{
+			"id": "chatcmpl-1",
+			"model": "test-model-q4",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "西湖很美"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 5, "total_tokens": 17}
+		}`)
+	})
+
+	reply, err := server.model().Generate(context.Background(), []*mock.Message{{Role: "user", Content: "杭州有什么好玩的？"}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if reply.Role != "assistant" || reply.Content != "西湖很美" {
+		t.Errorf("reply = %q %q, want assistant 西湖很美", reply.Role, reply.Content)
+	}
+	if reply.Meta == nil {
+		t.Fatal("reply has no metadata")
+	}
+	if reply.Meta.Model != "test-model-q4" || reply.Meta.FinishReason != "stop" {
+		t.Errorf("meta = %+v", reply.Meta)
+	}
+	want := mock.TokenUsage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}
+	if reply.Meta.Usage != want {
+		t.Errorf("usage = %+v, want %+v", reply.Meta.Usage, want)
+	}
+
+	req := server.lastRequest(t)
+	if req["model"] != "test-model" || req["stream"] != false {
+		t.Errorf("request model/stream = %v/%v", req["model"], req["stream"])
+	}
+}
+
+func TestGenerateSendsOptions(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "{}"}}]}`)
+	})
+	model := server.model(WithDefaultOptions(mock.Options{
+		Temperature: mock.Float64(0.2),
+		TopP:        mock.Float64(0.9),
+		NumPredict:  128,
+	}))
+
+	// Options carried by the context override the defaults
+	ctx := mock.WithOptions(context.Background(), mock.Options{
+		Temperature: mock.Float64(0.7),
+		Seed:        mock.Int(42),
+		Stop:        []string{"END"},
+		Format:      mock.EmptyObjectSchema(),
+	})
+	if _, err := model.Generate(ctx, []*mock.Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	req := server.lastRequest(t)
+	for key, want := range map[string]interface{}{
+		"temperature": 0.7,
+		"top_p":       0.9,
+		"max_tokens":  128.0,
+		"seed":        42.0,
+	} {
+		if req[key] != want {
+			t.Errorf("%s = %v, want %v", key, req[key], want)
+		}
+	}
+	if stop, _ := req["stop"].([]interface{}); len(stop) != 1 || stop[0] != "END" {
+		t.Errorf("stop = %v, want [END]", req["stop"])
+	}
+	format, _ := req["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" || format["json_schema"] == nil {
+		t.Errorf("response_format = %v", req["response_format"])
+	}
+}
+
+func TestGenerateMapsToolCalls(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "", "tool_calls": [
+			{"id": "call_a", "type": "function", "function": {"name": "get_weather", "arguments": "{\"date\":\"2024-02-18\"}"}},
+			{"type": "function", "function": {"name": "search_hotels", "arguments": ""}}
+		]}, "finish_reason": "tool_calls"}]}`)
+	})
+	model := server.model()
+
+	tool := mock.NewMockTool("get_weather", "查询天气", nil).WithParameters(&mock.Schema{
+		Type:       "object",
+		Properties: map[string]*mock.Schema{"date": {Type: "string"}},
+	})
+	if err := model.BindTools([]mock.Tool{tool}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+
+	messages := []*mock.Message{
+		{Role: "user", Content: "明天天气？"},
+		{Role: "assistant", ToolCalls: []mock.ToolCall{{ID: "call_0", Name: "get_weather", Args: map[string]interface{}{"date": "2024-02-17"}}}},
+		{Role: "tool", Name: "get_weather", ToolCallID: "call_0", ToolResult: map[string]interface{}{"condition": "晴"}},
+	}
+	reply, err := model.Generate(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if len(reply.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(reply.ToolCalls))
+	}
+	first, second := reply.ToolCalls[0], reply.ToolCalls[1]
+	if first.ID != "call_a" || first.Name != "get_weather" || first.Args["date"] != "2024-02-18" {
+		t.Errorf("first call = %+v", first)
+	}
+	// Calls without an ID get one from their position, and no arguments
+	// decode to an empty map
+	if second.ID != "call_1" || second.Name != "search_hotels" || second.Args == nil || len(second.Args) != 0 {
+		t.Errorf("second call = %+v", second)
+	}
+
+	req := server.lastRequest(t)
+	tools, _ := req["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("request advertises %d tools, want 1", len(tools))
+	}
+	function := tools[0].(map[string]interface{})["function"].(map[string]interface{})
+	if function["name"] != "get_weather" || function["parameters"] == nil {
+		t.Errorf("tool definition = %v", function)
+	}
+
+	sent := req["messages"].([]interface{})
+	call := sent[1].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+	if args := call["function"].(map[string]interface{})["arguments"]; args != `{"date":"2024-02-17"}` {
+		t.Errorf("previous call arguments = %v", args)
+	}
+	result := sent[2].(map[string]interface{})
+	if result["tool_call_id"] != "call_0" || result["name"] != "get_weather" || result["content"] != `{"condition":"晴"}` {
+		t.Errorf("tool result message = %v", result)
+	}
+}
+
+func TestStreamAccumulatesChunks(t *testing.T) {
+	frames := []string{
+		`{"model": "test-model-q4", "choices": [{"index": 0, "delta": {"role": "assistant", "content": "我来"}}]}`,
+		`{"choices": [{"index": 0, "delta": {"content": "查一下"}}]}`,
+		`{"choices": [{"index": 0, "delta": {"tool_calls": [{"index": 0, "id": "call_w", "type": "function", "function": {"name": "get_weather", "arguments": "{\"loc"}}]}}]}`,
+		`{"choices": [{"index": 0, "delta": {"tool_calls": [{"index": 0, "function": {"arguments": "ation\":\"西"}}]}}]}`,
+		`{"choices": [{"index": 0, "delta": {"tool_calls": [{"index": 0, "function": {"arguments": "湖\"}"}}]}, "finish_reason": "tool_calls"}]}`,
+		`{"choices": [], "usage": {"prompt_tokens": 20, "completion_tokens": 8, "total_tokens": 28}}`,
+	}
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, frame := range frames {
+			// Comments and blank lines between events are ignored
+			fmt.Fprintf(w, ": keep-alive\n\ndata: %s\n\n", frame)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := server.model().Stream(context.Background(), []*mock.Message{{Role: "user", Content: "西湖天气"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	defer stream.Close()
+
+	var chunks []*mock.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Recv after the end = %v, want io.EOF", err)
+	}
+
+	var collector mock.StreamCollector
+	for _, chunk := range chunks {
+		collector.Add(chunk)
+	}
+	reply := collector.Message()
+	if reply.Content != "我来查一下" {
+		t.Errorf("content = %q, want 我来查一下", reply.Content)
+	}
+	if len(reply.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(reply.ToolCalls))
+	}
+	if call := reply.ToolCalls[0]; call.ID != "call_w" || call.Name != "get_weather" || call.Args["location"] != "西湖" {
+		t.Errorf("tool call = %+v", call)
+	}
+
+	// Tool calls and metadata come with the final chunk only
+	final := chunks[len(chunks)-1]
+	if len(final.ToolCalls) != 1 || final.Meta == nil {
+		t.Fatalf("final chunk = %+v", final)
+	}
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk.ToolCalls) > 0 || chunk.Meta != nil {
+			t.Errorf("intermediate chunk carries tool calls or metadata: %+v", chunk)
+		}
+	}
+	want := mock.TokenUsage{PromptTokens: 20, CompletionTokens: 8, TotalTokens: 28}
+	if final.Meta.Usage != want || final.Meta.Model != "test-model-q4" || final.Meta.FinishReason != "tool_calls" {
+		t.Errorf("meta = %+v", final.Meta)
+	}
+
+	req := server.lastRequest(t)
+	options, _ := req["stream_options"].(map[string]interface{})
+	if req["stream"] != true || options["include_usage"] != true {
+		t.Errorf("request stream/stream_options = %v/%v", req["stream"], req["stream_options"])
+	}
+}
+
+func TestStreamWithoutDoneMarker(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"好\"}, \"finish_reason\": \"stop\"}]}\n\n")
+	})
+
+	stream, err := server.model().Stream(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	reply, err := mock.CollectStream(stream)
+	if err != nil {
+		t.Fatalf("CollectStream: %v", err)
+	}
+	if reply.Content != "好" || reply.Meta == nil || reply.Meta.FinishReason != "stop" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+		errType string
+	}{
+		{
+			name:    "openai error object",
+			status:  http.StatusBadRequest,
+			body:    `{"error": {"message": "maximum context length exceeded", "type": "invalid_request_error"}}`,
+			message: "maximum context length exceeded",
+			errType: "invalid_request_error",
+		},
+		{
+			name:    "plain text",
+			status:  http.StatusServiceUnavailable,
+			body:    "model is loading\n",
+			message: "model is loading",
+		},
+		{
+			name:   "empty body",
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			model := server.model()
+			messages := []*mock.Message{{Role: "user", Content: "hi"}}
+
+			_, generateErr := model.Generate(context.Background(), messages)
+			_, streamErr := model.Stream(context.Background(), messages)
+			for _, err := range []error{generateErr, streamErr} {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("error = %v, want *APIError", err)
+				}
+				if apiErr.StatusCode != tt.status || apiErr.Message != tt.message || apiErr.Type != tt.errType {
+					t.Errorf("error = %+v", apiErr)
+				}
+				if !strings.Contains(err.Error(), fmt.Sprint(tt.status)) {
+					t.Errorf("error message %q lacks the status code", err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`)
+	}))
+	defer server.Close()
+
+	model := NewChatModel(server.URL+"/v1/", "test-model", WithAPIKey("secret"))
+	if _, err := model.Generate(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", auth)
+	}
+}
+
+func TestBindToolsConcurrently(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`)
+	})
+	model := server.model()
+	tool := mock.NewMockTool("noop", "does nothing", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			model.BindTools([]mock.Tool{tool})
+		}()
+		go func() {
+			defer wg.Done()
+			model.Generate(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}})
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithoutToolsOmitsBoundTools(t *testing.T) {
+	server := newStubServer(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`)
+	})
+	model := server.model()
+	if err := model.BindTools([]mock.Tool{mock.NewMockTool("noop", "does nothing", nil)}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	messages := []*mock.Message{{Role: "user", Content: "hi"}}
+
+	if _, err := model.Generate(mock.WithoutTools(context.Background()), messages); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if tools, ok := server.lastRequest(t)["tools"]; ok {
+		t.Errorf("request without tools advertises %v", tools)
+	}
+
+	if _, err := model.Generate(context.Background(), messages); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if tools, _ := server.lastRequest(t)["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("later request advertises %d tools, want 1", len(tools))
+	}
+}
@@ -0,0 +1,204 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamResponse represents a single server-sent chunk of a streamed completion
+type StreamResponse struct {
+	ID      string         `json:"id"`
+	Model   string         `json:"model"`
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+// StreamChoice holds the incremental delta of one choice
+type StreamChoice struct {
+	Index        int     `json:"index"`
+	Delta        Message `json:"delta"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// Stream generates a response from the model and yields it chunk by chunk.
+// Tool call fragments are accumulated and returned with the final chunk.
+func (m *ChatModel) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	start := time.Now()
+	resp, err := m.post(ctx, m.newRequest(ctx, messages, true))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	return &messageStream{
+		ctx:       ctx,
+		model:     m,
+		resp:      resp,
+		scanner:   scanner,
+		start:     start,
+		toolCalls: map[int]*ToolCall{},
+	}, nil
+}
+
+// messageStream reads server-sent events from a streamed completion
+type messageStream struct {
+	ctx       context.Context
+	model     *ChatModel
+	resp      *http.Response
+	scanner   *bufio.Scanner
+	start     time.Time
+	done      bool
+	closeOnce sync.Once
+
+	splitter     mock.ReasoningSplitter
+	reasoning    strings.Builder
+	toolCalls    map[int]*ToolCall
+	finishReason string
+	modelName    string
+	usage        *Usage
+}
+
+// Recv returns the next message chunk, or io.EOF when the model has finished
+func (s *messageStream) Recv() (*mock.Message, error) {
+	for {
+		if s.done {
+			return nil, io.EOF
+		}
+		if err := s.ctx.Err(); err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil {
+				s.done = true
+				if ctxErr := s.ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				return nil, fmt.Errorf("failed to read stream: %v", err)
+			}
+			// Some servers close the stream without a [DONE] marker
+			return s.finish()
+		}
+
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if string(payload) == "[DONE]" {
+			return s.finish()
+		}
+
+		var chunk StreamResponse
+		if err := json.Unmarshal(payload, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %v", err)
+		}
+		if chunk.Model != "" {
+			s.modelName = chunk.Model
+		}
+		if chunk.Usage != nil {
+			s.usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			s.finishReason = choice.FinishReason
+		}
+		s.accumulateToolCalls(choice.Delta.ToolCalls)
+
+		reasoning, answer := s.splitter.Push(choice.Delta.Content)
+		msg := &mock.Message{Role: "assistant", Content: answer}
+		s.handleReasoning(msg, choice.Delta.ReasoningContent+reasoning)
+		return msg, nil
+	}
+}
+
+// finish builds the final chunk carrying held-back text, tool calls and metadata
+func (s *messageStream) finish() (*mock.Message, error) {
+	s.done = true
+
+	reasoning, answer := s.splitter.Flush()
+	msg := &mock.Message{
+		Role:    "assistant",
+		Content: answer,
+		Meta:    newMeta(s.modelName, s.finishReason, time.Since(s.start), s.usage),
+	}
+	s.handleReasoning(msg, reasoning)
+
+	indexes := make([]int, 0, len(s.toolCalls))
+	for index := range s.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	calls := make([]ToolCall, len(indexes))
+	for i, index := range indexes {
+		calls[i] = *s.toolCalls[index]
+	}
+
+	toolCalls, err := fromToolCalls(calls)
+	if err != nil {
+		return nil, err
+	}
+	msg.ToolCalls = toolCalls
+	return msg, nil
+}
+
+// accumulateToolCalls merges tool call fragments, keyed by their index
+func (s *messageStream) accumulateToolCalls(deltas []ToolCall) {
+	for i, delta := range deltas {
+		index := i
+		if delta.Index != nil {
+			index = *delta.Index
+		}
+
+		call, ok := s.toolCalls[index]
+		if !ok {
+			call = &ToolCall{Type: "function"}
+			s.toolCalls[index] = call
+		}
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		call.Function.Name += delta.Function.Name
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+// handleReasoning applies the model's reasoning mode to the trace of a chunk.
+// In log mode the whole trace is logged once the stream is done.
+func (s *messageStream) handleReasoning(msg *mock.Message, reasoning string) {
+	switch s.model.reasoningMode {
+	case mock.ReasoningKeep:
+		msg.Reasoning = reasoning
+	case mock.ReasoningLog:
+		s.reasoning.WriteString(reasoning)
+		if s.done {
+			s.model.handleReasoning(msg, s.reasoning.String())
+		}
+	}
+}
+
+// Close releases the underlying HTTP response
+func (s *messageStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.resp.Body.Close()
+	})
+	return err
+}
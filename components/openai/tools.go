@@ -0,0 +1,124 @@
+package openai
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+)
+
+// Tool represents a function definition in the chat completion API
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON-schema parameters
+type ToolFunction struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Parameters  *mock.Schema `json:"parameters"`
+}
+
+// ToolCall represents a tool call in the chat completion API. Arguments are
+// encoded as a JSON string. Index identifies the call across stream deltas.
+type ToolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the name and JSON-encoded arguments of a call
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// toTool converts a mock.Tool into a function definition
+func toTool(tool mock.Tool) Tool {
+	parameters := tool.Parameters()
+	if parameters == nil {
+		parameters = mock.EmptyObjectSchema()
+	}
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  parameters,
+		},
+	}
+}
+
+// fromToolCalls converts returned tool calls, decoding their JSON arguments
+func fromToolCalls(calls []ToolCall) ([]mock.ToolCall, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	toolCalls := make([]mock.ToolCall, len(calls))
+	for i, call := range calls {
+		args := map[string]interface{}{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("failed to decode arguments of tool call %s: %v", call.Function.Name, err)
+			}
+		}
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		toolCalls[i] = mock.ToolCall{
+			ID:   id,
+			Name: call.Function.Name,
+			Args: args,
+		}
+	}
+	return toolCalls, nil
+}
+
+// toMessage converts a mock.Message into the chat completion format,
+// including attached images, previous tool calls and tool results
+func toMessage(msg *mock.Message) Message {
+	converted := Message{
+		Role:    msg.Role,
+		Content: msg.Content,
+	}
+
+	if len(msg.Images) > 0 {
+		converted.Parts = append(converted.Parts, ContentPart{Type: "text", Text: msg.Content})
+		for _, image := range msg.Images {
+			converted.Parts = append(converted.Parts, ContentPart{
+				Type:     "image_url",
+				ImageURL: &ImageURL{URL: image.DataURL()},
+			})
+		}
+	}
+
+	for _, call := range msg.ToolCalls {
+		args, err := json.Marshal(call.Args)
+		if err != nil {
+			args = []byte("{}")
+		}
+		converted.ToolCalls = append(converted.ToolCalls, ToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      call.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+
+	if msg.Role == "tool" {
+		converted.Name = msg.Name
+		converted.ToolCallID = msg.ToolCallID
+		if converted.Content == "" && msg.ToolResult != nil {
+			if result, err := json.Marshal(msg.ToolResult); err == nil {
+				converted.Content = string(result)
+			}
+		}
+	}
+
+	return converted
+}
@@ -0,0 +1,133 @@
+package router
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// Fallback is a mock.ChatModel calling its models in order until one
+// succeeds, e.g. a small local model backed by a larger or remote one.
+// Failures of a stream after its first chunk are not retried.
+type Fallback struct {
+	models []mock.ChatModel
+}
+
+// NewFallback creates a fallback chain of models, the preferred one first
+func NewFallback(models ...mock.ChatModel) *Fallback {
+	return &Fallback{models: models}
+}
+
+// Models returns the models of the chain in order
+func (f *Fallback) Models() []mock.ChatModel {
+	return f.models
+}
+
+// ModelName returns the name of the preferred model, if it reports one
+func (f *Fallback) ModelName() string {
+	if len(f.models) == 0 {
+		return ""
+	}
+	return modelName(f.models[0])
+}
+
+// Generate returns the reply of the first model that succeeds
+func (f *Fallback) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	var failures []string
+	for i, model := range f.models {
+		reply, err := model.Generate(ctx, messages)
+		if err == nil {
+			return reply, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		failures = append(failures, f.failure(i, err))
+	}
+	return nil, f.exhausted(failures)
+}
+
+// Stream returns the stream of the first model that starts one
+func (f *Fallback) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	var failures []string
+	for i, model := range f.models {
+		s, err := stream(ctx, model, messages)
+		if err == nil {
+			return s, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		failures = append(failures, f.failure(i, err))
+	}
+	return nil, f.exhausted(failures)
+}
+
+// BindTools binds tools to every model of the chain
+func (f *Fallback) BindTools(tools []mock.Tool) error {
+	for _, model := range f.models {
+		if err := model.BindTools(tools); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failure logs and describes the failed call of the i-th model
+func (f *Fallback) failure(i int, err error) string {
+	if i+1 < len(f.models) {
+		log.Printf("%s failed, falling back to %s: %v", f.name(i), f.name(i+1), err)
+	}
+	return fmt.Sprintf("%s: %v", f.name(i), err)
+}
+
+// name returns the name of the i-th model, or its position
+func (f *Fallback) name(i int) string {
+	if name := modelName(f.models[i]); name != "" {
+		return name
+	}
+	return fmt.Sprintf("model %d", i+1)
+}
+
+// exhausted returns the error of a call all models failed
+func (f *Fallback) exhausted(failures []string) error {
+	if len(failures) == 0 {
+		return errors.New("fallback chain has no models")
+	}
+	return fmt.Errorf("all %d models failed: %s", len(failures), strings.Join(failures, "; "))
+}
+
+// stream streams from model, or returns its whole reply as a single chunk
+// when it does not stream
+func stream(ctx context.Context, model mock.ChatModel, messages []*mock.Message) (mock.MessageStream, error) {
+	if streamer, ok := model.(mock.StreamingChatModel); ok {
+		return streamer.Stream(ctx, messages)
+	}
+	reply, err := model.Generate(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return &singleStream{msg: reply}, nil
+}
+
+// singleStream returns a complete reply as a single chunk
+type singleStream struct {
+	msg  *mock.Message
+	done bool
+}
+
+func (s *singleStream) Recv() (*mock.Message, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	s.done = true
+	return s.msg, nil
+}
+
+func (s *singleStream) Close() error {
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package router lets every agent use its own chat model, so that simple
+// tasks such as weather summaries run on a small model while the itinerary
+// is written by a large one.
+package router
+
+import (
+	"context"
+	"deepllm/components/mock"
+	"sort"
+	"sync"
+)
+
+// Agent names used by the multi-agent system
+const (
+	AgentWeather       = "weather"
+	AgentDining        = "dining"
+	AgentAccommodation = "accommodation"
+	AgentPlanner       = "planner"
+	AgentCoordinator   = "coordinator"
+)
+
+// Router is a mock.ChatModel that maps agent names to chat models. Agents
+// created with agent.NewBaseAgent resolve their model with ModelFor; calls
+// made on the router itself go to the default model.
+type Router struct {
+	defaultModel mock.ChatModel
+
+	mu     sync.RWMutex
+	routes map[string]mock.ChatModel
+}
+
+// New creates a router sending every agent to defaultModel until routes are
+// added
+func New(defaultModel mock.ChatModel) *Router {
+	return &Router{
+		defaultModel: defaultModel,
+		routes:       map[string]mock.ChatModel{},
+	}
+}
+
+// Route sends the calls of the named agent to models. With several models,
+// the later ones are fallbacks tried in order when a call fails.
+func (r *Router) Route(agent string, models ...mock.ChatModel) *Router {
+	if len(models) == 0 {
+		return r
+	}
+	model := models[0]
+	if len(models) > 1 {
+		model = NewFallback(models...)
+	}
+
+	r.mu.Lock()
+	r.routes[agent] = model
+	r.mu.Unlock()
+	return r
+}
+
+// ModelFor returns the model of the named agent, or the default model
+func (r *Router) ModelFor(agent string) mock.ChatModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if model, ok := r.routes[agent]; ok {
+		return model
+	}
+	return r.defaultModel
+}
+
+// ModelNames returns the names of the model of the named agent and of its
+// fallbacks, in order
+func (r *Router) ModelNames(agent string) []string {
+	model := r.ModelFor(agent)
+	if fallback, ok := model.(*Fallback); ok {
+		names := make([]string, len(fallback.models))
+		for i, m := range fallback.models {
+			names[i] = modelName(m)
+		}
+		return names
+	}
+	return []string{modelName(model)}
+}
+
+// Default returns the model of agents without a route
+func (r *Router) Default() mock.ChatModel {
+	return r.defaultModel
+}
+
+// Routes returns the names of the agents with a route, sorted
+func (r *Router) Routes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.routes))
+	for name := range r.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Models returns every distinct model the router may call, the default model
+// first and the models of fallback chains individually
+func (r *Router) Models() []mock.ChatModel {
+	var models []mock.ChatModel
+	seen := map[mock.ChatModel]bool{}
+	add := func(model mock.ChatModel) {
+		if fallback, ok := model.(*Fallback); ok {
+			for _, m := range fallback.models {
+				if !seen[m] {
+					seen[m] = true
+					models = append(models, m)
+				}
+			}
+			return
+		}
+		if !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+
+	add(r.defaultModel)
+	for _, name := range r.Routes() {
+		add(r.ModelFor(name))
+	}
+	return models
+}
+
+// Generate calls the default model
+func (r *Router) Generate(ctx context.Context, messages []*mock.Message) (*mock.Message, error) {
+	return r.defaultModel.Generate(ctx, messages)
+}
+
+// Stream streams from the default model
+func (r *Router) Stream(ctx context.Context, messages []*mock.Message) (mock.MessageStream, error) {
+	return stream(ctx, r.defaultModel, messages)
+}
+
+// BindTools binds tools to the default model
+func (r *Router) BindTools(tools []mock.Tool) error {
+	return r.defaultModel.BindTools(tools)
+}
+
+// ModelName returns the name of the default model, if it reports one
+func (r *Router) ModelName() string {
+	return modelName(r.defaultModel)
+}
+
+// modelName returns the name of a model, if it reports one
+func modelName(model mock.ChatModel) string {
+	if named, ok := model.(interface{ ModelName() string }); ok {
+		return named.ModelName()
+	}
+	return ""
+}
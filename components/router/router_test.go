@@ -0,0 +1,193 @@
+package router
+
+import (
+	"context"
+	"deepllm/components/agent"
+	"deepllm/components/mock"
+	"deepllm/internal/data"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// namedModel is a fake model reporting a model name
+type namedModel struct {
+	*mock.FakeChatModel
+	name string
+}
+
+func (m *namedModel) ModelName() string {
+	return m.name
+}
+
+// newNamedModel returns a fake model named name answering with its name
+func newNamedModel(name string) *namedModel {
+	fake := mock.NewFakeChatModel()
+	fake.On().ReplyText(name)
+	return &namedModel{FakeChatModel: fake, name: name}
+}
+
+func TestRouter(t *testing.T) {
+	large, small, fallback := newNamedModel("qwen2.5:14b"), newNamedModel("qwen2.5:3b"), newNamedModel("qwen2.5:7b")
+	r := New(large).
+		Route(AgentWeather, small, fallback).
+		Route(AgentDining, small).
+		Route(AgentPlanner)
+
+	tests := []struct {
+		agent string
+		model mock.ChatModel
+		names []string
+	}{
+		{AgentDining, small, []string{"qwen2.5:3b"}},
+		{AgentPlanner, large, []string{"qwen2.5:14b"}},
+		{AgentCoordinator, large, []string{"qwen2.5:14b"}},
+		{"guide", large, []string{"qwen2.5:14b"}},
+	}
+	for _, tt := range tests {
+		if model := r.ModelFor(tt.agent); model != tt.model {
+			t.Errorf("ModelFor(%s) = %v, want %v", tt.agent, modelName(model), modelName(tt.model))
+		}
+		if names := r.ModelNames(tt.agent); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("ModelNames(%s) = %v, want %v", tt.agent, names, tt.names)
+		}
+	}
+	if chain, ok := r.ModelFor(AgentWeather).(*Fallback); !ok || !reflect.DeepEqual(chain.Models(), []mock.ChatModel{small, fallback}) {
+		t.Errorf("weather model = %#v, want the fallback chain", r.ModelFor(AgentWeather))
+	}
+	if names := r.ModelNames(AgentWeather); !reflect.DeepEqual(names, []string{"qwen2.5:3b", "qwen2.5:7b"}) {
+		t.Errorf("weather model names = %v", names)
+	}
+
+	if routes := r.Routes(); !reflect.DeepEqual(routes, []string{AgentDining, AgentWeather}) {
+		t.Errorf("Routes = %v, want the routes with models", routes)
+	}
+	if models := r.Models(); !reflect.DeepEqual(models, []mock.ChatModel{large, small, fallback}) {
+		t.Errorf("Models = %d models, want each model once, the default first", len(models))
+	}
+
+	// Calls on the router itself go to the default model
+	if reply, err := r.Generate(context.Background(), []*mock.Message{{Role: "user", Content: "hi"}}); err != nil || reply.Content != "qwen2.5:14b" {
+		t.Errorf("Generate = %+v, %v", reply, err)
+	}
+	if r.ModelName() != "qwen2.5:14b" || r.Default() != large {
+		t.Errorf("default model = %s", r.ModelName())
+	}
+}
+
+// writeImage writes a PNG header as images/name in the data directory dir
+func writeImage(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "images", name), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoutedAgents(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeImage(t, dir, "west_lake_1.png")
+	dataQuery := data.NewDataQuery(data.NewDataLoader(dir))
+
+	// The weather agent needs tool calling, the guide describes images
+	text, tools, vision := newNamedModel("qwen2.5:14b"), newNamedModel("qwen2.5:3b"), newNamedModel("qwen2.5vl:7b")
+	tools.FakeChatModel = mock.NewFakeChatModel()
+	tools.On().WhenRole("user").ReplyToolCalls(mock.ToolCall{Name: "get_weather", Args: map[string]interface{}{"city": "杭州"}})
+	tools.On().WhenToolResult("get_weather").ReplyText("杭州明天晴")
+	r := New(text).Route(AgentWeather, tools).Route("guide", vision)
+
+	weatherTool := mock.NewMockTool("get_weather", "查询天气", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"condition": "晴"}, nil
+	})
+	weather := agent.NewBaseAgent(AgentWeather, r, []mock.Tool{weatherTool}, dataQuery)
+	react, err := weather.CreateReactAgent(ctx, "你是天气助手")
+	if err != nil {
+		t.Fatalf("CreateReactAgent: %v", err)
+	}
+	answer, err := react.Invoke(ctx, []*mock.Message{{Role: "user", Content: "明天杭州天气如何？"}})
+	if err != nil || answer.Content != "杭州明天晴" {
+		t.Fatalf("Invoke = %+v, %v", answer, err)
+	}
+	if calls := tools.Calls(); len(calls) != 2 || !reflect.DeepEqual(calls[0].Tools, []string{"get_weather"}) {
+		t.Errorf("tool model calls = %+v, want 2 offering get_weather", calls)
+	}
+
+	guide := agent.NewBaseAgent("guide", r, nil, dataQuery)
+	reply, err := guide.DescribeImages(ctx, "描述图片", []string{"west_lake_1.png"})
+	if err != nil || reply.Content != "qwen2.5vl:7b" {
+		t.Fatalf("DescribeImages = %+v, %v", reply, err)
+	}
+	if calls := vision.Calls(); len(calls) != 1 || len(calls[0].LastMessage().Images) != 1 || len(calls[0].Tools) != 0 {
+		t.Errorf("vision model calls = %+v, want one with the image and no tools", calls)
+	}
+
+	// Neither call reached the default model, which has no tools bound
+	if text.CallCount() != 0 {
+		t.Errorf("default model called %d times", text.CallCount())
+	}
+	if _, err := r.Generate(ctx, []*mock.Message{{Role: "user", Content: "hi"}}); err != nil || len(text.Calls()[0].Tools) != 0 {
+		t.Errorf("default model offered tools %v, %v", text.Calls()[0].Tools, err)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	messages := []*mock.Message{{Role: "user", Content: "推荐景点"}}
+
+	failing := newNamedModel("qwen2.5:3b")
+	failing.FakeChatModel = mock.NewFakeChatModel()
+	failing.On().ReplyError(errors.New("connection refused"))
+	backup := newNamedModel("qwen2.5:7b")
+
+	chain := NewFallback(failing, backup)
+	if chain.ModelName() != "qwen2.5:3b" {
+		t.Errorf("ModelName = %q, want the preferred model", chain.ModelName())
+	}
+	reply, err := chain.Generate(ctx, messages)
+	if err != nil || reply.Content != "qwen2.5:7b" {
+		t.Errorf("Generate = %+v, %v, want the backup reply", reply, err)
+	}
+	stream, err := chain.Stream(ctx, messages)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if reply, err := mock.CollectStream(stream); err != nil || reply.Content != "qwen2.5:7b" {
+		t.Errorf("streamed %+v, %v, want the backup reply", reply, err)
+	}
+	if failing.CallCount() != 2 || backup.CallCount() != 2 {
+		t.Errorf("calls = %d and %d, want 2 each", failing.CallCount(), backup.CallCount())
+	}
+
+	// Tools are bound to every model of the chain
+	tool := mock.NewMockTool("get_weather", "查询天气", nil)
+	if err := chain.BindTools([]mock.Tool{tool}); err != nil {
+		t.Fatalf("BindTools: %v", err)
+	}
+	chain.Generate(ctx, messages)
+	if calls := backup.Calls(); len(calls[2].Tools) != 1 || len(failing.Calls()[2].Tools) != 1 {
+		t.Errorf("tools offered = %v and %v", failing.Calls()[2].Tools, calls[2].Tools)
+	}
+
+	// When every model fails, the error names each failure
+	_, err = NewFallback(failing, failing).Generate(ctx, messages)
+	if err == nil || !strings.Contains(err.Error(), "all 2 models failed") || strings.Count(err.Error(), "qwen2.5:3b: connection refused") != 2 {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := NewFallback().Generate(ctx, messages); err == nil {
+		t.Error("an empty chain did not fail")
+	}
+
+	// A cancelled call is not retried on the next model
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	before := backup.CallCount()
+	if _, err := NewFallback(failing, backup).Generate(cancelled, messages); err == nil || backup.CallCount() != before {
+		t.Errorf("cancelled call = %v with %d backup calls, want an error without fallback", err, backup.CallCount()-before)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"deepllm/components/mock"
 	"deepllm/internal/data"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
@@ -23,6 +24,7 @@ func CreateTourismTools(dataQuery *data.DataQuery) []mock.Tool {
 type BaseTool struct {
 	name        string
 	description string
+	parameters  *mock.Schema
 	handler     func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
 }
 
@@ -34,35 +36,35 @@ func (t *BaseTool) Description() string {
 	return t.description
 }
 
+func (t *BaseTool) Parameters() *mock.Schema {
+	return t.parameters
+}
+
+// Execute 执行工具。参数校验失败时不返回错误，而是把结构化的校验结果交给模型，
+// 以便模型修正参数后重试
 func (t *BaseTool) Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
-	return t.handler(ctx, args)
+	result, err := t.handler(ctx, args)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return map[string]interface{}{
+			"error":             "invalid_arguments",
+			"validation_errors": validationErr.Errors,
+		}, nil
+	}
+	return result, err
 }
 
 // NewSearchAttractionsTool 创建景点搜索工具
 func NewSearchAttractionsTool(t *TourismTools) mock.Tool {
 	return &BaseTool{
 		name:        "search_attractions",
-		description: "搜索景点信息，支持按位置、类别、价格等条件筛选",
+		description: "搜索景点信息，支持按位置、类别、行政区、价格等条件筛选，按评分排序并支持分页，指定位置时返回与该位置的距离",
+		parameters:  schemaFor(&AttractionQueryParams{}),
 		handler: func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
 			// 解析参数
 			params := &AttractionQueryParams{}
-			if loc, ok := args["location"].(map[string]interface{}); ok {
-				params.Location = &data.Location{
-					Name:      loc["name"].(string),
-					Latitude:  loc["latitude"].(float64),
-					Longitude: loc["longitude"].(float64),
-				}
-			}
-			if radius, ok := args["radius"].(float64); ok {
-				params.Radius = radius
-			}
-			if categories, ok := args["categories"].([]interface{}); ok {
-				for _, cat := range categories {
-					params.Categories = append(params.Categories, cat.(string))
-				}
-			}
-			if maxPrice, ok := args["max_price"].(float64); ok {
-				params.MaxPrice = maxPrice
+			if err := decodeArgs(args, params); err != nil {
+				return nil, err
 			}
 
 			// 执行搜索
@@ -72,13 +74,14 @@ func NewSearchAttractionsTool(t *TourismTools) mock.Tool {
 			}
 
 			// 解析JSON结果
-			var attractions []data.Attraction
-			if err := json.Unmarshal([]byte(result), &attractions); err != nil {
+			var page AttractionSearchResult
+			if err := json.Unmarshal([]byte(result), &page); err != nil {
 				return nil, fmt.Errorf("解析结果失败: %v", err)
 			}
 
 			return map[string]interface{}{
-				"attractions": attractions,
+				"total":       page.Total,
+				"attractions": page.Attractions,
 			}, nil
 		},
 	}
@@ -88,27 +91,13 @@ func NewSearchAttractionsTool(t *TourismTools) mock.Tool {
 func NewSearchRestaurantsTool(t *TourismTools) mock.Tool {
 	return &BaseTool{
 		name:        "search_restaurants",
-		description: "搜索餐厅信息，支持按位置、菜系、价格区间等条件筛选",
+		description: "搜索餐厅信息，支持按位置、菜系、行政区、价格区间等条件筛选，支持分页，指定位置时由近及远返回并附带距离",
+		parameters:  schemaFor(&RestaurantQueryParams{}),
 		handler: func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
 			// 解析参数
 			params := &RestaurantQueryParams{}
-			if loc, ok := args["location"].(map[string]interface{}); ok {
-				params.Location = &data.Location{
-					Name:      loc["name"].(string),
-					Latitude:  loc["latitude"].(float64),
-					Longitude: loc["longitude"].(float64),
-				}
-			}
-			if radius, ok := args["radius"].(float64); ok {
-				params.Radius = radius
-			}
-			if cuisines, ok := args["cuisines"].([]interface{}); ok {
-				for _, cuisine := range cuisines {
-					params.Cuisines = append(params.Cuisines, cuisine.(string))
-				}
-			}
-			if priceRange, ok := args["price_range"].(string); ok {
-				params.PriceRange = priceRange
+			if err := decodeArgs(args, params); err != nil {
+				return nil, err
 			}
 
 			// 执行搜索
@@ -118,13 +107,14 @@ func NewSearchRestaurantsTool(t *TourismTools) mock.Tool {
 			}
 
 			// 解析JSON结果
-			var restaurants []data.Restaurant
-			if err := json.Unmarshal([]byte(result), &restaurants); err != nil {
+			var page RestaurantSearchResult
+			if err := json.Unmarshal([]byte(result), &page); err != nil {
 				return nil, fmt.Errorf("解析结果失败: %v", err)
 			}
 
 			return map[string]interface{}{
-				"restaurants": restaurants,
+				"total":       page.Total,
+				"restaurants": page.Restaurants,
 			}, nil
 		},
 	}
@@ -134,30 +124,13 @@ func NewSearchRestaurantsTool(t *TourismTools) mock.Tool {
 func NewSearchHotelsTool(t *TourismTools) mock.Tool {
 	return &BaseTool{
 		name:        "search_hotels",
-		description: "搜索酒店信息，支持按位置、星级、价格、设施等条件筛选",
+		description: "搜索酒店信息，支持按位置、星级、行政区、价格、设施等条件筛选，支持分页，指定位置时由近及远返回并附带距离",
+		parameters:  schemaFor(&HotelQueryParams{}),
 		handler: func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
 			// 解析参数
 			params := &HotelQueryParams{}
-			if loc, ok := args["location"].(map[string]interface{}); ok {
-				params.Location = &data.Location{
-					Name:      loc["name"].(string),
-					Latitude:  loc["latitude"].(float64),
-					Longitude: loc["longitude"].(float64),
-				}
-			}
-			if radius, ok := args["radius"].(float64); ok {
-				params.Radius = radius
-			}
-			if minStars, ok := args["min_stars"].(float64); ok {
-				params.MinStars = int(minStars)
-			}
-			if maxPrice, ok := args["max_price"].(float64); ok {
-				params.MaxPrice = maxPrice
-			}
-			if amenities, ok := args["required_amenities"].([]interface{}); ok {
-				for _, amen := range amenities {
-					params.RequiredAmens = append(params.RequiredAmens, amen.(string))
-				}
+			if err := decodeArgs(args, params); err != nil {
+				return nil, err
 			}
 
 			// 执行搜索
@@ -167,13 +140,14 @@ func NewSearchHotelsTool(t *TourismTools) mock.Tool {
 			}
 
 			// 解析JSON结果
-			var hotels []data.Hotel
-			if err := json.Unmarshal([]byte(result), &hotels); err != nil {
+			var page HotelSearchResult
+			if err := json.Unmarshal([]byte(result), &page); err != nil {
 				return nil, fmt.Errorf("解析结果失败: %v", err)
 			}
 
 			return map[string]interface{}{
-				"hotels": hotels,
+				"total":  page.Total,
+				"hotels": page.Hotels,
 			}, nil
 		},
 	}
@@ -184,18 +158,12 @@ func NewGetWeatherTool(t *TourismTools) mock.Tool {
 	return &BaseTool{
 		name:        "get_weather",
 		description: "获取指定日期和位置的天气信息",
+		parameters:  schemaFor(&WeatherQueryParams{}),
 		handler: func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
 			// 解析参数
 			params := &WeatherQueryParams{}
-			if loc, ok := args["location"].(map[string]interface{}); ok {
-				params.Location = &data.Location{
-					Name:      loc["name"].(string),
-					Latitude:  loc["latitude"].(float64),
-					Longitude: loc["longitude"].(float64),
-				}
-			}
-			if date, ok := args["date"].(string); ok {
-				params.Date = date
+			if err := decodeArgs(args, params); err != nil {
+				return nil, err
 			}
 
 			// 执行查询
@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"deepllm/components/mock"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldError 描述单个参数的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 参数校验失败时返回的错误，会以结构化形式反馈给模型
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Sprintf("参数校验失败: %s", strings.Join(msgs, "; "))
+}
+
+// schemaCache 缓存各参数结构体对应的 JSON Schema
+var schemaCache sync.Map
+
+// schemaFor 返回参数结构体的 JSON Schema
+func schemaFor(target interface{}) *mock.Schema {
+	t := reflect.TypeOf(target)
+	if cached, ok := schemaCache.Load(t); ok {
+		return cached.(*mock.Schema)
+	}
+	schema := mock.SchemaOf(target)
+	schemaCache.Store(t, schema)
+	return schema
+}
+
+// decodeArgs 按参数结构体的 Schema 校验并转换模型传入的参数，然后解码到 target。
+// 会把字符串形式的数字转换为数字、把单个值包装为数组、补齐默认值；
+// 所有不合法的字段会汇总为 *ValidationError 返回。
+func decodeArgs(args map[string]interface{}, target interface{}) error {
+	schema := schemaFor(target)
+
+	d := &argDecoder{}
+	coerced := d.coerceObject(schema, args, "")
+	if len(d.errors) > 0 {
+		return &ValidationError{Errors: d.errors}
+	}
+
+	raw, err := json.Marshal(coerced)
+	if err != nil {
+		return fmt.Errorf("序列化参数失败: %v", err)
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return &ValidationError{Errors: []FieldError{{Field: "$", Message: err.Error()}}}
+	}
+	return nil
+}
+
+// argDecoder 收集转换过程中的校验错误
+type argDecoder struct {
+	errors []FieldError
+}
+
+func (d *argDecoder) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "$"
+	}
+	d.errors = append(d.errors, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// coerce 将 value 转换为 schema 描述的类型
+func (d *argDecoder) coerce(schema *mock.Schema, value interface{}, path string) (interface{}, bool) {
+	var result interface{}
+	ok := true
+
+	switch schema.Type {
+	case "object":
+		obj, isObj := value.(map[string]interface{})
+		if s, isStr := value.(string); isStr && json.Unmarshal([]byte(s), &obj) == nil {
+			isObj = true
+		}
+		if !isObj {
+			d.fail(path, "应为对象，实际为 %s", describeValue(value))
+			return nil, false
+		}
+		before := len(d.errors)
+		result = d.coerceObject(schema, obj, path)
+		ok = len(d.errors) == before
+	case "array":
+		items, isArr := value.([]interface{})
+		if !isArr {
+			// 模型常把单个值直接传入，自动包装为数组
+			items = []interface{}{value}
+		}
+		list := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			itemSchema := schema.Items
+			if itemSchema == nil {
+				itemSchema = &mock.Schema{}
+			}
+			if v, itemOK := d.coerce(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); itemOK {
+				list = append(list, v)
+			} else {
+				ok = false
+			}
+		}
+		result = list
+	case "number":
+		n, err := toFloat(value)
+		if err != nil {
+			d.fail(path, "应为数字，实际为 %s", describeValue(value))
+			return nil, false
+		}
+		result = n
+	case "integer":
+		n, err := toFloat(value)
+		if err != nil || n != math.Trunc(n) {
+			d.fail(path, "应为整数，实际为 %s", describeValue(value))
+			return nil, false
+		}
+		result = int64(n)
+	case "string":
+		switch v := value.(type) {
+		case string:
+			result = v
+		case float64:
+			result = strconv.FormatFloat(v, 'f', -1, 64)
+		case json.Number:
+			result = v.String()
+		default:
+			d.fail(path, "应为字符串，实际为 %s", describeValue(value))
+			return nil, false
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			result = v
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				d.fail(path, "应为布尔值，实际为 %s", describeValue(value))
+				return nil, false
+			}
+			result = b
+		default:
+			d.fail(path, "应为布尔值，实际为 %s", describeValue(value))
+			return nil, false
+		}
+	default:
+		result = value
+	}
+
+	if ok && !d.checkConstraints(schema, result, path) {
+		ok = false
+	}
+	return result, ok
+}
+
+// coerceObject 转换对象的各个属性，补齐默认值并检查必填字段
+func (d *argDecoder) coerceObject(schema *mock.Schema, obj map[string]interface{}, path string) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema.Properties))
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := schema.Properties[name]
+		fieldPath := joinPath(path, name)
+		value, present := obj[name]
+		if !present || value == nil {
+			if prop.Default != nil {
+				result[name] = prop.Default
+			}
+			continue
+		}
+		if v, ok := d.coerce(prop, value, fieldPath); ok {
+			result[name] = v
+		}
+	}
+
+	for _, name := range schema.Required {
+		if _, present := result[name]; present {
+			continue
+		}
+		if value, given := obj[name]; !given || value == nil {
+			d.fail(joinPath(path, name), "缺少必填参数")
+		}
+	}
+
+	return result
+}
+
+// checkConstraints 检查枚举值与取值范围
+func (d *argDecoder) checkConstraints(schema *mock.Schema, value interface{}, path string) bool {
+	if len(schema.Enum) > 0 {
+		matched := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			d.fail(path, "取值 %v 不在允许范围内 %v", value, schema.Enum)
+			return false
+		}
+	}
+
+	if n, err := toFloat(value); err == nil && (schema.Type == "number" || schema.Type == "integer") {
+		if schema.Minimum != nil && n < *schema.Minimum {
+			d.fail(path, "不能小于 %v", *schema.Minimum)
+			return false
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			d.fail(path, "不能大于 %v", *schema.Maximum)
+			return false
+		}
+	}
+	return true
+}
+
+// toFloat 将数字或数字字符串转换为 float64
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("not a number: %v", value)
+	}
+}
+
+// describeValue 返回值的简短描述，用于错误信息
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("字符串 %q", value)
+	case bool:
+		return fmt.Sprintf("布尔值 %v", value)
+	case float64, json.Number:
+		return fmt.Sprintf("数字 %v", value)
+	case []interface{}:
+		return "数组"
+	case map[string]interface{}:
+		return "对象"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinPath 拼接参数路径
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
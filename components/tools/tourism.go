@@ -11,32 +11,80 @@ import (
 // 景点查询参数
 type AttractionQueryParams struct {
 	Location   *data.Location `json:"location,omitempty" jsonschema:"description=查询位置"`
-	Radius     float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）"`
+	Radius     float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）,default=5"`
 	Categories []string       `json:"categories,omitempty" jsonschema:"description=景点类别，如：自然风光、人文景观等"`
-	MaxPrice   float64        `json:"max_price,omitempty" jsonschema:"description=最高门票价格"`
+	District   string         `json:"district,omitempty" jsonschema:"description=所在行政区，如：西湖区、上城区"`
+	MaxPrice   *float64       `json:"max_price,omitempty" jsonschema:"description=最高门票价格，0 表示只查免费景点，不填不限,minimum=0"`
+	Limit      int            `json:"limit,omitempty" jsonschema:"description=最多返回的结果数量，不填返回全部,minimum=1"`
+	Offset     int            `json:"offset,omitempty" jsonschema:"description=跳过的结果数量，用于翻页,minimum=0"`
 }
 
 // 餐厅查询参数
 type RestaurantQueryParams struct {
 	Location   *data.Location `json:"location,omitempty" jsonschema:"description=查询位置"`
-	Radius     float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）"`
+	Radius     float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）,default=5"`
 	Cuisines   []string       `json:"cuisines,omitempty" jsonschema:"description=菜系类型，如：杭帮菜、海鲜等"`
-	PriceRange string         `json:"price_range,omitempty" jsonschema:"description=价格区间，$-$$$$"`
+	PriceRange string         `json:"price_range,omitempty" jsonschema:"description=价格区间，$-$$$$,enum=$,enum=$$,enum=$$$,enum=$$$$"`
+	District   string         `json:"district,omitempty" jsonschema:"description=所在行政区，如：西湖区、上城区"`
+	Limit      int            `json:"limit,omitempty" jsonschema:"description=最多返回的结果数量，不填返回全部,minimum=1"`
+	Offset     int            `json:"offset,omitempty" jsonschema:"description=跳过的结果数量，用于翻页,minimum=0"`
 }
 
 // 酒店查询参数
 type HotelQueryParams struct {
 	Location      *data.Location `json:"location,omitempty" jsonschema:"description=查询位置"`
-	Radius        float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）"`
-	MinStars      int            `json:"min_stars,omitempty" jsonschema:"description=最低星级"`
-	MaxPrice      float64        `json:"max_price,omitempty" jsonschema:"description=最高房价/晚"`
+	Radius        float64        `json:"radius,omitempty" jsonschema:"description=搜索半径（公里）,default=5"`
+	MinStars      int            `json:"min_stars,omitempty" jsonschema:"description=最低星级,minimum=1,maximum=5"`
+	MaxPrice      *float64       `json:"max_price,omitempty" jsonschema:"description=最高房价/晚，不填不限,minimum=0"`
 	RequiredAmens []string       `json:"required_amenities,omitempty" jsonschema:"description=必需设施，如：游泳池、健身房等"`
+	District      string         `json:"district,omitempty" jsonschema:"description=所在行政区，如：西湖区、上城区"`
+	Limit         int            `json:"limit,omitempty" jsonschema:"description=最多返回的结果数量，不填返回全部,minimum=1"`
+	Offset        int            `json:"offset,omitempty" jsonschema:"description=跳过的结果数量，用于翻页,minimum=0"`
 }
 
 // 天气查询参数
 type WeatherQueryParams struct {
-	Location *data.Location `json:"location,omitempty" jsonschema:"description=查询位置"`
-	Date     string         `json:"date,omitempty" jsonschema:"description=查询日期，格式：2024-02-18"`
+	Location *data.Location `json:"location,omitempty" jsonschema:"description=查询位置,required"`
+	Date     string         `json:"date,omitempty" jsonschema:"description=查询日期，格式：2024-02-18,format=date,required"`
+}
+
+// AttractionResult 景点搜索结果，指定位置时附带与该位置的距离
+type AttractionResult struct {
+	data.Attraction
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	Distance   string  `json:"distance,omitempty"` // 如：距西湖500m
+}
+
+// RestaurantResult 餐厅搜索结果，指定位置时附带与该位置的距离
+type RestaurantResult struct {
+	data.Restaurant
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	Distance   string  `json:"distance,omitempty"`
+}
+
+// HotelResult 酒店搜索结果，指定位置时附带与该位置的距离
+type HotelResult struct {
+	data.Hotel
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	Distance   string  `json:"distance,omitempty"`
+}
+
+// AttractionSearchResult 景点搜索的一页结果
+type AttractionSearchResult struct {
+	Total       int                `json:"total"` // 符合条件的结果总数
+	Attractions []AttractionResult `json:"attractions"`
+}
+
+// RestaurantSearchResult 餐厅搜索的一页结果
+type RestaurantSearchResult struct {
+	Total       int                `json:"total"`
+	Restaurants []RestaurantResult `json:"restaurants"`
+}
+
+// HotelSearchResult 酒店搜索的一页结果
+type HotelSearchResult struct {
+	Total  int           `json:"total"`
+	Hotels []HotelResult `json:"hotels"`
 }
 
 // TourismTools 提供旅游相关的工具集
@@ -51,130 +99,127 @@ func NewTourismTools(dataQuery *data.DataQuery) *TourismTools {
 	}
 }
 
-// SearchAttractions 搜索景点
+// SearchAttractions 搜索景点，按评分排序
 func (t *TourismTools) SearchAttractions(ctx context.Context, params *AttractionQueryParams) (string, error) {
-	attractions, err := t.dataQuery.Loader.LoadAttractions()
+	query, err := t.dataQuery.Attractions()
 	if err != nil {
 		return "", fmt.Errorf("加载景点数据失败: %v", err)
 	}
 
-	// 按位置筛选
-	var filtered []data.Attraction
+	// 按位置、类别、行政区和价格筛选
 	if params.Location != nil {
-		filtered = data.FindNearbyAttractions(attractions, *params.Location, params.Radius)
-	} else {
-		filtered = attractions
+		query.Near(*params.Location, params.Radius)
 	}
-
-	// 按类别筛选
-	if len(params.Categories) > 0 {
-		filtered = t.dataQuery.FilterAttractionsByPreferences(filtered, params.Categories)
+	if params.District != "" {
+		query.District(params.District)
 	}
-
-	// 按价格筛选
-	if params.MaxPrice > 0 {
-		filtered = t.dataQuery.FilterByBudget(filtered, params.MaxPrice)
+	if params.MaxPrice != nil {
+		query.MaxPrice(*params.MaxPrice)
 	}
+	fields := query.Fields()
+	page := query.
+		Where(data.Or(fields.HasCategory(params.Categories...), fields.HasTag(params.Categories...))).
+		SortBy(data.Desc(data.SortByRating), data.Asc(data.SortByDistance)).
+		Offset(params.Offset).
+		Limit(params.Limit).
+		Run()
 
-	// 按评分排序
-	sorted := t.dataQuery.SortByRating(filtered)
-
-	// 转换为JSON
-	result, err := json.Marshal(sorted)
-	if err != nil {
-		return "", fmt.Errorf("序列化结果失败: %v", err)
+	result := AttractionSearchResult{Total: page.Total, Attractions: make([]AttractionResult, len(page.Hits))}
+	for i, hit := range page.Hits {
+		result.Attractions[i] = AttractionResult{Attraction: hit.Item}
+		result.Attractions[i].DistanceKm, result.Attractions[i].Distance = describeDistance(params.Location, hit.Distance)
 	}
-
-	return string(result), nil
+	return marshalResult(result)
 }
 
-// SearchRestaurants 搜索餐厅
+// SearchRestaurants 搜索餐厅，指定位置时由近及远排序
 func (t *TourismTools) SearchRestaurants(ctx context.Context, params *RestaurantQueryParams) (string, error) {
-	restaurants, err := t.dataQuery.Loader.LoadRestaurants()
+	query, err := t.dataQuery.Restaurants()
 	if err != nil {
 		return "", fmt.Errorf("加载餐厅数据失败: %v", err)
 	}
 
-	// 按位置筛选
-	var filtered []data.Restaurant
+	// 按位置、菜系、行政区和价格区间筛选
 	if params.Location != nil {
-		filtered = data.FindNearbyRestaurants(restaurants, *params.Location, params.Radius)
-	} else {
-		filtered = restaurants
+		query.Near(*params.Location, params.Radius)
 	}
-
-	// 按菜系筛选
-	if len(params.Cuisines) > 0 {
-		filtered = t.dataQuery.FilterRestaurantsByPreferences(filtered, params.Cuisines)
+	if params.District != "" {
+		query.District(params.District)
 	}
-
-	// 转换为JSON
-	result, err := json.Marshal(filtered)
-	if err != nil {
-		return "", fmt.Errorf("序列化结果失败: %v", err)
+	if params.PriceRange != "" {
+		query.Where(func(r data.Restaurant) bool { return r.PriceRange == params.PriceRange })
 	}
+	page := query.
+		Category(params.Cuisines...).
+		Offset(params.Offset).
+		Limit(params.Limit).
+		Run()
 
-	return string(result), nil
+	result := RestaurantSearchResult{Total: page.Total, Restaurants: make([]RestaurantResult, len(page.Hits))}
+	for i, hit := range page.Hits {
+		result.Restaurants[i] = RestaurantResult{Restaurant: hit.Item}
+		result.Restaurants[i].DistanceKm, result.Restaurants[i].Distance = describeDistance(params.Location, hit.Distance)
+	}
+	return marshalResult(result)
 }
 
-// SearchHotels 搜索酒店
+// SearchHotels 搜索酒店，指定位置时由近及远排序
 func (t *TourismTools) SearchHotels(ctx context.Context, params *HotelQueryParams) (string, error) {
-	hotels, err := t.dataQuery.Loader.LoadHotels()
+	query, err := t.dataQuery.Hotels()
 	if err != nil {
 		return "", fmt.Errorf("加载酒店数据失败: %v", err)
 	}
 
-	// 按位置筛选
-	var filtered []data.Hotel
+	// 按位置、星级、行政区、价格和设施筛选
 	if params.Location != nil {
-		filtered = data.FindNearbyHotels(hotels, *params.Location, params.Radius)
-	} else {
-		filtered = hotels
-	}
-
-	// 按星级和价格筛选
-	var result []data.Hotel
-	for _, hotel := range filtered {
-		if params.MinStars > 0 && hotel.Stars < params.MinStars {
-			continue
-		}
-		if params.MaxPrice > 0 && hotel.PricePerNight > params.MaxPrice {
-			continue
-		}
-		if len(params.RequiredAmens) > 0 {
-			hasAllAmens := true
-			for _, required := range params.RequiredAmens {
-				found := false
-				for _, amen := range hotel.Amenities {
-					if amen == required {
-						found = true
-						break
-					}
-				}
-				if !found {
-					hasAllAmens = false
-					break
-				}
-			}
-			if !hasAllAmens {
-				continue
-			}
-		}
-		result = append(result, hotel)
+		query.Near(*params.Location, params.Radius)
+	}
+	if params.District != "" {
+		query.District(params.District)
+	}
+	if params.MinStars > 0 {
+		query.Where(func(h data.Hotel) bool { return h.Stars >= params.MinStars })
+	}
+	if params.MaxPrice != nil {
+		query.MaxPrice(*params.MaxPrice)
 	}
+	page := query.
+		Where(query.Fields().HasAllTags(params.RequiredAmens...)).
+		Offset(params.Offset).
+		Limit(params.Limit).
+		Run()
 
-	// 转换为JSON
-	jsonResult, err := json.Marshal(result)
+	result := HotelSearchResult{Total: page.Total, Hotels: make([]HotelResult, len(page.Hits))}
+	for i, hit := range page.Hits {
+		result.Hotels[i] = HotelResult{Hotel: hit.Item}
+		result.Hotels[i].DistanceKm, result.Hotels[i].Distance = describeDistance(params.Location, hit.Distance)
+	}
+	return marshalResult(result)
+}
+
+// describeDistance 返回结果与查询位置的距离及其描述，如"距西湖500m"；未指定位置时返回空值
+func describeDistance(from *data.Location, km float64) (float64, string) {
+	if from == nil {
+		return 0, ""
+	}
+	if from.Name == "" {
+		return km, data.FormatDistance(km)
+	}
+	return km, "距" + from.Name + data.FormatDistance(km)
+}
+
+// marshalResult 将搜索结果序列化为JSON
+func marshalResult(result interface{}) (string, error) {
+	raw, err := json.Marshal(result)
 	if err != nil {
 		return "", fmt.Errorf("序列化结果失败: %v", err)
 	}
-
-	return string(jsonResult), nil
+	return string(raw), nil
 }
 
 // GetWeather 获取天气信息
 func (t *TourismTools) GetWeather(ctx context.Context, params *WeatherQueryParams) (string, error) {
-	weatherData, err := t.dataQuery.Loader.LoadWeather()
+	weatherData, err := t.dataQuery.LoadWeather()
 	if err != nil {
 		return "", fmt.Errorf("加载天气数据失败: %v", err)
 	}
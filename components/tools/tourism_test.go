@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"deepllm/internal/data"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestTools 创建读取临时数据目录的旅游工具集
+func newTestTools(t *testing.T) *TourismTools {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"attractions.json": `[
+			{"id": "wl001", "name": "西湖", "price": 0, "rating": 4.8},
+			{"id": "lft001", "name": "灵隐寺", "price": 30, "rating": 4.7},
+			{"id": "xhs001", "name": "西溪国家湿地公园", "price": 80, "rating": 4.6}
+		]`,
+		"restaurants.json": `[]`,
+		"hotels.json": `[
+			{"id": "zh001", "name": "浙江大酒店", "price_per_night": 400},
+			{"id": "gh001", "name": "杭州凯悦酒店", "price_per_night": 1800}
+		]`,
+		"weather.json": `[]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewTourismTools(data.NewDataQuery(data.NewDataLoader(dir)))
+}
+
+func TestSearchMaxPrice(t *testing.T) {
+	tourism := newTestTools(t)
+	tests := []struct {
+		name string
+		tool string
+		args map[string]interface{}
+		want int
+	}{
+		{"attractions without limit", "search_attractions", map[string]interface{}{}, 3},
+		{"free attractions", "search_attractions", map[string]interface{}{"max_price": 0}, 1},
+		{"free attractions as text", "search_attractions", map[string]interface{}{"max_price": "0"}, 1},
+		{"attractions up to 30", "search_attractions", map[string]interface{}{"max_price": 30}, 2},
+		{"hotels without limit", "search_hotels", map[string]interface{}{}, 2},
+		{"hotels up to 500", "search_hotels", map[string]interface{}{"max_price": 500}, 1},
+	}
+	tools := map[string]interface {
+		Execute(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
+	}{
+		"search_attractions": NewSearchAttractionsTool(tourism),
+		"search_hotels":      NewSearchHotelsTool(tourism),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tools[tt.tool].Execute(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if result["total"] != tt.want {
+				t.Errorf("total = %v, want %d", result["total"], tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ toolchain go1.23.6
 require (
 	github.com/cloudwego/eino v0.3.10
 	github.com/cloudwego/eino-ext/components/model/ollama v0.0.0-20250214113135-17929da14fef
+	github.com/getkin/kin-openapi v0.118.0
 )
 
 require (
@@ -15,7 +16,6 @@ require (
 	github.com/cloudwego/base64x v0.1.4 // indirect
 	github.com/cloudwego/iasm v0.2.0 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
-	github.com/getkin/kin-openapi v0.118.0 // indirect
 	github.com/go-openapi/jsonpointer v0.19.5 // indirect
 	github.com/go-openapi/swag v0.19.5 // indirect
 	github.com/goph/emperror v0.17.2 // indirect
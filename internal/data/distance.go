@@ -30,35 +30,17 @@ func toRadians(degrees float64) float64 {
 	return degrees * math.Pi / 180
 }
 
-// FindNearbyAttractions finds attractions within the specified radius (in km) from a location
+// FindNearbyAttractions finds attractions within the specified radius (in km) from a location, nearest first
 func FindNearbyAttractions(attractions []Attraction, center Location, radius float64) []Attraction {
-	var nearby []Attraction
-	for _, attraction := range attractions {
-		if distance := CalculateDistance(center, attraction.Location); distance <= radius {
-			nearby = append(nearby, attraction)
-		}
-	}
-	return nearby
+	return Items(NewAttractionIndex(attractions).WithinRadius(center, radius))
 }
 
-// FindNearbyRestaurants finds restaurants within the specified radius (in km) from a location
+// FindNearbyRestaurants finds restaurants within the specified radius (in km) from a location, nearest first
 func FindNearbyRestaurants(restaurants []Restaurant, center Location, radius float64) []Restaurant {
-	var nearby []Restaurant
-	for _, restaurant := range restaurants {
-		if distance := CalculateDistance(center, restaurant.Location); distance <= radius {
-			nearby = append(nearby, restaurant)
-		}
-	}
-	return nearby
+	return Items(NewRestaurantIndex(restaurants).WithinRadius(center, radius))
 }
 
-// FindNearbyHotels finds hotels within the specified radius (in km) from a location
+// FindNearbyHotels finds hotels within the specified radius (in km) from a location, nearest first
 func FindNearbyHotels(hotels []Hotel, center Location, radius float64) []Hotel {
-	var nearby []Hotel
-	for _, hotel := range hotels {
-		if distance := CalculateDistance(center, hotel.Location); distance <= radius {
-			nearby = append(nearby, hotel)
-		}
-	}
-	return nearby
+	return Items(NewHotelIndex(hotels).WithinRadius(center, radius))
 }
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"errors"
+	"os"
+)
+
+// District is an administrative district of data/geographic/districts.json.
+// Attractions, restaurants and hotels of the rich datasets refer to it by ID.
+type District struct {
+	ID             string      `json:"id"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	Coordinates    Coordinates `json:"coordinates"`
+	AreaKm2        float64     `json:"area_km2"`
+	Transportation []string    `json:"transportation"`
+	Landmarks      []string    `json:"landmarks"`
+}
+
+// Location returns the center of the district
+func (d District) Location() Location {
+	return d.Coordinates.Location(d.Name)
+}
+
+// LoadDistricts loads data/geographic/districts.json
+func (d *DataLoader) LoadDistricts() ([]District, error) {
+	var file struct {
+		Districts []District `json:"districts"`
+	}
+	err := d.loadJSON("geographic/districts.json", &file)
+	return file.Districts, err
+}
+
+// DistrictsByID indexes districts by their ID
+func DistrictsByID(districts []District) map[string]District {
+	byID := make(map[string]District, len(districts))
+	for _, district := range districts {
+		byID[district.ID] = district
+	}
+	return byID
+}
+
+// districtsByID loads the districts indexed by ID; without the districts
+// file the index is empty
+func (d *DataLoader) districtsByID() (map[string]District, error) {
+	districts, err := d.LoadDistricts()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return DistrictsByID(districts), nil
+}
@@ -0,0 +1,326 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Embedder computes text embeddings, e.g. an Ollama embedding model
+type Embedder interface {
+	// Embed returns one embedding per input text, in input order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// POI kinds stored in an EmbeddingIndex
+const (
+	KindAttraction = "attraction"
+	KindRestaurant = "restaurant"
+	KindHotel      = "hotel"
+)
+
+// embedBatchSize is the number of texts sent to the embedder per call
+const embedBatchSize = 32
+
+// IndexEntry is the embedding of a point of interest
+type IndexEntry struct {
+	Kind   string    `json:"kind"`
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Hash   string    `json:"hash"` // hash of the embedded text
+	Vector []float32 `json:"vector"`
+}
+
+// EmbeddingIndex holds the embeddings of attraction, restaurant and hotel
+// descriptions and tags. Model identifies the embedder that produced them.
+type EmbeddingIndex struct {
+	Model   string       `json:"model"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+// IndexMatch is a search result of an EmbeddingIndex
+type IndexMatch struct {
+	Entry IndexEntry
+	Score float64 // cosine similarity
+}
+
+// document is a text to embed for a point of interest
+type document struct {
+	kind, id, name, text string
+}
+
+// LoadEmbeddingIndex reads an index saved with Save. A missing file yields
+// an empty index.
+func LoadEmbeddingIndex(path string) (*EmbeddingIndex, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &EmbeddingIndex{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedding index: %v", err)
+	}
+
+	var index EmbeddingIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("error unmarshaling embedding index: %v", err)
+	}
+	return &index, nil
+}
+
+// Save writes the index to path
+func (idx *EmbeddingIndex) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("error marshaling embedding index: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating index directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing embedding index: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Update makes the index cover exactly the given points of interest. Entries
+// whose text is unchanged keep their vectors, so only new and edited POIs
+// are embedded. The whole index is rebuilt when the embedder model changed.
+// It reports whether the index changed.
+func (idx *EmbeddingIndex) Update(ctx context.Context, embedder Embedder, attractions []Attraction, restaurants []Restaurant, hotels []Hotel) (bool, error) {
+	model := embedderName(embedder)
+	existing := map[string]IndexEntry{}
+	if idx.Model == model {
+		for _, e := range idx.Entries {
+			existing[e.Kind+"/"+e.ID] = e
+		}
+	}
+
+	docs := documents(attractions, restaurants, hotels)
+	entries := make([]IndexEntry, len(docs))
+	var pending []int
+	for i, doc := range docs {
+		entries[i] = IndexEntry{Kind: doc.kind, ID: doc.id, Name: doc.name, Hash: textHash(doc.text)}
+		if old, ok := existing[doc.kind+"/"+doc.id]; ok && old.Hash == entries[i].Hash {
+			entries[i].Vector = old.Vector
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		texts := make([]string, 0, end-start)
+		for _, i := range pending[start:end] {
+			texts = append(texts, docs[i].text)
+		}
+
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return false, fmt.Errorf("error embedding documents: %v", err)
+		}
+		if len(vectors) != len(texts) {
+			return false, fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(texts))
+		}
+		for j, i := range pending[start:end] {
+			entries[i].Vector = vectors[j]
+		}
+	}
+
+	changed := idx.Model != model || len(pending) > 0 || len(entries) != len(idx.Entries)
+	idx.Model = model
+	idx.Entries = entries
+	return changed, nil
+}
+
+// Search returns the entries most similar to the query vector, best first.
+// An empty kind searches all kinds; limit <= 0 returns every entry.
+func (idx *EmbeddingIndex) Search(query []float32, kind string, limit int) []IndexMatch {
+	var matches []IndexMatch
+	for _, e := range idx.Entries {
+		if kind != "" && e.Kind != kind {
+			continue
+		}
+		matches = append(matches, IndexMatch{Entry: e, Score: cosine(query, e.Vector)})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// documents builds the texts embedded for each point of interest
+func documents(attractions []Attraction, restaurants []Restaurant, hotels []Hotel) []document {
+	var docs []document
+	for _, a := range attractions {
+		docs = append(docs, document{KindAttraction, a.ID, a.Name, joinText(a.Name, a.Description, a.Category, a.Tags)})
+	}
+	for _, r := range restaurants {
+		docs = append(docs, document{KindRestaurant, r.ID, r.Name, joinText(r.Name, r.Description, r.Cuisine, r.Tags)})
+	}
+	for _, h := range hotels {
+		docs = append(docs, document{KindHotel, h.ID, h.Name, joinText(h.Name, h.Description, h.Amenities, nil)})
+	}
+	return docs
+}
+
+// joinText combines the descriptive fields of a POI into one text
+func joinText(name, description string, labels, tags []string) string {
+	parts := []string{name, description}
+	if len(labels) > 0 {
+		parts = append(parts, strings.Join(labels, ", "))
+	}
+	if len(tags) > 0 {
+		parts = append(parts, strings.Join(tags, ", "))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// textHash identifies the embedded text of an entry
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// embedderName identifies the embedder, so vectors of different models are
+// never compared
+func embedderName(embedder Embedder) string {
+	if named, ok := embedder.(interface{ ModelName() string }); ok {
+		return named.ModelName()
+	}
+	return fmt.Sprintf("%T", embedder)
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when their
+// dimensions differ or one is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// HashEmbedder is a deterministic embedder for offline tests. It hashes
+// words and, for CJK text, single characters and character bigrams into a
+// fixed number of dimensions, so texts sharing terms are similar. It does
+// not capture meaning: "观光" and "风景名胜" share no terms and stay apart.
+type HashEmbedder struct {
+	Dim int
+}
+
+// NewHashEmbedder creates a hash embedder with dim dimensions
+func NewHashEmbedder(dim int) *HashEmbedder {
+	return &HashEmbedder{Dim: dim}
+}
+
+// ModelName identifies the embedder in an EmbeddingIndex
+func (e *HashEmbedder) ModelName() string {
+	return fmt.Sprintf("hash-%d", e.Dim)
+}
+
+// Embed returns the normalized hashed term vector of every text
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.Dim <= 0 {
+		return nil, fmt.Errorf("hash embedder needs a positive dimension")
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+// embed hashes the terms of a text into a unit vector
+func (e *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.Dim)
+	for _, term := range terms(strings.ToLower(text)) {
+		h := fnv.New32a()
+		h.Write([]byte(term))
+		sum := h.Sum32()
+		sign := float32(1)
+		if sum&1 == 1 {
+			sign = -1
+		}
+		vector[int(sum>>1)%e.Dim] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
+
+// terms splits text into words, and CJK runs into characters and bigrams
+func terms(text string) []string {
+	var out []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			out = append(out, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			out = append(out, string(r))
+			if i+1 < len(cjk) {
+				out = append(out, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return out
+}
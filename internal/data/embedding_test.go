@@ -0,0 +1,195 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// countingEmbedder embeds with a HashEmbedder under the given model name and
+// records the texts it was asked to embed
+type countingEmbedder struct {
+	*HashEmbedder
+	name     string
+	embedded []string
+}
+
+func (e *countingEmbedder) ModelName() string {
+	return e.name
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.embedded = append(e.embedded, texts...)
+	return e.HashEmbedder.Embed(ctx, texts)
+}
+
+func newCountingEmbedder(name string) *countingEmbedder {
+	return &countingEmbedder{HashEmbedder: NewHashEmbedder(256), name: name}
+}
+
+// testPOIs returns a small set of points of interest around the West Lake
+func testPOIs() ([]Attraction, []Restaurant, []Hotel) {
+	attractions := []Attraction{
+		{ID: "a1", Name: "西湖", Description: "杭州最著名的湖泊，湖光山色", Category: []string{"自然风光"}, Tags: []string{"湖泊", "游船"}},
+		{ID: "a2", Name: "灵隐寺", Description: "千年古刹，佛教寺庙", Category: []string{"宗教文化"}, Tags: []string{"寺庙", "历史"}},
+	}
+	restaurants := []Restaurant{
+		{ID: "r1", Name: "楼外楼", Description: "西湖边的百年老店，西湖醋鱼", Cuisine: []string{"杭帮菜"}, Tags: []string{"湖景"}},
+	}
+	hotels := []Hotel{
+		{ID: "h1", Name: "西湖国宾馆", Description: "湖畔园林酒店", Amenities: []string{"游泳池", "停车场"}},
+	}
+	return attractions, restaurants, hotels
+}
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder(64)
+	vectors, err := e.Embed(context.Background(), []string{"西湖游船", "西湖游船", "寺庙历史", ""})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vectors) != 4 || len(vectors[0]) != 64 {
+		t.Fatalf("got %d vectors of %d dimensions, want 4 of 64", len(vectors), len(vectors[0]))
+	}
+	if !reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Error("the same text embeds differently")
+	}
+	if score := cosine(vectors[0], vectors[0]); score < 0.999 || score > 1.001 {
+		t.Errorf("vector is not normalized: self similarity %v", score)
+	}
+	if cosine(vectors[0], vectors[2]) >= cosine(vectors[0], vectors[1]) {
+		t.Error("unrelated texts are as similar as equal ones")
+	}
+	if cosine(vectors[0], vectors[3]) != 0 {
+		t.Error("the empty text has a non-zero vector")
+	}
+
+	if _, err := NewHashEmbedder(0).Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("a zero dimension embedder did not fail")
+	}
+}
+
+func TestEmbeddingIndexUpdate(t *testing.T) {
+	ctx := context.Background()
+	attractions, restaurants, hotels := testPOIs()
+	embedder := newCountingEmbedder("hash-a")
+	idx := &EmbeddingIndex{}
+
+	changed, err := idx.Update(ctx, embedder, attractions, restaurants, hotels)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !changed || len(idx.Entries) != 4 || len(embedder.embedded) != 4 || idx.Model != "hash-a" {
+		t.Fatalf("first update: changed %v, %d entries, %d embedded, model %q", changed, len(idx.Entries), len(embedder.embedded), idx.Model)
+	}
+
+	// Unchanged documents keep their vectors
+	embedder.embedded = nil
+	changed, err = idx.Update(ctx, embedder, attractions, restaurants, hotels)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if changed || len(embedder.embedded) != 0 {
+		t.Errorf("unchanged update: changed %v, embedded %q", changed, embedder.embedded)
+	}
+
+	// Only the edited document is embedded again
+	attractions[1].Description = "千年古刹，香火旺盛"
+	changed, err = idx.Update(ctx, embedder, attractions, restaurants, hotels)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !changed || len(embedder.embedded) != 1 || embedder.embedded[0] != joinText("灵隐寺", "千年古刹，香火旺盛", []string{"宗教文化"}, []string{"寺庙", "历史"}) {
+		t.Errorf("edited update: changed %v, embedded %q", changed, embedder.embedded)
+	}
+
+	// Removed documents leave the index
+	embedder.embedded = nil
+	changed, err = idx.Update(ctx, embedder, attractions[:1], restaurants, hotels)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !changed || len(idx.Entries) != 3 || len(embedder.embedded) != 0 {
+		t.Errorf("removal update: changed %v, %d entries, embedded %q", changed, len(idx.Entries), embedder.embedded)
+	}
+
+	// Another embedder invalidates every vector
+	other := newCountingEmbedder("hash-b")
+	changed, err = idx.Update(ctx, other, attractions[:1], restaurants, hotels)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if !changed || len(other.embedded) != 3 || idx.Model != "hash-b" {
+		t.Errorf("new embedder update: changed %v, embedded %d, model %q", changed, len(other.embedded), idx.Model)
+	}
+}
+
+func TestEmbeddingIndexSaveLoad(t *testing.T) {
+	attractions, restaurants, hotels := testPOIs()
+	idx := &EmbeddingIndex{}
+	if _, err := idx.Update(context.Background(), NewHashEmbedder(32), attractions, restaurants, hotels); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "index", "embeddings.json")
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadEmbeddingIndex(path)
+	if err != nil {
+		t.Fatalf("LoadEmbeddingIndex: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, idx) {
+		t.Errorf("loaded index differs:\n got %+v\nwant %+v", loaded, idx)
+	}
+
+	// A missing file is an empty index
+	empty, err := LoadEmbeddingIndex(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || empty.Model != "" || len(empty.Entries) != 0 {
+		t.Errorf("missing file = %+v, %v, want an empty index", empty, err)
+	}
+}
+
+func TestEmbeddingIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	attractions, restaurants, hotels := testPOIs()
+	embedder := NewHashEmbedder(256)
+	idx := &EmbeddingIndex{}
+	if _, err := idx.Update(ctx, embedder, attractions, restaurants, hotels); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	query, err := embedder.Embed(ctx, []string{"寺庙 历史"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	matches := idx.Search(query[0], "", 0)
+	if len(matches) != 4 {
+		t.Fatalf("got %d matches, want every entry", len(matches))
+	}
+	if matches[0].Entry.ID != "a2" {
+		t.Errorf("best match = %s, want a2", matches[0].Entry.ID)
+	}
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Errorf("match %d scores %v above match %d (%v)", i, matches[i].Score, i-1, matches[i-1].Score)
+		}
+	}
+
+	if top := idx.Search(query[0], "", 2); len(top) != 2 || top[0].Entry.ID != "a2" {
+		t.Errorf("limited search = %+v", top)
+	}
+
+	query, err = embedder.Embed(ctx, []string{"西湖"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	hotelMatches := idx.Search(query[0], KindHotel, 0)
+	if len(hotelMatches) != 1 || hotelMatches[0].Entry.ID != "h1" {
+		t.Errorf("hotel search = %+v, want only h1", hotelMatches)
+	}
+	if matches := idx.Search(query[0], KindRestaurant, 0); len(matches) != 1 || matches[0].Entry.Kind != KindRestaurant {
+		t.Errorf("restaurant search = %+v", matches)
+	}
+}
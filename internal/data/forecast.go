@@ -0,0 +1,119 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// forecastZone is the time zone of the dates in data/weather/forecast.json
+var forecastZone = time.FixedZone("CST", 8*3600)
+
+// Measure is a daily range of a measurement
+type Measure struct {
+	Max  float64 `json:"max"`
+	Min  float64 `json:"min"`
+	Unit string  `json:"unit"`
+}
+
+// DailyForecast is the forecast of one day in data/weather/forecast.json
+type DailyForecast struct {
+	Date    string `json:"date"` // 2006-01-02
+	Weather struct {
+		Day   string `json:"day"`
+		Night string `json:"night"`
+	} `json:"weather"`
+	Temperature Measure `json:"temperature"`
+	Humidity    Measure `json:"humidity"`
+	Wind        struct {
+		Direction string  `json:"direction"`
+		Speed     Measure `json:"speed"`
+	} `json:"wind"`
+	Precipitation struct {
+		Probability float64 `json:"probability"` // percent
+		Amount      float64 `json:"amount"`
+		Unit        string  `json:"unit"`
+	} `json:"precipitation"`
+	AirQuality struct {
+		AQI              int    `json:"aqi"`
+		Level            string `json:"level"`
+		PrimaryPollutant string `json:"primary_pollutant"`
+	} `json:"air_quality"`
+	Suggestion struct {
+		Tourism string `json:"tourism"`
+		Comfort string `json:"comfort"`
+		Notes   string `json:"notes"`
+	} `json:"suggestion"`
+}
+
+// Notice is a travel notice issued with a forecast
+type Notice struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// Forecast is the city forecast of data/weather/forecast.json
+type Forecast struct {
+	City           string          `json:"city"`
+	UpdateTime     time.Time       `json:"update_time"`
+	Source         string          `json:"source"`
+	DailyForecasts []DailyForecast `json:"daily_forecasts"`
+	SpecialNotices []Notice        `json:"special_notices"`
+}
+
+// LoadForecast loads data/weather/forecast.json
+func (d *DataLoader) LoadForecast() (*Forecast, error) {
+	var forecast Forecast
+	if err := d.loadJSON("weather/forecast.json", &forecast); err != nil {
+		return nil, err
+	}
+	for _, day := range forecast.DailyForecasts {
+		if _, err := day.Time(); err != nil {
+			return nil, fmt.Errorf("error parsing forecast date %q: %v", day.Date, err)
+		}
+	}
+	return &forecast, nil
+}
+
+// Time returns the date of the forecast at midnight, China time
+func (f DailyForecast) Time() (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", f.Date, forecastZone)
+}
+
+// ToWeather maps the forecast onto Weather at location. The condition is the
+// daytime weather, the humidity the daily mean and the wind speed the
+// maximum.
+func (f DailyForecast) ToWeather(location Location) Weather {
+	date, _ := f.Time()
+	w := Weather{
+		Date:          date,
+		Location:      location,
+		Condition:     f.Weather.Day,
+		Humidity:      (f.Humidity.Min + f.Humidity.Max) / 2,
+		WindSpeed:     f.Wind.Speed.Max,
+		Precipitation: f.Precipitation.Amount,
+	}
+	w.Temperature.Min = f.Temperature.Min
+	w.Temperature.Max = f.Temperature.Max
+	return w
+}
+
+// ToWeather maps the city forecast onto Weather at location, one per day. The
+// forecast covers the whole city, so any location in it can be used.
+func (f *Forecast) ToWeather(location Location) []Weather {
+	weather := make([]Weather, len(f.DailyForecasts))
+	for i, day := range f.DailyForecasts {
+		weather[i] = day.ToWeather(location)
+	}
+	return weather
+}
+
+// ForDate returns the forecast of the day of date, in China time
+func (f *Forecast) ForDate(date time.Time) (DailyForecast, bool) {
+	day := date.In(forecastZone).Format("2006-01-02")
+	for _, forecast := range f.DailyForecasts {
+		if forecast.Date == day {
+			return forecast, true
+		}
+	}
+	return DailyForecast{}, false
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxImageSize limits the size of image files sent to a model
+const maxImageSize = 20 << 20
+
+// imageDirs are the directories, relative to the data directory, searched for
+// the image names referenced by attractions and hotels
+var imageDirs = []string{"images", "images/attractions", "images/hotels", "."}
+
+// Image is an image file referenced by the dataset
+type Image struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// ResolveImage returns the path of an image referenced by name, e.g.
+// "west_lake_1.jpg", searching the image directories under the data directory.
+// Names must stay within the data directory.
+func (d *DataLoader) ResolveImage(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("image name %q is outside the data directory", name)
+	}
+
+	var searched []string
+	for _, dir := range imageDirs {
+		path := filepath.Join(d.BasePath, dir, clean)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() {
+			return path, nil
+		}
+		searched = append(searched, path)
+	}
+	return "", fmt.Errorf("image %s not found, looked in %s", name, strings.Join(searched, ", "))
+}
+
+// LoadImage reads an image referenced by name
+func (d *DataLoader) LoadImage(name string) (*Image, error) {
+	path, err := d.ResolveImage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading image %s: %v", name, err)
+	}
+	if info.Size() > maxImageSize {
+		return nil, fmt.Errorf("image %s is too large (%d bytes)", name, info.Size())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading image %s: %v", name, err)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("file %s is not an image (%s)", name, mimeType)
+	}
+
+	return &Image{Name: name, MIMEType: mimeType, Data: data}, nil
+}
+
+// LoadImages reads the images referenced by names
+func (d *DataLoader) LoadImages(names []string) ([]Image, error) {
+	images := make([]Image, 0, len(names))
+	for _, name := range names {
+		image, err := d.LoadImage(name)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
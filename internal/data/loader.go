@@ -2,6 +2,7 @@ package data
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,12 +20,13 @@ func NewDataLoader(basePath string) *DataLoader {
 	}
 }
 
-// loadJSON loads data from a JSON file into the target interface
+// loadJSON loads data from a JSON file into the target interface. A missing
+// file yields an error matching os.ErrNotExist.
 func (d *DataLoader) loadJSON(filename string, target interface{}) error {
 	path := filepath.Join(d.BasePath, filename)
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("error reading file %s: %v", filename, err)
+		return fmt.Errorf("error reading file %s: %w", filename, err)
 	}
 
 	if err := json.Unmarshal(data, target); err != nil {
@@ -34,25 +36,70 @@ func (d *DataLoader) loadJSON(filename string, target interface{}) error {
 	return nil
 }
 
-// LoadAttractions loads attractions data
+// LoadAttractions loads attractions data, enriched with the detailed
+// attractions of data/tourism/attractions.json when present
 func (d *DataLoader) LoadAttractions() ([]Attraction, error) {
 	var attractions []Attraction
-	err := d.loadJSON("attractions.json", &attractions)
-	return attractions, err
+	if err := d.loadJSON("attractions.json", &attractions); err != nil {
+		return attractions, err
+	}
+
+	details, err := d.LoadAttractionDetails()
+	if errors.Is(err, os.ErrNotExist) {
+		return attractions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	districts, err := d.districtsByID()
+	if err != nil {
+		return nil, err
+	}
+	return mergeByName(attractions, AttractionsFromDetails(details, districts), AttractionFields.Name, (*Attraction).enrich), nil
 }
 
-// LoadRestaurants loads restaurants data
+// LoadRestaurants loads restaurants data, enriched with the detailed
+// restaurants of data/tourism/restaurants.json when present
 func (d *DataLoader) LoadRestaurants() ([]Restaurant, error) {
 	var restaurants []Restaurant
-	err := d.loadJSON("restaurants.json", &restaurants)
-	return restaurants, err
+	if err := d.loadJSON("restaurants.json", &restaurants); err != nil {
+		return restaurants, err
+	}
+
+	details, err := d.LoadRestaurantDetails()
+	if errors.Is(err, os.ErrNotExist) {
+		return restaurants, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	districts, err := d.districtsByID()
+	if err != nil {
+		return nil, err
+	}
+	return mergeByName(restaurants, RestaurantsFromDetails(details, districts), RestaurantFields.Name, (*Restaurant).enrich), nil
 }
 
-// LoadHotels loads hotels data
+// LoadHotels loads hotels data, enriched with the detailed hotels of
+// data/tourism/hotels.json when present
 func (d *DataLoader) LoadHotels() ([]Hotel, error) {
 	var hotels []Hotel
-	err := d.loadJSON("hotels.json", &hotels)
-	return hotels, err
+	if err := d.loadJSON("hotels.json", &hotels); err != nil {
+		return hotels, err
+	}
+
+	details, err := d.LoadHotelDetails()
+	if errors.Is(err, os.ErrNotExist) {
+		return hotels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	districts, err := d.districtsByID()
+	if err != nil {
+		return nil, err
+	}
+	return mergeByName(hotels, HotelsFromDetails(details, districts), HotelFields.Name, (*Hotel).enrich), nil
 }
 
 // LoadWeather loads weather data
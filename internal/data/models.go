@@ -41,6 +41,13 @@ type Attraction struct {
 	Tags        []string `json:"tags"`
 	Rating      float64  `json:"rating"`
 	Images      []string `json:"images,omitempty"`
+
+	// Enriched from data/tourism/attractions.json when listed there
+	District   string      `json:"district,omitempty"`
+	Highlights []string    `json:"highlights,omitempty"`
+	BestTimes  []string    `json:"best_times,omitempty"`
+	VisitHours float64     `json:"visit_hours,omitempty"` // recommended length of the visit
+	CrowdLevel *CrowdLevel `json:"crowd_level,omitempty"`
 }
 
 // Restaurant represents a dining establishment
@@ -54,6 +61,11 @@ type Restaurant struct {
 	OpenHours   []string `json:"open_hours"`
 	Description string   `json:"description"`
 	Tags        []string `json:"tags"`
+
+	// Enriched from data/tourism/restaurants.json when listed there
+	District             string   `json:"district,omitempty"`
+	SignatureDishes      []string `json:"signature_dishes,omitempty"`
+	ReservationsRequired bool     `json:"reservations_required,omitempty"`
 }
 
 // Hotel represents an accommodation option
@@ -67,6 +79,10 @@ type Hotel struct {
 	Description   string   `json:"description"`
 	Rating        float64  `json:"rating"`
 	Images        []string `json:"images,omitempty"`
+
+	// Enriched from data/tourism/hotels.json when listed there
+	District       string   `json:"district,omitempty"`
+	NearbyStations []string `json:"nearby_stations,omitempty"`
 }
 
 // Weather represents weather information for a specific date and location
@@ -105,7 +121,7 @@ type Activity struct {
 type Meal struct {
 	Restaurant Restaurant `json:"restaurant"`
 	Time       time.Time  `json:"time"`
-	Type       string     `json:"type"` // breakfast, lunch, dinner
+	Type       string     `json:"type" jsonschema:"enum=breakfast,enum=lunch,enum=dinner"`
 	Cost       float64    `json:"cost"`
 }
 
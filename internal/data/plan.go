@@ -0,0 +1,35 @@
+package data
+
+import "fmt"
+
+// Validate checks that a trip plan is complete enough to be presented
+func (p *TripPlan) Validate() error {
+	if len(p.DailyPlans) == 0 {
+		return fmt.Errorf("daily_plans must contain at least one day")
+	}
+	if p.TotalCost < 0 {
+		return fmt.Errorf("total_cost must not be negative")
+	}
+
+	for i, day := range p.DailyPlans {
+		if day.Date.IsZero() {
+			return fmt.Errorf("daily_plans[%d].date is required", i)
+		}
+		for j, activity := range day.Activities {
+			if activity.Attraction.Name == "" {
+				return fmt.Errorf("daily_plans[%d].activities[%d].attraction.name is required", i, j)
+			}
+			if activity.EndTime.Before(activity.StartTime) {
+				return fmt.Errorf("daily_plans[%d].activities[%d] ends before it starts", i, j)
+			}
+		}
+		for j, meal := range day.Meals {
+			switch meal.Type {
+			case "breakfast", "lunch", "dinner":
+			default:
+				return fmt.Errorf("daily_plans[%d].meals[%d].type must be breakfast, lunch or dinner, got %q", i, j, meal.Type)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+package data
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields reads the attributes of a point of interest type for queries.
+// Unset fields read as empty.
+type Fields[T any] struct {
+	ID       func(T) string
+	Name     func(T) string
+	Location func(T) Location
+	// Categories are the broad kinds of the item, e.g. the cuisines of a
+	// restaurant
+	Categories func(T) []string
+	Tags       func(T) []string
+	// OpenHours are "15:04-15:04" ranges
+	OpenHours func(T) []string
+	Price     func(T) float64
+	Rating    func(T) float64
+	// District is the name of the district, e.g. 西湖区
+	District func(T) string
+}
+
+// AttractionFields reads attractions; the price is the entrance fee
+var AttractionFields = Fields[Attraction]{
+	ID:         func(a Attraction) string { return a.ID },
+	Name:       func(a Attraction) string { return a.Name },
+	Location:   func(a Attraction) Location { return a.Location },
+	Categories: func(a Attraction) []string { return a.Category },
+	Tags:       func(a Attraction) []string { return a.Tags },
+	OpenHours:  func(a Attraction) []string { return a.OpenHours },
+	Price:      func(a Attraction) float64 { return a.Price },
+	Rating:     func(a Attraction) float64 { return a.Rating },
+	District:   func(a Attraction) string { return a.District },
+}
+
+// RestaurantFields reads restaurants; the categories are the cuisines and the
+// price is the $ level, 1 to 4
+var RestaurantFields = Fields[Restaurant]{
+	ID:         func(r Restaurant) string { return r.ID },
+	Name:       func(r Restaurant) string { return r.Name },
+	Location:   func(r Restaurant) Location { return r.Location },
+	Categories: func(r Restaurant) []string { return r.Cuisine },
+	Tags:       func(r Restaurant) []string { return r.Tags },
+	OpenHours:  func(r Restaurant) []string { return r.OpenHours },
+	Price:      func(r Restaurant) float64 { return float64(strings.Count(r.PriceRange, "$")) },
+	Rating:     func(r Restaurant) float64 { return r.Rating },
+	District:   func(r Restaurant) string { return r.District },
+}
+
+// HotelFields reads hotels; the tags are the amenities and the price is the
+// price per night. Hotels have no categories or opening hours.
+var HotelFields = Fields[Hotel]{
+	ID:       func(h Hotel) string { return h.ID },
+	Name:     func(h Hotel) string { return h.Name },
+	Location: func(h Hotel) Location { return h.Location },
+	Tags:     func(h Hotel) []string { return h.Amenities },
+	Price:    func(h Hotel) float64 { return h.PricePerNight },
+	Rating:   func(h Hotel) float64 { return h.Rating },
+	District: func(h Hotel) string { return h.District },
+}
+
+// Predicate selects the items of a query
+type Predicate[T any] func(T) bool
+
+// And matches the items matching all of predicates
+func And[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(item T) bool {
+		for _, p := range predicates {
+			if !p(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches the items matching any of predicates
+func Or[T any](predicates ...Predicate[T]) Predicate[T] {
+	return func(item T) bool {
+		for _, p := range predicates {
+			if p(item) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches the items predicate does not match
+func Not[T any](predicate Predicate[T]) Predicate[T] {
+	return func(item T) bool {
+		return !predicate(item)
+	}
+}
+
+// HasCategory matches the items with a category containing any of values,
+// ignoring case. Without values it matches every item.
+func (f Fields[T]) HasCategory(values ...string) Predicate[T] {
+	return containsAny(f.Categories, values)
+}
+
+// HasTag matches the items with a tag containing any of values, ignoring
+// case. Without values it matches every item.
+func (f Fields[T]) HasTag(values ...string) Predicate[T] {
+	return containsAny(f.Tags, values)
+}
+
+// HasAllTags matches the items having, for each of values, a tag containing
+// it, ignoring case
+func (f Fields[T]) HasAllTags(values ...string) Predicate[T] {
+	predicates := make([]Predicate[T], len(values))
+	for i, value := range values {
+		predicates[i] = f.HasTag(value)
+	}
+	return And(predicates...)
+}
+
+// PriceBetween matches the items priced from min to max inclusive
+func (f Fields[T]) PriceBetween(min, max float64) Predicate[T] {
+	return func(item T) bool {
+		price := read(f.Price, item)
+		return price >= min && price <= max
+	}
+}
+
+// PriceAtMost matches the items priced at most max
+func (f Fields[T]) PriceAtMost(max float64) Predicate[T] {
+	return func(item T) bool {
+		return read(f.Price, item) <= max
+	}
+}
+
+// RatingAtLeast matches the items rated at least min
+func (f Fields[T]) RatingAtLeast(min float64) Predicate[T] {
+	return func(item T) bool {
+		return read(f.Rating, item) >= min
+	}
+}
+
+// OpenAt matches the items open at the time of day of t. Ranges may end at
+// 24:00 or past midnight, e.g. "18:00-02:00". Items without readable opening
+// hours are assumed to be open.
+func (f Fields[T]) OpenAt(t time.Time) Predicate[T] {
+	minute := t.Hour()*60 + t.Minute()
+	return func(item T) bool {
+		known := false
+		for _, r := range read(f.OpenHours, item) {
+			start, end, ok := parseHours(r)
+			if !ok {
+				continue
+			}
+			known = true
+			if start <= end && minute >= start && minute < end ||
+				start > end && (minute >= start || minute < end) {
+				return true
+			}
+		}
+		return !known
+	}
+}
+
+// InDistrict matches the items in a district whose name contains any of
+// values, e.g. "西湖" for 西湖区. Without values it matches every item.
+func (f Fields[T]) InDistrict(values ...string) Predicate[T] {
+	var district func(T) []string
+	if f.District != nil {
+		district = func(item T) []string { return []string{f.District(item)} }
+	}
+	return containsAny(district, values)
+}
+
+// Within matches the items within radius kilometers of center
+func (f Fields[T]) Within(center Location, radius float64) Predicate[T] {
+	return func(item T) bool {
+		return CalculateDistance(center, read(f.Location, item)) <= radius
+	}
+}
+
+// containsAny matches the items with a value of list containing any of
+// values, ignoring case
+func containsAny[T any](list func(T) []string, values []string) Predicate[T] {
+	if len(values) == 0 {
+		return func(T) bool { return true }
+	}
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return func(item T) bool {
+		for _, v := range read(list, item) {
+			v = strings.ToLower(v)
+			for _, value := range lowered {
+				if strings.Contains(v, value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// read returns field of item, or the zero value when the field is unset
+func read[T, V any](field func(T) V, item T) V {
+	if field == nil {
+		var zero V
+		return zero
+	}
+	return field(item)
+}
+
+// parseHours parses a "15:04-15:04" range into minutes of the day
+func parseHours(r string) (start, end int, ok bool) {
+	from, to, found := strings.Cut(strings.TrimSpace(r), "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, ok = parseClock(from)
+	if !ok {
+		return 0, 0, false
+	}
+	end, ok = parseClock(to)
+	return start, end, ok
+}
+
+// parseClock parses "15:04", accepting "24:00", into minutes of the day
+func parseClock(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, true
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// SortKey is an attribute queries can sort by
+type SortKey int
+
+const (
+	SortByRating SortKey = iota
+	SortByPrice
+	// SortByDistance sorts by the distance from the center set with Near
+	SortByDistance
+	SortByName
+)
+
+// Order is a sort key and direction
+type Order struct {
+	Key        SortKey
+	Descending bool
+}
+
+// Asc orders by key, smallest first
+func Asc(key SortKey) Order {
+	return Order{Key: key}
+}
+
+// Desc orders by key, largest first
+func Desc(key SortKey) Order {
+	return Order{Key: key, Descending: true}
+}
+
+// Result is a page of the items matching a query
+type Result[T any] struct {
+	// Hits are the items of the page with their distance from the center set
+	// with Near, or zero
+	Hits []Nearby[T]
+	// Total is the number of matching items on all pages
+	Total int
+}
+
+// Items returns the items of the page
+func (r Result[T]) Items() []T {
+	return Items(r.Hits)
+}
+
+// Query selects, sorts and pages points of interest. Build it with the
+// chained methods and call Run; a Query is not safe for concurrent use.
+type Query[T any] struct {
+	fields     Fields[T]
+	items      []T
+	index      *SpatialIndex[T]
+	predicates []Predicate[T]
+	center     *Location
+	radius     float64
+	orders     []Order
+	offset     int
+	limit      int
+}
+
+// NewQuery creates a query over items, read with fields
+func NewQuery[T any](fields Fields[T], items []T) *Query[T] {
+	return &Query[T]{fields: fields, items: items}
+}
+
+// NewIndexedQuery creates a query over the items of index, which answers the
+// radius of Near without scanning every item
+func NewIndexedQuery[T any](fields Fields[T], index *SpatialIndex[T]) *Query[T] {
+	return &Query[T]{fields: fields, items: index.items, index: index}
+}
+
+// QueryAttractions creates a query over attractions
+func QueryAttractions(attractions []Attraction) *Query[Attraction] {
+	return NewQuery(AttractionFields, attractions)
+}
+
+// QueryRestaurants creates a query over restaurants
+func QueryRestaurants(restaurants []Restaurant) *Query[Restaurant] {
+	return NewQuery(RestaurantFields, restaurants)
+}
+
+// QueryHotels creates a query over hotels
+func QueryHotels(hotels []Hotel) *Query[Hotel] {
+	return NewQuery(HotelFields, hotels)
+}
+
+// Fields returns the fields the query reads its items with, to build
+// predicates
+func (q *Query[T]) Fields() Fields[T] {
+	return q.fields
+}
+
+// Where keeps the items matching all of predicates
+func (q *Query[T]) Where(predicates ...Predicate[T]) *Query[T] {
+	q.predicates = append(q.predicates, predicates...)
+	return q
+}
+
+// Category keeps the items with a category containing any of values; it
+// does nothing without values
+func (q *Query[T]) Category(values ...string) *Query[T] {
+	if len(values) == 0 {
+		return q
+	}
+	return q.Where(q.fields.HasCategory(values...))
+}
+
+// Tag keeps the items with a tag containing any of values; it does nothing
+// without values
+func (q *Query[T]) Tag(values ...string) *Query[T] {
+	if len(values) == 0 {
+		return q
+	}
+	return q.Where(q.fields.HasTag(values...))
+}
+
+// District keeps the items in a district whose name contains any of values;
+// it does nothing without values
+func (q *Query[T]) District(values ...string) *Query[T] {
+	if len(values) == 0 {
+		return q
+	}
+	return q.Where(q.fields.InDistrict(values...))
+}
+
+// MaxPrice keeps the items priced at most max, so a max of zero keeps the
+// free items only. Leave it out for no limit.
+func (q *Query[T]) MaxPrice(max float64) *Query[T] {
+	return q.Where(q.fields.PriceAtMost(max))
+}
+
+// MinRating keeps the items rated at least min
+func (q *Query[T]) MinRating(min float64) *Query[T] {
+	return q.Where(q.fields.RatingAtLeast(min))
+}
+
+// OpenAt keeps the items open at the time of day of t
+func (q *Query[T]) OpenAt(t time.Time) *Query[T] {
+	return q.Where(q.fields.OpenAt(t))
+}
+
+// Near measures the distance of the items from center, keeps those within
+// radius kilometers, or all with a radius of zero or less, and orders them
+// nearest first unless sorted otherwise
+func (q *Query[T]) Near(center Location, radius float64) *Query[T] {
+	q.center = &center
+	q.radius = radius
+	return q
+}
+
+// SortBy sorts the items by orders, the first order first. Items equal on
+// every order keep their order.
+func (q *Query[T]) SortBy(orders ...Order) *Query[T] {
+	q.orders = append(q.orders, orders...)
+	return q
+}
+
+// Offset skips the first n matching items
+func (q *Query[T]) Offset(n int) *Query[T] {
+	q.offset = n
+	return q
+}
+
+// Limit returns at most n items; zero or less returns all
+func (q *Query[T]) Limit(n int) *Query[T] {
+	q.limit = n
+	return q
+}
+
+// Run returns the page of matching items
+func (q *Query[T]) Run() Result[T] {
+	hits := q.candidates()
+
+	n := 0
+	for _, hit := range hits {
+		if q.matches(hit.Item) {
+			hits[n] = hit
+			n++
+		}
+	}
+	hits = hits[:n]
+
+	if len(q.orders) > 0 {
+		sort.SliceStable(hits, func(i, j int) bool {
+			for _, order := range q.orders {
+				if c := q.compare(order.Key, hits[i], hits[j]); c != 0 {
+					return c < 0 != order.Descending
+				}
+			}
+			return false
+		})
+	}
+
+	result := Result[T]{Total: len(hits)}
+	start := min(max(q.offset, 0), len(hits))
+	end := len(hits)
+	if q.limit > 0 {
+		end = min(start+q.limit, end)
+	}
+	result.Hits = hits[start:end]
+	return result
+}
+
+// candidates returns the items within the radius of Near, nearest first, or
+// all items in order
+func (q *Query[T]) candidates() []Nearby[T] {
+	if q.center == nil {
+		hits := make([]Nearby[T], len(q.items))
+		for i, item := range q.items {
+			hits[i] = Nearby[T]{Item: item}
+		}
+		return hits
+	}
+	if q.index != nil && q.radius > 0 {
+		return q.index.WithinRadius(*q.center, q.radius)
+	}
+
+	var hits []Nearby[T]
+	for _, item := range q.items {
+		distance := CalculateDistance(*q.center, read(q.fields.Location, item))
+		if q.radius <= 0 || distance <= q.radius {
+			hits = append(hits, Nearby[T]{Item: item, Distance: distance})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Distance < hits[j].Distance })
+	return hits
+}
+
+// matches reports whether item matches every predicate
+func (q *Query[T]) matches(item T) bool {
+	for _, p := range q.predicates {
+		if !p(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare compares two hits on key
+func (q *Query[T]) compare(key SortKey, a, b Nearby[T]) int {
+	switch key {
+	case SortByRating:
+		return cmp.Compare(read(q.fields.Rating, a.Item), read(q.fields.Rating, b.Item))
+	case SortByPrice:
+		return cmp.Compare(read(q.fields.Price, a.Item), read(q.fields.Price, b.Item))
+	case SortByDistance:
+		return cmp.Compare(a.Distance, b.Distance)
+	case SortByName:
+		return strings.Compare(read(q.fields.Name, a.Item), read(q.fields.Name, b.Item))
+	}
+	return 0
+}
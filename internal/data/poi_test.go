@@ -0,0 +1,193 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryMaxPrice(t *testing.T) {
+	attractions := []Attraction{
+		{ID: "free", Price: 0},
+		{ID: "cheap", Price: 30},
+		{ID: "dear", Price: 80},
+	}
+	tests := []struct {
+		name  string
+		query *Query[Attraction]
+		want  int
+	}{
+		{"no limit", QueryAttractions(attractions), 3},
+		{"free only", QueryAttractions(attractions).MaxPrice(0), 1},
+		{"inclusive", QueryAttractions(attractions).MaxPrice(30), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.query.Run(); result.Total != tt.want {
+				t.Errorf("got %d attractions, want %d", result.Total, tt.want)
+			}
+		})
+	}
+}
+
+// ids returns the IDs of the attractions of a result
+func ids(result Result[Attraction]) []string {
+	ids := make([]string, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.Item.ID)
+	}
+	return ids
+}
+
+func TestQueryOpenAt(t *testing.T) {
+	attractions := []Attraction{
+		{ID: "day", OpenHours: []string{"08:30-17:00"}},
+		{ID: "night", OpenHours: []string{"18:00-02:00"}},
+		{ID: "always", OpenHours: []string{"00:00-24:00"}},
+		{ID: "split", OpenHours: []string{"09:00-12:00", "14:00-18:00"}},
+		{ID: "unknown", OpenHours: []string{"全天开放"}},
+		{ID: "unlisted"},
+	}
+	tests := []struct {
+		clock string
+		want  []string
+	}{
+		{"00:00", []string{"night", "always", "unknown", "unlisted"}},
+		{"01:59", []string{"night", "always", "unknown", "unlisted"}},
+		{"02:00", []string{"always", "unknown", "unlisted"}},
+		{"08:30", []string{"day", "always", "unknown", "unlisted"}},
+		{"12:30", []string{"day", "always", "unknown", "unlisted"}},
+		{"16:59", []string{"day", "always", "split", "unknown", "unlisted"}},
+		{"17:00", []string{"always", "split", "unknown", "unlisted"}},
+		{"18:00", []string{"night", "always", "unknown", "unlisted"}},
+		{"23:59", []string{"night", "always", "unknown", "unlisted"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			at, err := time.Parse("2006-01-02 15:04", "2025-02-18 "+tt.clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(QueryAttractions(attractions).OpenAt(at).Run()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("open at %s: %v, want %v", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuerySortBy(t *testing.T) {
+	attractions := []Attraction{
+		{ID: "a", Name: "西湖", Rating: 4.8, Price: 0},
+		{ID: "b", Name: "灵隐寺", Rating: 4.7, Price: 75},
+		{ID: "c", Name: "雷峰塔", Rating: 4.7, Price: 40},
+		{ID: "d", Name: "河坊街", Rating: 4.5, Price: 0},
+		{ID: "e", Name: "宋城", Rating: 4.7, Price: 40},
+		{ID: "f", Name: "西溪湿地", Rating: 4.8, Price: 0},
+	}
+	tests := []struct {
+		name   string
+		orders []Order
+		want   []string
+	}{
+		{"unsorted", nil, []string{"a", "b", "c", "d", "e", "f"}},
+		{"rating, ties in input order", []Order{Desc(SortByRating)}, []string{"a", "f", "b", "c", "e", "d"}},
+		{"rating then price", []Order{Desc(SortByRating), Asc(SortByPrice)}, []string{"a", "f", "c", "e", "b", "d"}},
+		{"price then rating", []Order{Asc(SortByPrice), Desc(SortByRating)}, []string{"a", "f", "d", "c", "e", "b"}},
+		{"three keys", []Order{Desc(SortByRating), Asc(SortByPrice), Desc(SortByName)}, []string{"f", "a", "c", "e", "b", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(QueryAttractions(attractions).SortBy(tt.orders...).Run()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Sorting in two calls is the same as in one
+	got := ids(QueryAttractions(attractions).SortBy(Desc(SortByRating)).SortBy(Asc(SortByPrice)).Run())
+	if want := []string{"a", "f", "c", "e", "b", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted in two calls %v, want %v", got, want)
+	}
+}
+
+func TestQueryPaging(t *testing.T) {
+	attractions := []Attraction{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}}
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          []string
+	}{
+		{"all", 0, 0, []string{"a", "b", "c", "d", "e"}},
+		{"first page", 0, 2, []string{"a", "b"}},
+		{"middle page", 2, 2, []string{"c", "d"}},
+		{"last partial page", 4, 2, []string{"e"}},
+		{"limit past the end", 3, 10, []string{"d", "e"}},
+		{"offset at the end", 5, 2, []string{}},
+		{"offset past the end", 9, 2, []string{}},
+		{"offset without limit", 3, 0, []string{"d", "e"}},
+		{"negative offset", -1, 2, []string{"a", "b"}},
+		{"negative limit", 1, -1, []string{"b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := QueryAttractions(attractions).Offset(tt.offset).Limit(tt.limit).Run()
+			if got := ids(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+			if result.Total != len(attractions) {
+				t.Errorf("Total = %d, want every match", result.Total)
+			}
+		})
+	}
+}
+
+func TestQueryNear(t *testing.T) {
+	westLake := Location{Latitude: 30.2590, Longitude: 120.1388}
+	attractions := []Attraction{
+		{ID: "lingyin", Location: Location{Latitude: 30.2417, Longitude: 120.0962}, Rating: 4.7},
+		{ID: "leifeng", Location: Location{Latitude: 30.2312, Longitude: 120.1489}, Rating: 4.6},
+		{ID: "duanqiao", Location: Location{Latitude: 30.2612, Longitude: 120.1505}, Rating: 4.8},
+		{ID: "xixi", Location: Location{Latitude: 30.2700, Longitude: 120.0630}, Rating: 4.7},
+		{ID: "qiandao", Location: Location{Latitude: 29.6050, Longitude: 119.0420}, Rating: 4.9},
+	}
+	queries := map[string]func() *Query[Attraction]{
+		"scan":    func() *Query[Attraction] { return NewQuery(AttractionFields, attractions) },
+		"indexed": func() *Query[Attraction] { return NewIndexedQuery(AttractionFields, NewAttractionIndex(attractions)) },
+	}
+	tests := []struct {
+		name  string
+		build func(q *Query[Attraction]) *Query[Attraction]
+		want  []string
+	}{
+		{"within radius, nearest first", func(q *Query[Attraction]) *Query[Attraction] {
+			return q.Near(westLake, 5)
+		}, []string{"duanqiao", "leifeng", "lingyin"}},
+		{"no radius", func(q *Query[Attraction]) *Query[Attraction] {
+			return q.Near(westLake, 0)
+		}, []string{"duanqiao", "leifeng", "lingyin", "xixi", "qiandao"}},
+		{"nothing in range", func(q *Query[Attraction]) *Query[Attraction] {
+			return q.Near(Location{Latitude: 31.2304, Longitude: 121.4737}, 10)
+		}, []string{}},
+		{"sorted by rating", func(q *Query[Attraction]) *Query[Attraction] {
+			return q.Near(westLake, 10).SortBy(Desc(SortByRating))
+		}, []string{"duanqiao", "lingyin", "xixi", "leifeng"}},
+		{"filtered and paged", func(q *Query[Attraction]) *Query[Attraction] {
+			return q.Near(westLake, 10).MinRating(4.7).Offset(1).Limit(1)
+		}, []string{"lingyin"}},
+	}
+	for _, tt := range tests {
+		for kind, newQuery := range queries {
+			t.Run(tt.name+"/"+kind, func(t *testing.T) {
+				result := tt.build(newQuery()).Run()
+				if got := ids(result); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+				for _, hit := range result.Hits {
+					if want := CalculateDistance(westLake, hit.Item.Location); tt.name != "nothing in range" && hit.Distance != want {
+						t.Errorf("distance of %s = %v, want %v", hit.Item.ID, hit.Distance, want)
+					}
+				}
+			})
+		}
+	}
+}
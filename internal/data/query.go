@@ -1,13 +1,34 @@
 package data
 
 import (
-	"strings"
+	"sync"
 	"time"
 )
 
+// Dataset provides the points of interest and weather data. A DataLoader
+// reads the files on every call, a Repository serves them from memory.
+type Dataset interface {
+	LoadAttractions() ([]Attraction, error)
+	LoadRestaurants() ([]Restaurant, error)
+	LoadHotels() ([]Hotel, error)
+	LoadWeather() ([]Weather, error)
+	// LoadForecast returns an error matching os.ErrNotExist when there is
+	// no detailed forecast
+	LoadForecast() (*Forecast, error)
+	LoadDistricts() ([]District, error)
+}
+
 // DataQuery provides methods to query and filter data
 type DataQuery struct {
 	Loader *DataLoader
+	// dataset overrides Loader as the source of the data, see UseDataset
+	dataset Dataset
+
+	// Semantic search, see EnableSemanticSearch
+	embedder  Embedder
+	indexPath string
+	indexMu   sync.Mutex
+	index     *EmbeddingIndex
 }
 
 // NewDataQuery creates a new DataQuery instance
@@ -17,82 +38,77 @@ func NewDataQuery(loader *DataLoader) *DataQuery {
 	}
 }
 
-// FilterAttractionsByPreferences filters attractions based on user preferences
-func (q *DataQuery) FilterAttractionsByPreferences(attractions []Attraction, preferences []string) []Attraction {
-	if len(preferences) == 0 {
-		return attractions
-	}
+// UseDataset makes the query read its data from dataset, e.g. a Repository,
+// instead of Loader. Images are still read by Loader.
+func (q *DataQuery) UseDataset(dataset Dataset) {
+	q.dataset = dataset
+}
 
-	var filtered []Attraction
-	for _, attraction := range attractions {
-		for _, pref := range preferences {
-			prefLower := strings.ToLower(pref)
-			// Check if preference matches category or tags
-			for _, category := range attraction.Category {
-				if strings.Contains(strings.ToLower(category), prefLower) {
-					filtered = append(filtered, attraction)
-					goto nextAttraction
-				}
-			}
-			for _, tag := range attraction.Tags {
-				if strings.Contains(strings.ToLower(tag), prefLower) {
-					filtered = append(filtered, attraction)
-					goto nextAttraction
-				}
-			}
-		}
-	nextAttraction:
+// Dataset returns the source of the data: the dataset set with UseDataset,
+// or Loader
+func (q *DataQuery) Dataset() Dataset {
+	if q.dataset != nil {
+		return q.dataset
 	}
-	return filtered
+	return q.Loader
 }
 
-// FilterRestaurantsByPreferences filters restaurants based on cuisine preferences
-func (q *DataQuery) FilterRestaurantsByPreferences(restaurants []Restaurant, cuisinePrefs []string) []Restaurant {
-	if len(cuisinePrefs) == 0 {
-		return restaurants
-	}
+// LoadAttractions returns the attractions of the dataset
+func (q *DataQuery) LoadAttractions() ([]Attraction, error) {
+	return q.Dataset().LoadAttractions()
+}
 
-	var filtered []Restaurant
-	for _, restaurant := range restaurants {
-		for _, pref := range cuisinePrefs {
-			prefLower := strings.ToLower(pref)
-			for _, cuisine := range restaurant.Cuisine {
-				if strings.Contains(strings.ToLower(cuisine), prefLower) {
-					filtered = append(filtered, restaurant)
-					goto nextRestaurant
-				}
-			}
-		}
-	nextRestaurant:
-	}
-	return filtered
+// LoadRestaurants returns the restaurants of the dataset
+func (q *DataQuery) LoadRestaurants() ([]Restaurant, error) {
+	return q.Dataset().LoadRestaurants()
 }
 
-// FilterHotelsByPreferences filters hotels based on preferences and budget
-func (q *DataQuery) FilterHotelsByPreferences(hotels []Hotel, preferences []string, maxPrice float64) []Hotel {
-	var filtered []Hotel
-	for _, hotel := range hotels {
-		if hotel.PricePerNight > maxPrice {
-			continue
-		}
+// LoadHotels returns the hotels of the dataset
+func (q *DataQuery) LoadHotels() ([]Hotel, error) {
+	return q.Dataset().LoadHotels()
+}
 
-		if len(preferences) == 0 {
-			filtered = append(filtered, hotel)
-			continue
-		}
+// LoadWeather returns the weather data of the dataset
+func (q *DataQuery) LoadWeather() ([]Weather, error) {
+	return q.Dataset().LoadWeather()
+}
 
-		for _, pref := range preferences {
-			prefLower := strings.ToLower(pref)
-			for _, amenity := range hotel.Amenities {
-				if strings.Contains(strings.ToLower(amenity), prefLower) {
-					filtered = append(filtered, hotel)
-					goto nextHotel
-				}
-			}
-		}
-	nextHotel:
+// LoadForecast returns the detailed city forecast of the dataset. The error
+// matches os.ErrNotExist when there is none.
+func (q *DataQuery) LoadForecast() (*Forecast, error) {
+	return q.Dataset().LoadForecast()
+}
+
+// LoadDistricts returns the districts of the dataset
+func (q *DataQuery) LoadDistricts() ([]District, error) {
+	return q.Dataset().LoadDistricts()
+}
+
+// Attractions returns a query over the attractions of the dataset
+func (q *DataQuery) Attractions() (*Query[Attraction], error) {
+	index, err := q.AttractionIndex()
+	if err != nil {
+		return nil, err
+	}
+	return NewIndexedQuery(AttractionFields, index), nil
+}
+
+// Restaurants returns a query over the restaurants of the dataset
+func (q *DataQuery) Restaurants() (*Query[Restaurant], error) {
+	index, err := q.RestaurantIndex()
+	if err != nil {
+		return nil, err
+	}
+	return NewIndexedQuery(RestaurantFields, index), nil
+}
+
+// Hotels returns a query over the hotels of the dataset
+func (q *DataQuery) Hotels() (*Query[Hotel], error) {
+	index, err := q.HotelIndex()
+	if err != nil {
+		return nil, err
 	}
-	return filtered
+	return NewIndexedQuery(HotelFields, index), nil
 }
 
 // GetWeatherForDate gets weather information for a specific date and location
@@ -107,28 +123,3 @@ func (q *DataQuery) GetWeatherForDate(weather []Weather, date time.Time, locatio
 	}
 	return Weather{}, false
 }
-
-// FilterByBudget filters places by budget constraints
-func (q *DataQuery) FilterByBudget(attractions []Attraction, maxBudget float64) []Attraction {
-	var filtered []Attraction
-	for _, attraction := range attractions {
-		if attraction.Price <= maxBudget {
-			filtered = append(filtered, attraction)
-		}
-	}
-	return filtered
-}
-
-// SortByRating sorts places by their rating in descending order
-func (q *DataQuery) SortByRating(attractions []Attraction) []Attraction {
-	sorted := make([]Attraction, len(attractions))
-	copy(sorted, attractions)
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j].Rating > sorted[i].Rating {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-	return sorted
-}
@@ -0,0 +1,378 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReloadInterval is how often Watch polls the source for changes
+const DefaultReloadInterval = 5 * time.Second
+
+// Snapshot is an immutable copy of the dataset with its entities indexed by
+// ID. Its slices must not be modified; the Repository accessors return
+// copies.
+type Snapshot struct {
+	Attractions []Attraction
+	Restaurants []Restaurant
+	Hotels      []Hotel
+	Weather     []Weather
+	// Forecast is the detailed city forecast, nil without one
+	Forecast  *Forecast
+	Districts []District
+	// Version identifies the data the snapshot was loaded from
+	Version  string
+	LoadedAt time.Time
+
+	attractionsByID map[string]int
+	restaurantsByID map[string]int
+	hotelsByID      map[string]int
+
+	attractionIndex *SpatialIndex[Attraction]
+	restaurantIndex *SpatialIndex[Restaurant]
+	hotelIndex      *SpatialIndex[Hotel]
+}
+
+// NewSnapshot creates a snapshot of the given data, indexed by ID and by
+// location. When IDs repeat the first entity with an ID is indexed.
+func NewSnapshot(attractions []Attraction, restaurants []Restaurant, hotels []Hotel, weather []Weather) *Snapshot {
+	s := &Snapshot{
+		Attractions:     attractions,
+		Restaurants:     restaurants,
+		Hotels:          hotels,
+		Weather:         weather,
+		LoadedAt:        time.Now(),
+		attractionsByID: make(map[string]int, len(attractions)),
+		restaurantsByID: make(map[string]int, len(restaurants)),
+		hotelsByID:      make(map[string]int, len(hotels)),
+		attractionIndex: NewAttractionIndex(attractions),
+		restaurantIndex: NewRestaurantIndex(restaurants),
+		hotelIndex:      NewHotelIndex(hotels),
+	}
+	for i := len(attractions) - 1; i >= 0; i-- {
+		s.attractionsByID[attractions[i].ID] = i
+	}
+	for i := len(restaurants) - 1; i >= 0; i-- {
+		s.restaurantsByID[restaurants[i].ID] = i
+	}
+	for i := len(hotels) - 1; i >= 0; i-- {
+		s.hotelsByID[hotels[i].ID] = i
+	}
+	return s
+}
+
+// Attraction returns the attraction with the given ID
+func (s *Snapshot) Attraction(id string) (Attraction, bool) {
+	i, ok := s.attractionsByID[id]
+	if !ok {
+		return Attraction{}, false
+	}
+	return s.Attractions[i], true
+}
+
+// Restaurant returns the restaurant with the given ID
+func (s *Snapshot) Restaurant(id string) (Restaurant, bool) {
+	i, ok := s.restaurantsByID[id]
+	if !ok {
+		return Restaurant{}, false
+	}
+	return s.Restaurants[i], true
+}
+
+// Hotel returns the hotel with the given ID
+func (s *Snapshot) Hotel(id string) (Hotel, bool) {
+	i, ok := s.hotelsByID[id]
+	if !ok {
+		return Hotel{}, false
+	}
+	return s.Hotels[i], true
+}
+
+// AttractionIndex returns the spatial index of the attractions
+func (s *Snapshot) AttractionIndex() *SpatialIndex[Attraction] {
+	return s.attractionIndex
+}
+
+// RestaurantIndex returns the spatial index of the restaurants
+func (s *Snapshot) RestaurantIndex() *SpatialIndex[Restaurant] {
+	return s.restaurantIndex
+}
+
+// HotelIndex returns the spatial index of the hotels
+func (s *Snapshot) HotelIndex() *SpatialIndex[Hotel] {
+	return s.hotelIndex
+}
+
+// Source provides the snapshots of a Repository
+type Source interface {
+	// Load reads a complete snapshot
+	Load() (*Snapshot, error)
+	// Version changes whenever the data changes, e.g. with the modification
+	// times of the files
+	Version() (string, error)
+}
+
+// dataFiles are the files a DataLoader snapshot is read from
+var dataFiles = []string{"attractions.json", "restaurants.json", "hotels.json", "weather.json"}
+
+// optionalDataFiles are the detailed datasets a DataLoader snapshot is read
+// from when present
+var optionalDataFiles = []string{
+	"tourism/attractions.json", "tourism/restaurants.json", "tourism/hotels.json",
+	"weather/forecast.json", "geographic/districts.json",
+}
+
+// Load reads the attractions, restaurants, hotels and weather files, with
+// the detailed datasets present, into a snapshot, making DataLoader a Source
+func (d *DataLoader) Load() (*Snapshot, error) {
+	version, err := d.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	attractions, err := d.LoadAttractions()
+	if err != nil {
+		return nil, err
+	}
+	restaurants, err := d.LoadRestaurants()
+	if err != nil {
+		return nil, err
+	}
+	hotels, err := d.LoadHotels()
+	if err != nil {
+		return nil, err
+	}
+	weather, err := d.LoadWeather()
+	if err != nil {
+		return nil, err
+	}
+	forecast, err := d.LoadForecast()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	districts, err := d.LoadDistricts()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	snapshot := NewSnapshot(attractions, restaurants, hotels, weather)
+	snapshot.Forecast = forecast
+	snapshot.Districts = districts
+	snapshot.Version = version
+	return snapshot, nil
+}
+
+// Version returns the size and modification time of the data files, and
+// whether the optional ones are present
+func (d *DataLoader) Version() (string, error) {
+	var parts []string
+	for _, name := range dataFiles {
+		info, err := os.Stat(filepath.Join(d.BasePath, name))
+		if err != nil {
+			return "", fmt.Errorf("error reading file %s: %v", name, err)
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano()))
+	}
+	for _, name := range optionalDataFiles {
+		info, err := os.Stat(filepath.Join(d.BasePath, name))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			parts = append(parts, name+":-")
+		case err != nil:
+			return "", fmt.Errorf("error reading file %s: %v", name, err)
+		default:
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", name, info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// RepositoryOption configures a Repository
+type RepositoryOption func(*Repository)
+
+// WithReloadHandler sets a function called after every reload attempt made
+// by Refresh or Watch, with the new snapshot or the error that kept the
+// previous one
+func WithReloadHandler(handler func(*Snapshot, error)) RepositoryOption {
+	return func(r *Repository) {
+		r.onReload = handler
+	}
+}
+
+// Repository keeps the dataset in memory so that it is parsed once instead of
+// on every query. Reads are lock free and safe for concurrent use; a reload
+// swaps in a complete new snapshot, and a reload that fails keeps the last
+// good one.
+type Repository struct {
+	source   Source
+	snapshot atomic.Pointer[Snapshot]
+	onReload func(*Snapshot, error)
+
+	// mu serializes reloads
+	mu      sync.Mutex
+	lastErr error
+	// failedVersion is the source version that last failed to load
+	failedVersion string
+}
+
+// NewRepository loads the first snapshot from source
+func NewRepository(source Source, opts ...RepositoryOption) (*Repository, error) {
+	r := &Repository{source: source}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	snapshot, err := source.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading data: %v", err)
+	}
+	r.snapshot.Store(snapshot)
+	return r, nil
+}
+
+// Snapshot returns the current snapshot
+func (r *Repository) Snapshot() *Snapshot {
+	return r.snapshot.Load()
+}
+
+// LoadAttractions returns a copy of the current attractions
+func (r *Repository) LoadAttractions() ([]Attraction, error) {
+	return append([]Attraction(nil), r.Snapshot().Attractions...), nil
+}
+
+// LoadRestaurants returns a copy of the current restaurants
+func (r *Repository) LoadRestaurants() ([]Restaurant, error) {
+	return append([]Restaurant(nil), r.Snapshot().Restaurants...), nil
+}
+
+// LoadHotels returns a copy of the current hotels
+func (r *Repository) LoadHotels() ([]Hotel, error) {
+	return append([]Hotel(nil), r.Snapshot().Hotels...), nil
+}
+
+// LoadWeather returns a copy of the current weather data
+func (r *Repository) LoadWeather() ([]Weather, error) {
+	return append([]Weather(nil), r.Snapshot().Weather...), nil
+}
+
+// LoadForecast returns a copy of the current city forecast. The error
+// matches os.ErrNotExist when the data has none.
+func (r *Repository) LoadForecast() (*Forecast, error) {
+	forecast := r.Snapshot().Forecast
+	if forecast == nil {
+		return nil, fmt.Errorf("no forecast loaded: %w", os.ErrNotExist)
+	}
+	f := *forecast
+	f.DailyForecasts = append([]DailyForecast(nil), forecast.DailyForecasts...)
+	f.SpecialNotices = append([]Notice(nil), forecast.SpecialNotices...)
+	return &f, nil
+}
+
+// LoadDistricts returns a copy of the current districts
+func (r *Repository) LoadDistricts() ([]District, error) {
+	return append([]District(nil), r.Snapshot().Districts...), nil
+}
+
+// AttractionIndex returns the spatial index of the current attractions
+func (r *Repository) AttractionIndex() *SpatialIndex[Attraction] {
+	return r.Snapshot().AttractionIndex()
+}
+
+// RestaurantIndex returns the spatial index of the current restaurants
+func (r *Repository) RestaurantIndex() *SpatialIndex[Restaurant] {
+	return r.Snapshot().RestaurantIndex()
+}
+
+// HotelIndex returns the spatial index of the current hotels
+func (r *Repository) HotelIndex() *SpatialIndex[Hotel] {
+	return r.Snapshot().HotelIndex()
+}
+
+// Err returns the error of the last reload, nil once a reload succeeds
+func (r *Repository) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Reload loads a new snapshot from the source and swaps it in. On error the
+// current snapshot is kept.
+func (r *Repository) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload()
+}
+
+// Refresh reloads the snapshot when the source version changed and reports
+// whether it did
+func (r *Repository) Refresh() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, err := r.source.Version()
+	if err != nil {
+		// The files may be in the middle of being replaced
+		r.lastErr = fmt.Errorf("error checking data version: %v", err)
+		r.notify(nil, r.lastErr)
+		return false, r.lastErr
+	}
+	if version == r.Snapshot().Version {
+		return false, nil
+	}
+	if version == r.failedVersion {
+		// Wait for the next change instead of reporting the error again
+		return false, r.lastErr
+	}
+	if err := r.reload(); err != nil {
+		r.failedVersion = version
+		return false, err
+	}
+	return true, nil
+}
+
+// reload loads and swaps in a new snapshot; r.mu must be held
+func (r *Repository) reload() error {
+	snapshot, err := r.source.Load()
+	if err != nil {
+		r.lastErr = fmt.Errorf("error reloading data: %v", err)
+		r.notify(nil, r.lastErr)
+		return r.lastErr
+	}
+	r.snapshot.Store(snapshot)
+	r.lastErr = nil
+	r.notify(snapshot, nil)
+	return nil
+}
+
+// notify calls the reload handler, if any
+func (r *Repository) notify(snapshot *Snapshot, err error) {
+	if r.onReload != nil {
+		r.onReload(snapshot, err)
+	}
+}
+
+// Watch polls the source every interval and reloads the snapshot when it
+// changed, until ctx is done. Run it in its own goroutine. Reload errors are
+// reported to the reload handler and returned by Err.
+func (r *Repository) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh()
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// touch moves the modification time of the file name in dir forward so that
+// a rewrite is seen as a change even within the timestamp resolution
+func touch(t *testing.T, dir, name string, offset time.Duration) {
+	t.Helper()
+	at := time.Now().Add(offset)
+	if err := os.Chtimes(filepath.Join(dir, name), at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepositoryRefresh(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, flatFiles)
+
+	var reloads []error
+	repository, err := NewRepository(NewDataLoader(dir), WithReloadHandler(func(snapshot *Snapshot, err error) {
+		if (snapshot == nil) == (err == nil) {
+			t.Errorf("reload handler called with %v and %v, want one of them", snapshot, err)
+		}
+		reloads = append(reloads, err)
+	}))
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	first := repository.Snapshot()
+
+	// An unchanged tree is not reloaded
+	for i := 0; i < 2; i++ {
+		if changed, err := repository.Refresh(); changed || err != nil {
+			t.Fatalf("Refresh of unchanged data = %v, %v, want no reload", changed, err)
+		}
+	}
+	if repository.Snapshot() != first || len(reloads) != 0 {
+		t.Fatalf("unchanged data replaced the snapshot after %d reloads", len(reloads))
+	}
+
+	// A change is picked up
+	writeFiles(t, dir, map[string]string{"attractions.json": `[{"id": "lyy001", "name": "灵隐寺", "rating": 4.7}]`})
+	touch(t, dir, "attractions.json", time.Second)
+	if changed, err := repository.Refresh(); !changed || err != nil {
+		t.Fatalf("Refresh after a change = %v, %v, want a reload", changed, err)
+	}
+	second := repository.Snapshot()
+	if _, ok := second.Attraction("lyy001"); !ok || second == first {
+		t.Fatalf("attractions = %+v, want the new data", second.Attractions)
+	}
+
+	// A failed reload keeps the previous snapshot
+	writeFiles(t, dir, map[string]string{"attractions.json": `[{"id": "lyy001", "name": `})
+	touch(t, dir, "attractions.json", 2*time.Second)
+	if changed, err := repository.Refresh(); changed || err == nil {
+		t.Fatalf("Refresh of broken data = %v, %v, want an error", changed, err)
+	}
+	if repository.Snapshot() != second || repository.Err() == nil {
+		t.Errorf("broken data replaced the snapshot, Err = %v", repository.Err())
+	}
+	attractions, _ := repository.LoadAttractions()
+	if len(attractions) != 1 || attractions[0].ID != "lyy001" {
+		t.Errorf("attractions after a failed reload = %+v", attractions)
+	}
+
+	// The same broken data is not loaded again, but still reported
+	if changed, err := repository.Refresh(); changed || err == nil {
+		t.Errorf("second Refresh of broken data = %v, %v, want the error", changed, err)
+	}
+	if len(reloads) != 2 {
+		t.Errorf("%d reload attempts, want the broken data loaded once", len(reloads))
+	}
+
+	// A missing file fails the version check and keeps the snapshot too
+	if err := os.Rename(filepath.Join(dir, "hotels.json"), filepath.Join(dir, "hotels.bak")); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := repository.Refresh(); changed || err == nil || repository.Snapshot() != second {
+		t.Errorf("Refresh without hotels.json = %v, %v, want an error keeping the snapshot", changed, err)
+	}
+	if err := os.Rename(filepath.Join(dir, "hotels.bak"), filepath.Join(dir, "hotels.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fixing the data recovers
+	writeFiles(t, dir, map[string]string{"attractions.json": flatFiles["attractions.json"]})
+	touch(t, dir, "attractions.json", 3*time.Second)
+	if changed, err := repository.Refresh(); !changed || err != nil {
+		t.Fatalf("Refresh of fixed data = %v, %v, want a reload", changed, err)
+	}
+	if _, ok := repository.Snapshot().Attraction("wl001"); !ok || repository.Err() != nil {
+		t.Errorf("fixed data not loaded, Err = %v", repository.Err())
+	}
+}
+
+// versionedSource generates snapshots whose entities all carry the version
+// they were loaded with, and fails every load while failing is set
+type versionedSource struct {
+	version atomic.Int64
+	failing atomic.Bool
+}
+
+func (s *versionedSource) Version() (string, error) {
+	return fmt.Sprint(s.version.Load()), nil
+}
+
+func (s *versionedSource) Load() (*Snapshot, error) {
+	if s.failing.Load() {
+		return nil, errors.New("data being replaced")
+	}
+	version, _ := s.Version()
+	attractions := make([]Attraction, 20)
+	for i := range attractions {
+		attractions[i] = Attraction{
+			ID:       fmt.Sprintf("a%d", i),
+			Name:     version,
+			Location: Location{Latitude: 30.2 + float64(i)/100, Longitude: 120.1},
+		}
+	}
+	snapshot := NewSnapshot(attractions, nil, nil, nil)
+	snapshot.Version = version
+	return snapshot, nil
+}
+
+func TestRepositoryConcurrentReads(t *testing.T) {
+	source := &versionedSource{}
+	repository, err := NewRepository(source)
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Every read sees one complete snapshot
+				snapshot := repository.Snapshot()
+				for _, nearby := range snapshot.AttractionIndex().Nearest(Location{Latitude: 30.3, Longitude: 120.1}, 3) {
+					if nearby.Item.Name != snapshot.Version {
+						t.Errorf("snapshot %s has an attraction of version %s", snapshot.Version, nearby.Item.Name)
+						return
+					}
+				}
+				if a, ok := snapshot.Attraction("a5"); !ok || a.Name != snapshot.Version {
+					t.Errorf("snapshot %s found %+v", snapshot.Version, a)
+					return
+				}
+				attractions, _ := repository.LoadAttractions()
+				if len(attractions) != 20 {
+					t.Errorf("loaded %d attractions", len(attractions))
+					return
+				}
+				// The copy is the caller's to change
+				attractions[0].Name = "changed"
+			}
+		}()
+	}
+
+	for i := 1; i <= 50; i++ {
+		source.version.Store(int64(i))
+		source.failing.Store(i%5 == 0)
+		changed, err := repository.Refresh()
+		if i%5 == 0 && (changed || err == nil) {
+			t.Errorf("Refresh of failing version %d = %v, %v", i, changed, err)
+		}
+		if i%5 != 0 && (!changed || err != nil) {
+			t.Errorf("Refresh of version %d = %v, %v", i, changed, err)
+		}
+		if i%7 == 0 {
+			repository.Reload()
+		}
+	}
+	close(stop)
+	readers.Wait()
+
+	if got := repository.Snapshot().Version; got != "49" {
+		t.Errorf("final version = %s, want the last one that loaded", got)
+	}
+	for _, a := range repository.Snapshot().Attractions {
+		if a.Name != "49" {
+			t.Fatalf("a reader's change reached the snapshot: %+v", a)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"fmt"
+)
+
+// SemanticMatch is a point of interest ranked by SemanticSearch. Exactly one
+// of Attraction, Restaurant and Hotel is set, according to Kind.
+type SemanticMatch struct {
+	Kind       string
+	Score      float64
+	Attraction *Attraction
+	Restaurant *Restaurant
+	Hotel      *Hotel
+}
+
+// Name returns the name of the matched point of interest
+func (m SemanticMatch) Name() string {
+	switch {
+	case m.Attraction != nil:
+		return m.Attraction.Name
+	case m.Restaurant != nil:
+		return m.Restaurant.Name
+	case m.Hotel != nil:
+		return m.Hotel.Name
+	}
+	return ""
+}
+
+// EnableSemanticSearch configures the embedder used by SemanticSearch. When
+// indexPath is not empty the embeddings are persisted there, so only new or
+// edited points of interest are embedded on later runs.
+func (q *DataQuery) EnableSemanticSearch(embedder Embedder, indexPath string) {
+	q.indexMu.Lock()
+	defer q.indexMu.Unlock()
+
+	q.embedder = embedder
+	q.indexPath = indexPath
+	q.index = nil
+}
+
+// SemanticSearch ranks attractions, restaurants and hotels by the similarity
+// of their descriptions and tags to free-text preferences, so "观光" can
+// match "风景名胜" without sharing a word. kind is one of KindAttraction,
+// KindRestaurant and KindHotel, or empty for all; limit <= 0 returns all.
+func (q *DataQuery) SemanticSearch(ctx context.Context, preferences string, kind string, limit int) ([]SemanticMatch, error) {
+	q.indexMu.Lock()
+	defer q.indexMu.Unlock()
+
+	if q.embedder == nil {
+		return nil, fmt.Errorf("semantic search is not enabled")
+	}
+
+	attractions, err := q.LoadAttractions()
+	if err != nil {
+		return nil, err
+	}
+	restaurants, err := q.LoadRestaurants()
+	if err != nil {
+		return nil, err
+	}
+	hotels, err := q.LoadHotels()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.refreshIndex(ctx, attractions, restaurants, hotels); err != nil {
+		return nil, err
+	}
+
+	vectors, err := q.embedder.Embed(ctx, []string{preferences})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding preferences: %v", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 query", len(vectors))
+	}
+
+	attractionsByID := make(map[string]*Attraction, len(attractions))
+	for i := range attractions {
+		attractionsByID[attractions[i].ID] = &attractions[i]
+	}
+	restaurantsByID := make(map[string]*Restaurant, len(restaurants))
+	for i := range restaurants {
+		restaurantsByID[restaurants[i].ID] = &restaurants[i]
+	}
+	hotelsByID := make(map[string]*Hotel, len(hotels))
+	for i := range hotels {
+		hotelsByID[hotels[i].ID] = &hotels[i]
+	}
+
+	var results []SemanticMatch
+	for _, m := range q.index.Search(vectors[0], kind, limit) {
+		result := SemanticMatch{Kind: m.Entry.Kind, Score: m.Score}
+		switch m.Entry.Kind {
+		case KindAttraction:
+			result.Attraction = attractionsByID[m.Entry.ID]
+		case KindRestaurant:
+			result.Restaurant = restaurantsByID[m.Entry.ID]
+		case KindHotel:
+			result.Hotel = hotelsByID[m.Entry.ID]
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// refreshIndex loads the persisted index on first use and brings it up to
+// date with the data, saving it when it changed
+func (q *DataQuery) refreshIndex(ctx context.Context, attractions []Attraction, restaurants []Restaurant, hotels []Hotel) error {
+	if q.index == nil {
+		q.index = &EmbeddingIndex{}
+		if q.indexPath != "" {
+			index, err := LoadEmbeddingIndex(q.indexPath)
+			if err != nil {
+				return err
+			}
+			q.index = index
+		}
+	}
+
+	changed, err := q.index.Update(ctx, q.embedder, attractions, restaurants, hotels)
+	if err != nil {
+		return err
+	}
+	if changed && q.indexPath != "" {
+		return q.index.Save(q.indexPath)
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Nearby is an item found by a spatial query with its distance from the
+// query location
+type Nearby[T any] struct {
+	Item T `json:"item"`
+	// Distance is the great-circle distance in kilometers
+	Distance float64 `json:"distance_km"`
+}
+
+// DistanceText formats the distance for display, e.g. "500m" or "1.2km"
+func (n Nearby[T]) DistanceText() string {
+	return FormatDistance(n.Distance)
+}
+
+// Items returns the items of results without their distances
+func Items[T any](results []Nearby[T]) []T {
+	items := make([]T, len(results))
+	for i, r := range results {
+		items[i] = r.Item
+	}
+	return items
+}
+
+// FormatDistance formats a distance in kilometers, in meters rounded to 10m
+// below 1km, e.g. "500m" or "1.2km"
+func FormatDistance(km float64) string {
+	if meters := math.Round(km*100) * 10; meters < 1000 {
+		return fmt.Sprintf("%.0fm", meters)
+	}
+	return fmt.Sprintf("%.1fkm", km)
+}
+
+// BoundingBox is an area between two latitudes and two longitudes, in
+// degrees. A box crossing the antimeridian has MinLongitude > MaxLongitude.
+type BoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// Contains reports whether loc is inside the box, edges included
+func (b BoundingBox) Contains(loc Location) bool {
+	if loc.Latitude < b.MinLatitude || loc.Latitude > b.MaxLatitude {
+		return false
+	}
+	if b.MinLongitude <= b.MaxLongitude {
+		return loc.Longitude >= b.MinLongitude && loc.Longitude <= b.MaxLongitude
+	}
+	return loc.Longitude >= b.MinLongitude || loc.Longitude <= b.MaxLongitude
+}
+
+// Center returns the middle of the box
+func (b BoundingBox) Center() Location {
+	maxLon := b.MaxLongitude
+	if b.MinLongitude > maxLon {
+		maxLon += 360
+	}
+	lon := (b.MinLongitude + maxLon) / 2
+	if lon > 180 {
+		lon -= 360
+	}
+	return Location{Latitude: (b.MinLatitude + b.MaxLatitude) / 2, Longitude: lon}
+}
+
+// SpatialIndex is a k-d tree over the positions of items, answering radius,
+// nearest neighbour and bounding-box queries without scanning every item.
+// Positions are points on the unit sphere, so the straight-line distance
+// between them grows with the great-circle distance and the tree needs no
+// special case at the poles or the antimeridian. The index is immutable and
+// safe for concurrent use.
+type SpatialIndex[T any] struct {
+	items     []T
+	locations []Location
+	// points is the tree in implicit layout: the node of points[lo:hi] is the
+	// median points[(lo+hi)/2], split on axis depth%3
+	points []spatialPoint
+}
+
+// spatialPoint is the position of items[item] on the unit sphere
+type spatialPoint struct {
+	v    [3]float64
+	item int
+}
+
+// NewSpatialIndex indexes items at the positions returned by location
+func NewSpatialIndex[T any](items []T, location func(T) Location) *SpatialIndex[T] {
+	idx := &SpatialIndex[T]{
+		items:     items,
+		locations: make([]Location, len(items)),
+		points:    make([]spatialPoint, len(items)),
+	}
+	for i, item := range items {
+		idx.locations[i] = location(item)
+		idx.points[i] = spatialPoint{v: unitVector(idx.locations[i]), item: i}
+	}
+	idx.build(0, len(idx.points), 0)
+	return idx
+}
+
+// NewAttractionIndex indexes attractions by location
+func NewAttractionIndex(attractions []Attraction) *SpatialIndex[Attraction] {
+	return NewSpatialIndex(attractions, func(a Attraction) Location { return a.Location })
+}
+
+// NewRestaurantIndex indexes restaurants by location
+func NewRestaurantIndex(restaurants []Restaurant) *SpatialIndex[Restaurant] {
+	return NewSpatialIndex(restaurants, func(r Restaurant) Location { return r.Location })
+}
+
+// NewHotelIndex indexes hotels by location
+func NewHotelIndex(hotels []Hotel) *SpatialIndex[Hotel] {
+	return NewSpatialIndex(hotels, func(h Hotel) Location { return h.Location })
+}
+
+// SpatialDataset is a Dataset keeping spatial indexes of its points of
+// interest, e.g. a Repository
+type SpatialDataset interface {
+	Dataset
+	AttractionIndex() *SpatialIndex[Attraction]
+	RestaurantIndex() *SpatialIndex[Restaurant]
+	HotelIndex() *SpatialIndex[Hotel]
+}
+
+// AttractionIndex returns the spatial index of the attractions of the
+// dataset, built from the loaded attractions when the dataset keeps none
+func (q *DataQuery) AttractionIndex() (*SpatialIndex[Attraction], error) {
+	if dataset, ok := q.Dataset().(SpatialDataset); ok {
+		return dataset.AttractionIndex(), nil
+	}
+	attractions, err := q.LoadAttractions()
+	if err != nil {
+		return nil, err
+	}
+	return NewAttractionIndex(attractions), nil
+}
+
+// RestaurantIndex returns the spatial index of the restaurants of the
+// dataset, built from the loaded restaurants when the dataset keeps none
+func (q *DataQuery) RestaurantIndex() (*SpatialIndex[Restaurant], error) {
+	if dataset, ok := q.Dataset().(SpatialDataset); ok {
+		return dataset.RestaurantIndex(), nil
+	}
+	restaurants, err := q.LoadRestaurants()
+	if err != nil {
+		return nil, err
+	}
+	return NewRestaurantIndex(restaurants), nil
+}
+
+// HotelIndex returns the spatial index of the hotels of the dataset, built
+// from the loaded hotels when the dataset keeps none
+func (q *DataQuery) HotelIndex() (*SpatialIndex[Hotel], error) {
+	if dataset, ok := q.Dataset().(SpatialDataset); ok {
+		return dataset.HotelIndex(), nil
+	}
+	hotels, err := q.LoadHotels()
+	if err != nil {
+		return nil, err
+	}
+	return NewHotelIndex(hotels), nil
+}
+
+// Len returns the number of indexed items
+func (idx *SpatialIndex[T]) Len() int {
+	return len(idx.items)
+}
+
+// build arranges points[lo:hi] into a subtree split on axis depth%3
+func (idx *SpatialIndex[T]) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	axis := depth % 3
+	span := idx.points[lo:hi]
+	sort.Slice(span, func(i, j int) bool { return span[i].v[axis] < span[j].v[axis] })
+	mid := (lo + hi) / 2
+	idx.build(lo, mid, depth+1)
+	idx.build(mid+1, hi, depth+1)
+}
+
+// WithinRadius returns the items within radius kilometers of center, nearest
+// first
+func (idx *SpatialIndex[T]) WithinRadius(center Location, radius float64) []Nearby[T] {
+	if radius < 0 {
+		return nil
+	}
+	q := unitVector(center)
+	// A little slack for rounding; the exact distances are checked below
+	limit := chordLength(radius) + 1e-9
+	var found []int
+	idx.visit(0, len(idx.points), 0, func(p spatialPoint) {
+		if chordSquared(q, p.v) <= limit*limit {
+			found = append(found, p.item)
+		}
+	}, func(axis int, split float64) (bool, bool) {
+		diff := q[axis] - split
+		return diff <= limit, diff >= -limit
+	})
+
+	results := idx.measure(center, found)
+	n := 0
+	for _, r := range results {
+		if r.Distance <= radius {
+			results[n] = r
+			n++
+		}
+	}
+	return results[:n]
+}
+
+// Nearest returns the k items nearest to center, nearest first
+func (idx *SpatialIndex[T]) Nearest(center Location, k int) []Nearby[T] {
+	if k <= 0 || len(idx.points) == 0 {
+		return nil
+	}
+	q := unitVector(center)
+	// best holds the nearest points found so far, nearest first
+	type candidate struct {
+		item int
+		d2   float64
+	}
+	best := make([]candidate, 0, k)
+	worst := func() float64 {
+		if len(best) < k {
+			return math.Inf(1)
+		}
+		return best[len(best)-1].d2
+	}
+
+	var search func(lo, hi, depth int)
+	search = func(lo, hi, depth int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		p := idx.points[mid]
+		if d2 := chordSquared(q, p.v); d2 < worst() {
+			i := sort.Search(len(best), func(i int) bool { return best[i].d2 > d2 })
+			if len(best) < k {
+				best = append(best, candidate{})
+			}
+			copy(best[i+1:], best[i:])
+			best[i] = candidate{item: p.item, d2: d2}
+		}
+
+		// Descend into the side of the query first so that the other side
+		// can usually be pruned
+		axis := depth % 3
+		diff := q[axis] - p.v[axis]
+		near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+		if diff > 0 {
+			near, far = far, near
+		}
+		search(near[0], near[1], depth+1)
+		if diff*diff < worst() {
+			search(far[0], far[1], depth+1)
+		}
+	}
+	search(0, len(idx.points), 0)
+
+	found := make([]int, len(best))
+	for i, c := range best {
+		found[i] = c.item
+	}
+	return idx.measure(center, found)
+}
+
+// InBoundingBox returns the items inside box, ordered by their distance from
+// from, e.g. the user's position or box.Center()
+func (idx *SpatialIndex[T]) InBoundingBox(box BoundingBox, from Location) []Nearby[T] {
+	if box.MinLatitude > box.MaxLatitude {
+		return nil
+	}
+	lower, upper := box.bounds()
+	var found []int
+	idx.visit(0, len(idx.points), 0, func(p spatialPoint) {
+		for axis := range p.v {
+			if p.v[axis] < lower[axis] || p.v[axis] > upper[axis] {
+				return
+			}
+		}
+		found = append(found, p.item)
+	}, func(axis int, split float64) (bool, bool) {
+		return lower[axis] <= split, upper[axis] >= split
+	})
+
+	// The bounds of the box on the sphere are wider than the box itself
+	n := 0
+	for _, i := range found {
+		if box.Contains(idx.locations[i]) {
+			found[n] = i
+			n++
+		}
+	}
+	return idx.measure(from, found[:n])
+}
+
+// visit calls match for the nodes of points[lo:hi] that may match; descend
+// reports whether to search the subtrees below and above split on axis
+func (idx *SpatialIndex[T]) visit(lo, hi, depth int, match func(spatialPoint), descend func(axis int, split float64) (bool, bool)) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	p := idx.points[mid]
+	match(p)
+	below, above := descend(depth%3, p.v[depth%3])
+	if below {
+		idx.visit(lo, mid, depth+1, match, descend)
+	}
+	if above {
+		idx.visit(mid+1, hi, depth+1, match, descend)
+	}
+}
+
+// measure returns the items with their haversine distance from center,
+// nearest first; equal distances keep the order of the indexed items
+func (idx *SpatialIndex[T]) measure(center Location, found []int) []Nearby[T] {
+	sort.Ints(found)
+	results := make([]Nearby[T], len(found))
+	for i, item := range found {
+		results[i] = Nearby[T]{Item: idx.items[item], Distance: CalculateDistance(center, idx.locations[item])}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	return results
+}
+
+// unitVector returns the position of loc on the unit sphere
+func unitVector(loc Location) [3]float64 {
+	lat, lon := toRadians(loc.Latitude), toRadians(loc.Longitude)
+	return [3]float64{
+		math.Cos(lat) * math.Cos(lon),
+		math.Cos(lat) * math.Sin(lon),
+		math.Sin(lat),
+	}
+}
+
+// chordLength returns the straight-line distance between two points of the
+// unit sphere km apart on the surface of Earth
+func chordLength(km float64) float64 {
+	angle := math.Min(km/earthRadius, math.Pi)
+	return 2 * math.Sin(angle/2)
+}
+
+// chordSquared returns the squared straight-line distance between a and b
+func chordSquared(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// bounds returns the smallest box around the unit-sphere points of b.
+// Latitudes beyond the poles are clamped, as the sine of the latitude would
+// shrink past them.
+func (b BoundingBox) bounds() (lower, upper [3]float64) {
+	lat1, lat2 := toRadians(math.Max(b.MinLatitude, -90)), toRadians(math.Min(b.MaxLatitude, 90))
+	lon1, lon2 := toRadians(b.MinLongitude), toRadians(b.MaxLongitude)
+	if lon1 > lon2 {
+		lon2 += 2 * math.Pi
+	}
+
+	cosLat := trigRange(math.Cos, lat1, lat2, 0, math.Pi)
+	cosLon := trigRange(math.Cos, lon1, lon2, 0, math.Pi)
+	sinLon := trigRange(math.Sin, lon1, lon2, math.Pi/2, -math.Pi/2)
+	lower[0], upper[0] = productRange(cosLat, cosLon)
+	lower[1], upper[1] = productRange(cosLat, sinLon)
+	lower[2], upper[2] = math.Sin(lat1), math.Sin(lat2)
+	// Keep the points on the edges despite rounding
+	for axis := range lower {
+		lower[axis] -= 1e-12
+		upper[axis] += 1e-12
+	}
+	return lower, upper
+}
+
+// trigRange returns the range of f over [lo, hi], for a periodic f with its
+// maximum at maxAt and minimum at minAt
+func trigRange(f func(float64) float64, lo, hi, maxAt, minAt float64) [2]float64 {
+	r := [2]float64{math.Min(f(lo), f(hi)), math.Max(f(lo), f(hi))}
+	if containsAngle(lo, hi, minAt) {
+		r[0] = f(minAt)
+	}
+	if containsAngle(lo, hi, maxAt) {
+		r[1] = f(maxAt)
+	}
+	return r
+}
+
+// containsAngle reports whether [lo, hi] contains angle plus a multiple of 2π
+func containsAngle(lo, hi, angle float64) bool {
+	k := math.Ceil((lo - angle) / (2 * math.Pi))
+	return angle+2*math.Pi*k <= hi
+}
+
+// productRange returns the range of x*y for x in a and y in b
+func productRange(a, b [2]float64) (float64, float64) {
+	products := []float64{a[0] * b[0], a[0] * b[1], a[1] * b[0], a[1] * b[1]}
+	lo, hi := products[0], products[0]
+	for _, p := range products[1:] {
+		lo, hi = math.Min(lo, p), math.Max(hi, p)
+	}
+	return lo, hi
+}
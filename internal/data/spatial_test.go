@@ -0,0 +1,188 @@
+package data
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomLocations returns n locations spread over the globe, around
+// Hangzhou, near the poles and on both sides of the antimeridian, with a
+// few duplicates
+func randomLocations(rng *rand.Rand, n int) []Location {
+	locations := make([]Location, 0, n)
+	for len(locations) < n {
+		var loc Location
+		switch rng.Intn(5) {
+		case 0:
+			loc = Location{Latitude: rng.Float64()*180 - 90, Longitude: rng.Float64()*360 - 180}
+		case 1:
+			loc = Location{Latitude: 30.25 + rng.Float64()*0.2 - 0.1, Longitude: 120.15 + rng.Float64()*0.2 - 0.1}
+		case 2:
+			loc = Location{Latitude: 85 + rng.Float64()*5, Longitude: rng.Float64()*360 - 180}
+		case 3:
+			loc = Location{Latitude: rng.Float64()*20 - 10, Longitude: 178 + rng.Float64()*4}
+			if loc.Longitude > 180 {
+				loc.Longitude -= 360
+			}
+		default:
+			if len(locations) > 0 {
+				loc = locations[rng.Intn(len(locations))]
+			}
+		}
+		locations = append(locations, loc)
+	}
+	return locations
+}
+
+// scan returns the items of locations accepted by keep, measured from
+// center, nearest first, by a linear scan
+func scan(locations []Location, center Location, keep func(Location) bool) []Nearby[int] {
+	var results []Nearby[int]
+	for i, loc := range locations {
+		if keep(loc) {
+			results = append(results, Nearby[int]{Item: i, Distance: CalculateDistance(center, loc)})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	return results
+}
+
+// checkSameResults fails unless got and want find the same items at the
+// same distances, nearest first
+func checkSameResults(t *testing.T, query string, got, want []Nearby[int]) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d results, want %d", query, len(got), len(want))
+	}
+	gotItems, wantItems := make([]int, len(got)), make([]int, len(want))
+	for i := range got {
+		if math.Abs(got[i].Distance-want[i].Distance) > 1e-9 {
+			t.Fatalf("%s: result %d at %vkm, want %vkm", query, i, got[i].Distance, want[i].Distance)
+		}
+		gotItems[i], wantItems[i] = got[i].Item, want[i].Item
+	}
+	sort.Ints(gotItems)
+	sort.Ints(wantItems)
+	for i := range gotItems {
+		if gotItems[i] != wantItems[i] {
+			t.Fatalf("%s: found items %v, want %v", query, gotItems, wantItems)
+		}
+	}
+}
+
+// newIntIndex indexes the positions of locations
+func newIntIndex(locations []Location) *SpatialIndex[int] {
+	items := make([]int, len(locations))
+	for i := range items {
+		items[i] = i
+	}
+	return NewSpatialIndex(items, func(i int) Location { return locations[i] })
+}
+
+func TestSpatialIndexMatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	locations := randomLocations(rng, 2000)
+	idx := newIntIndex(locations)
+	if idx.Len() != len(locations) {
+		t.Fatalf("Len = %d, want %d", idx.Len(), len(locations))
+	}
+
+	for i := 0; i < 200; i++ {
+		center := randomLocations(rng, 1)[0]
+		if i%4 == 0 {
+			center = locations[rng.Intn(len(locations))]
+		}
+
+		radius := math.Pow(10, rng.Float64()*5-1) // 0.1km to 10000km
+		checkSameResults(t, "WithinRadius", idx.WithinRadius(center, radius),
+			scan(locations, center, func(loc Location) bool { return CalculateDistance(center, loc) <= radius }))
+
+		k := 1 + rng.Intn(20)
+		want := scan(locations, center, func(Location) bool { return true })[:k]
+		got := idx.Nearest(center, k)
+		if len(got) != k {
+			t.Fatalf("Nearest: got %d results, want %d", len(got), k)
+		}
+		// Items at equal distances may be exchanged at the k-th rank, so only
+		// the distances are compared
+		for j := range got {
+			if math.Abs(got[j].Distance-want[j].Distance) > 1e-9 {
+				t.Fatalf("Nearest: result %d at %vkm, want %vkm", j, got[j].Distance, want[j].Distance)
+			}
+		}
+
+		lat1, lat2 := rng.Float64()*180-90, rng.Float64()*180-90
+		box := BoundingBox{
+			MinLatitude:  math.Min(lat1, lat2),
+			MaxLatitude:  math.Max(lat1, lat2),
+			MinLongitude: rng.Float64()*360 - 180,
+			MaxLongitude: rng.Float64()*360 - 180,
+		}
+		if i%2 == 0 {
+			// Small boxes, some crossing the antimeridian or a pole
+			box.MinLatitude, box.MaxLatitude = center.Latitude-rng.Float64()*10, center.Latitude+rng.Float64()*10
+			box.MinLongitude = math.Mod(center.Longitude+540-rng.Float64()*10, 360) - 180
+			box.MaxLongitude = math.Mod(center.Longitude+540+rng.Float64()*10, 360) - 180
+		}
+		checkSameResults(t, "InBoundingBox", idx.InBoundingBox(box, center), scan(locations, center, box.Contains))
+	}
+}
+
+func TestSpatialIndexEdgeCases(t *testing.T) {
+	hangzhou := Location{Latitude: 30.2741, Longitude: 120.1551}
+
+	empty := newIntIndex(nil)
+	if empty.Len() != 0 || len(empty.WithinRadius(hangzhou, 100)) != 0 || len(empty.Nearest(hangzhou, 3)) != 0 ||
+		len(empty.InBoundingBox(BoundingBox{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}, hangzhou)) != 0 {
+		t.Error("an empty index found items")
+	}
+
+	locations := []Location{
+		{Latitude: 30.2590, Longitude: 120.1300}, // 西湖
+		hangzhou,
+		{Latitude: 30.2410, Longitude: 120.0980}, // 灵隐寺
+		hangzhou,
+		{Latitude: -17.7134, Longitude: 178.0650},  // Fiji, east of the antimeridian
+		{Latitude: -14.2710, Longitude: -170.1322}, // American Samoa, west of it
+		{Latitude: 89.5, Longitude: 10},
+	}
+	idx := newIntIndex(locations)
+
+	// k larger than the index returns every item, nearest first
+	nearest := idx.Nearest(hangzhou, 10)
+	checkSameResults(t, "Nearest", nearest, scan(locations, hangzhou, func(Location) bool { return true }))
+	if len(idx.Nearest(hangzhou, 0)) != 0 || len(idx.Nearest(hangzhou, -1)) != 0 {
+		t.Error("Nearest with k <= 0 found items")
+	}
+
+	// A zero radius finds the items at the center only
+	if found := Items(idx.WithinRadius(hangzhou, 0)); len(found) != 2 || found[0] != 1 || found[1] != 3 {
+		t.Errorf("WithinRadius 0 = %v, want [1 3]", found)
+	}
+	if found := idx.WithinRadius(hangzhou, -1); len(found) != 0 {
+		t.Errorf("WithinRadius -1 = %v, want none", found)
+	}
+
+	// A box with MinLongitude > MaxLongitude crosses the antimeridian
+	pacific := BoundingBox{MinLatitude: -20, MaxLatitude: -10, MinLongitude: 175, MaxLongitude: -165}
+	if found := Items(idx.InBoundingBox(pacific, pacific.Center())); len(found) != 2 || found[0]+found[1] != 9 {
+		t.Errorf("antimeridian box = %v, want items 4 and 5", found)
+	}
+	if center := pacific.Center(); center.Latitude != -15 || center.Longitude != -175 {
+		t.Errorf("antimeridian box center = %+v", center)
+	}
+	// The same longitudes in the usual order span the rest of the globe
+	rest := BoundingBox{MinLatitude: -20, MaxLatitude: 40, MinLongitude: -165, MaxLongitude: 175}
+	if found := Items(idx.InBoundingBox(rest, hangzhou)); len(found) != 4 {
+		t.Errorf("box without the antimeridian = %v, want the Hangzhou items", found)
+	}
+	// Latitudes past the pole do not narrow the box
+	if found := Items(idx.InBoundingBox(BoundingBox{MinLatitude: 85, MaxLatitude: 95, MinLongitude: 0, MaxLongitude: 20}, hangzhou)); len(found) != 1 || found[0] != 6 {
+		t.Errorf("box past the pole = %v, want [6]", found)
+	}
+	if found := idx.InBoundingBox(BoundingBox{MinLatitude: 40, MaxLatitude: 20, MinLongitude: 100, MaxLongitude: 130}, hangzhou); len(found) != 0 {
+		t.Errorf("box with MinLatitude > MaxLatitude = %v, want none", found)
+	}
+}
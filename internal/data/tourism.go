@@ -0,0 +1,425 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Coordinates is a position in the rich datasets
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Location returns the coordinates as a Location named name
+func (c Coordinates) Location(name string) Location {
+	return Location{Latitude: c.Latitude, Longitude: c.Longitude, Name: name}
+}
+
+// TimeRange is a span of the day in "15:04" format
+type TimeRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// OpeningHours are the daily opening hours, with an optional break
+type OpeningHours struct {
+	Start     string     `json:"start"`
+	End       string     `json:"end"`
+	BreakTime *TimeRange `json:"break_time,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+}
+
+// Ranges returns the opening hours as "start-end" ranges, split around the
+// break, in the format of Attraction.OpenHours
+func (h OpeningHours) Ranges() []string {
+	if h.Start == "" || h.End == "" {
+		return nil
+	}
+	if h.BreakTime == nil {
+		return []string{h.Start + "-" + h.End}
+	}
+	return []string{h.Start + "-" + h.BreakTime.Start, h.BreakTime.End + "-" + h.End}
+}
+
+// Price is an entrance fee
+type Price struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Notes    string  `json:"notes,omitempty"`
+}
+
+// PriceRange is a range of prices, per person for restaurants and per night
+// for hotels
+type PriceRange struct {
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Currency string  `json:"currency"`
+	Level    string  `json:"level,omitempty"`
+	Notes    string  `json:"notes,omitempty"`
+}
+
+// Contact holds the contact details of a restaurant or hotel
+type Contact struct {
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+// RecommendedTime is how long to stay at an attraction and when to go
+type RecommendedTime struct {
+	Hours     float64  `json:"hours"`
+	BestTimes []string `json:"best_times"`
+}
+
+// CrowdLevel describes how crowded an attraction is during the day
+type CrowdLevel struct {
+	Morning   string `json:"morning"`
+	Afternoon string `json:"afternoon"`
+	Evening   string `json:"evening"`
+}
+
+// AttractionDetails is an attraction of data/tourism/attractions.json
+type AttractionDetails struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	DistrictID      string          `json:"district_id"`
+	Description     string          `json:"description"`
+	Coordinates     Coordinates     `json:"coordinates"`
+	Price           Price           `json:"price"`
+	OpeningHours    OpeningHours    `json:"opening_hours"`
+	RecommendedTime RecommendedTime `json:"recommended_time"`
+	Highlights      []string        `json:"highlights"`
+	Tags            []string        `json:"tags"`
+	CrowdLevel      CrowdLevel      `json:"crowd_level"`
+}
+
+// RestaurantDetails is a restaurant of data/tourism/restaurants.json
+type RestaurantDetails struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	DistrictID           string       `json:"district_id"`
+	Description          string       `json:"description"`
+	Coordinates          Coordinates  `json:"coordinates"`
+	CuisineType          string       `json:"cuisine_type"`
+	PriceRange           PriceRange   `json:"price_range"`
+	OpeningHours         OpeningHours `json:"opening_hours"`
+	SignatureDishes      []string     `json:"signature_dishes"`
+	Features             []string     `json:"features"`
+	ReservationsRequired bool         `json:"reservations_required"`
+	Contact              Contact      `json:"contact"`
+}
+
+// Room is a room type of a hotel
+type Room struct {
+	Type     string   `json:"type"`
+	SizeSqm  float64  `json:"size_sqm"`
+	Price    float64  `json:"price"`
+	Features []string `json:"features"`
+}
+
+// HotelTransportation describes how to reach a hotel
+type HotelTransportation struct {
+	FromAirport struct {
+		TaxiTime   string  `json:"taxi_time"`
+		DistanceKm float64 `json:"distance_km"`
+	} `json:"from_airport"`
+	NearbyStations []string `json:"nearby_stations"`
+}
+
+// HotelDetails is a hotel of data/tourism/hotels.json
+type HotelDetails struct {
+	ID             string              `json:"id"`
+	Name           string              `json:"name"`
+	DistrictID     string              `json:"district_id"`
+	Description    string              `json:"description"`
+	Category       string              `json:"category"` // e.g. 五星级 or 精品酒店
+	Coordinates    Coordinates         `json:"coordinates"`
+	PriceRange     PriceRange          `json:"price_range"`
+	Rooms          []Room              `json:"rooms"`
+	Amenities      []string            `json:"amenities"`
+	Transportation HotelTransportation `json:"transportation"`
+	Contact        Contact             `json:"contact"`
+}
+
+// LoadAttractionDetails loads data/tourism/attractions.json
+func (d *DataLoader) LoadAttractionDetails() ([]AttractionDetails, error) {
+	var file struct {
+		Attractions []AttractionDetails `json:"attractions"`
+	}
+	err := d.loadJSON("tourism/attractions.json", &file)
+	return file.Attractions, err
+}
+
+// LoadRestaurantDetails loads data/tourism/restaurants.json
+func (d *DataLoader) LoadRestaurantDetails() ([]RestaurantDetails, error) {
+	var file struct {
+		Restaurants []RestaurantDetails `json:"restaurants"`
+	}
+	err := d.loadJSON("tourism/restaurants.json", &file)
+	return file.Restaurants, err
+}
+
+// LoadHotelDetails loads data/tourism/hotels.json
+func (d *DataLoader) LoadHotelDetails() ([]HotelDetails, error) {
+	var file struct {
+		Hotels []HotelDetails `json:"hotels"`
+	}
+	err := d.loadJSON("tourism/hotels.json", &file)
+	return file.Hotels, err
+}
+
+// ToAttraction maps the attraction onto Attraction, naming its district from
+// districts. The tags become the categories and, with the highlights and
+// best visiting times, the tags.
+func (a AttractionDetails) ToAttraction(districts map[string]District) Attraction {
+	description := a.Description
+	if a.Price.Notes != "" {
+		description += "。门票：" + a.Price.Notes
+	}
+
+	attraction := Attraction{
+		ID:          a.ID,
+		Name:        a.Name,
+		Location:    a.Coordinates.Location(a.Name),
+		Description: description,
+		Category:    a.Tags,
+		Price:       a.Price.Amount,
+		OpenHours:   a.OpeningHours.Ranges(),
+		Tags:        concat(a.Tags, a.Highlights, a.RecommendedTime.BestTimes),
+		District:    districts[a.DistrictID].Name,
+		Highlights:  a.Highlights,
+		BestTimes:   a.RecommendedTime.BestTimes,
+		VisitHours:  a.RecommendedTime.Hours,
+	}
+	if a.CrowdLevel != (CrowdLevel{}) {
+		crowd := a.CrowdLevel.normalize()
+		attraction.CrowdLevel = &crowd
+	}
+	return attraction
+}
+
+// String describes the crowd levels, e.g. "上午中等，下午较拥挤，晚上适中"
+func (c CrowdLevel) String() string {
+	c = c.normalize()
+	var parts []string
+	for _, level := range []struct{ period, level string }{
+		{"上午", c.Morning},
+		{"下午", c.Afternoon},
+		{"晚上", c.Evening},
+	} {
+		if level.level != "" {
+			parts = append(parts, level.period+level.level)
+		}
+	}
+	return strings.Join(parts, "，")
+}
+
+// normalize translates the English some entries mix in, e.g. "较crowded"
+func (c CrowdLevel) normalize() CrowdLevel {
+	fix := strings.NewReplacer("crowded", "拥挤")
+	return CrowdLevel{
+		Morning:   fix.Replace(c.Morning),
+		Afternoon: fix.Replace(c.Afternoon),
+		Evening:   fix.Replace(c.Evening),
+	}
+}
+
+// ToRestaurant maps the restaurant onto Restaurant, naming its district from
+// districts. The price range becomes a $ level from the maximum price per
+// person, and the signature dishes and features become tags.
+func (r RestaurantDetails) ToRestaurant(districts map[string]District) Restaurant {
+	description := r.Description
+	if r.Contact.Address != "" {
+		description += "。地址：" + r.Contact.Address
+	}
+
+	var cuisine []string
+	if r.CuisineType != "" {
+		cuisine = []string{r.CuisineType}
+	}
+	return Restaurant{
+		ID:                   r.ID,
+		Name:                 r.Name,
+		Location:             r.Coordinates.Location(r.Name),
+		Cuisine:              cuisine,
+		PriceRange:           priceLevel(r.PriceRange.Max),
+		OpenHours:            r.OpeningHours.Ranges(),
+		Description:          description,
+		Tags:                 concat(r.Features, r.SignatureDishes),
+		District:             districts[r.DistrictID].Name,
+		SignatureDishes:      r.SignatureDishes,
+		ReservationsRequired: r.ReservationsRequired,
+	}
+}
+
+// priceLevel converts a price per person in CNY to the $ levels of
+// Restaurant.PriceRange
+func priceLevel(price float64) string {
+	switch {
+	case price <= 0:
+		return ""
+	case price <= 100:
+		return "$"
+	case price <= 200:
+		return "$$"
+	case price <= 300:
+		return "$$$"
+	default:
+		return "$$$$"
+	}
+}
+
+// ToHotel maps the hotel onto Hotel, naming its district from districts.
+// The price per night is that of the cheapest room, and the rooms are added
+// to the description.
+func (h HotelDetails) ToHotel(districts map[string]District) Hotel {
+	description := h.Description
+	if h.Category != "" {
+		description = h.Category + "，" + description
+	}
+	for _, room := range h.Rooms {
+		description += fmt.Sprintf("。%s %g㎡ %g元（%s）", room.Type, room.SizeSqm, room.Price, strings.Join(room.Features, "、"))
+	}
+
+	return Hotel{
+		ID:             h.ID,
+		Name:           h.Name,
+		Location:       h.Coordinates.Location(h.Name),
+		Stars:          hotelStars(h.Category),
+		PricePerNight:  h.MinPrice(),
+		Amenities:      h.Amenities,
+		Description:    description,
+		District:       districts[h.DistrictID].Name,
+		NearbyStations: h.Transportation.NearbyStations,
+	}
+}
+
+// MinPrice returns the price of the cheapest room, or the minimum of the
+// price range when no room is listed
+func (h HotelDetails) MinPrice() float64 {
+	price := 0.0
+	for _, room := range h.Rooms {
+		if room.Price > 0 && (price == 0 || room.Price < price) {
+			price = room.Price
+		}
+	}
+	if price == 0 {
+		price = h.PriceRange.Min
+	}
+	return price
+}
+
+// hotelStars reads the star rating of a category such as "五星级"; other
+// categories, e.g. "精品酒店", have no stars
+func hotelStars(category string) int {
+	for i, numeral := range []string{"一", "二", "三", "四", "五"} {
+		if strings.HasPrefix(category, numeral+"星") {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// concat joins string lists into a new list
+func concat(lists ...[]string) []string {
+	var out []string
+	for _, list := range lists {
+		out = append(out, list...)
+	}
+	return out
+}
+
+// AttractionsFromDetails maps attractions onto Attraction
+func AttractionsFromDetails(details []AttractionDetails, districts map[string]District) []Attraction {
+	attractions := make([]Attraction, len(details))
+	for i, a := range details {
+		attractions[i] = a.ToAttraction(districts)
+	}
+	return attractions
+}
+
+// RestaurantsFromDetails maps restaurants onto Restaurant
+func RestaurantsFromDetails(details []RestaurantDetails, districts map[string]District) []Restaurant {
+	restaurants := make([]Restaurant, len(details))
+	for i, r := range details {
+		restaurants[i] = r.ToRestaurant(districts)
+	}
+	return restaurants
+}
+
+// HotelsFromDetails maps hotels onto Hotel
+func HotelsFromDetails(details []HotelDetails, districts map[string]District) []Hotel {
+	hotels := make([]Hotel, len(details))
+	for i, h := range details {
+		hotels[i] = h.ToHotel(districts)
+	}
+	return hotels
+}
+
+// mergeByName enriches each item of flat with the detailed item of the same
+// name and appends the detailed items without one. The datasets use
+// different IDs, so names are the only common key.
+func mergeByName[T any](flat, detailed []T, name func(T) string, enrich func(*T, T)) []T {
+	byName := make(map[string]int, len(flat))
+	for i := len(flat) - 1; i >= 0; i-- {
+		byName[name(flat[i])] = i
+	}
+
+	merged := append([]T(nil), flat...)
+	for _, item := range detailed {
+		if i, ok := byName[name(item)]; ok {
+			enrich(&merged[i], item)
+			continue
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// enrich copies the detailed fields of a onto the attraction and adds its tags
+func (attraction *Attraction) enrich(a Attraction) {
+	attraction.District = a.District
+	attraction.Highlights = a.Highlights
+	attraction.BestTimes = a.BestTimes
+	attraction.VisitHours = a.VisitHours
+	attraction.CrowdLevel = a.CrowdLevel
+	attraction.Tags = union(attraction.Tags, a.Tags)
+	if len(attraction.OpenHours) == 0 {
+		attraction.OpenHours = a.OpenHours
+	}
+}
+
+// enrich copies the detailed fields of r onto the restaurant and adds its tags
+func (restaurant *Restaurant) enrich(r Restaurant) {
+	restaurant.District = r.District
+	restaurant.SignatureDishes = r.SignatureDishes
+	restaurant.ReservationsRequired = r.ReservationsRequired
+	restaurant.Tags = union(restaurant.Tags, r.Tags)
+	if len(restaurant.OpenHours) == 0 {
+		restaurant.OpenHours = r.OpenHours
+	}
+}
+
+// enrich copies the detailed fields of h onto the hotel and adds its amenities
+func (hotel *Hotel) enrich(h Hotel) {
+	hotel.District = h.District
+	hotel.NearbyStations = h.NearbyStations
+	hotel.Amenities = union(hotel.Amenities, h.Amenities)
+}
+
+// union returns the values of a followed by those of b missing from a
+func union(a, b []string) []string {
+	out := append([]string(nil), a...)
+	seen := make(map[string]bool, len(a))
+	for _, v := range a {
+		seen[v] = true
+	}
+	for _, v := range b {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
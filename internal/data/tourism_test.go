@@ -0,0 +1,170 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFiles writes the files, by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// flatFiles are the files every DataLoader needs
+var flatFiles = map[string]string{
+	"attractions.json": `[{"id": "wl001", "name": "西湖", "category": ["自然风光"], "tags": ["风景名胜"], "open_hours": ["00:00-24:00"], "rating": 4.8}]`,
+	"restaurants.json": `[{"id": "lw001", "name": "楼外楼", "cuisine": ["杭帮菜"], "price_range": "$$$", "rating": 4.5}]`,
+	"hotels.json":      `[{"id": "fs001", "name": "杭州西子湖四季酒店", "stars": 5, "price_per_night": 3000, "amenities": ["游泳池"]}]`,
+	"weather.json":     `[]`,
+}
+
+// detailedFiles are the optional detailed datasets
+var detailedFiles = map[string]string{
+	"tourism/attractions.json": `{"attractions": [
+		{"id": "XH001", "name": "西湖", "district_id": "XH", "tags": ["风景名胜", "自然景观"], "highlights": ["断桥残雪"],
+		 "recommended_time": {"hours": 4, "best_times": ["日出时分"]},
+		 "crowd_level": {"morning": "中等", "afternoon": "较crowded"}},
+		{"id": "SC001", "name": "河坊街", "district_id": "SC", "tags": ["历史街区"], "price": {"amount": 0},
+		 "opening_hours": {"start": "09:00", "end": "22:00"}}
+	]}`,
+	"tourism/restaurants.json": `{"restaurants": [
+		{"id": "REST001", "name": "楼外楼", "district_id": "XH", "cuisine_type": "杭帮菜", "price_range": {"max": 300},
+		 "signature_dishes": ["西湖醋鱼"], "features": ["湖景"], "reservations_required": true}
+	]}`,
+	"tourism/hotels.json": `{"hotels": [
+		{"id": "HOTEL001", "name": "杭州西子湖四季酒店", "district_id": "XH", "category": "五星级", "amenities": ["游泳池", "SPA"],
+		 "rooms": [{"type": "豪华客房", "price": 2800}], "transportation": {"nearby_stations": ["龙翔桥地铁站"]}}
+	]}`,
+	"weather/forecast.json": `{"city": "杭州", "daily_forecasts": [{"date": "2025-02-18", "weather": {"day": "晴", "night": "多云"}}],
+		"special_notices": [{"type": "旅游建议", "content": "注意防晒"}]}`,
+	"geographic/districts.json": `{"districts": [{"id": "XH", "name": "西湖区"}, {"id": "SC", "name": "上城区"}]}`,
+}
+
+func TestLoadEnrichesFlatData(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, flatFiles)
+	writeFiles(t, dir, detailedFiles)
+	loader := NewDataLoader(dir)
+
+	attractions, err := loader.LoadAttractions()
+	if err != nil {
+		t.Fatalf("LoadAttractions: %v", err)
+	}
+	if len(attractions) != 2 {
+		t.Fatalf("got %d attractions, want the flat one and the detailed-only one", len(attractions))
+	}
+	lake := attractions[0]
+	if lake.ID != "wl001" || lake.Rating != 4.8 || lake.District != "西湖区" || lake.VisitHours != 4 {
+		t.Errorf("enriched attraction = %+v", lake)
+	}
+	if want := []string{"风景名胜", "自然景观", "断桥残雪", "日出时分"}; !reflect.DeepEqual(lake.Tags, want) {
+		t.Errorf("tags = %v, want %v", lake.Tags, want)
+	}
+	if want := (&CrowdLevel{Morning: "中等", Afternoon: "较拥挤"}); !reflect.DeepEqual(lake.CrowdLevel, want) {
+		t.Errorf("crowd level = %+v, want %+v", lake.CrowdLevel, want)
+	}
+	if street := attractions[1]; street.ID != "SC001" || street.District != "上城区" || !reflect.DeepEqual(street.OpenHours, []string{"09:00-22:00"}) {
+		t.Errorf("detailed-only attraction = %+v", street)
+	}
+
+	restaurants, err := loader.LoadRestaurants()
+	if err != nil {
+		t.Fatalf("LoadRestaurants: %v", err)
+	}
+	if r := restaurants[0]; len(restaurants) != 1 || r.PriceRange != "$$$" || !r.ReservationsRequired ||
+		!reflect.DeepEqual(r.SignatureDishes, []string{"西湖醋鱼"}) || r.District != "西湖区" {
+		t.Errorf("restaurants = %+v", restaurants)
+	}
+
+	hotels, err := loader.LoadHotels()
+	if err != nil {
+		t.Fatalf("LoadHotels: %v", err)
+	}
+	if h := hotels[0]; len(hotels) != 1 || h.PricePerNight != 3000 || !reflect.DeepEqual(h.Amenities, []string{"游泳池", "SPA"}) ||
+		!reflect.DeepEqual(h.NearbyStations, []string{"龙翔桥地铁站"}) {
+		t.Errorf("hotels = %+v", hotels)
+	}
+
+	// The query builder filters by district
+	if result := QueryAttractions(attractions).District("上城").Run(); result.Total != 1 || result.Hits[0].Item.ID != "SC001" {
+		t.Errorf("district query = %+v", result.Items())
+	}
+}
+
+func TestRepositoryServesForecast(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, flatFiles)
+	writeFiles(t, dir, detailedFiles)
+
+	repository, err := NewRepository(NewDataLoader(dir))
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	query := NewDataQuery(NewDataLoader(dir))
+	query.UseDataset(repository)
+
+	forecast, err := query.LoadForecast()
+	if err != nil {
+		t.Fatalf("LoadForecast: %v", err)
+	}
+	if forecast.City != "杭州" || len(forecast.DailyForecasts) != 1 || len(forecast.SpecialNotices) != 1 {
+		t.Errorf("forecast = %+v", forecast)
+	}
+	districts, err := query.LoadDistricts()
+	if err != nil || len(districts) != 2 {
+		t.Errorf("districts = %+v, %v", districts, err)
+	}
+
+	// Removing the forecast is picked up by a refresh
+	if err := os.Remove(filepath.Join(dir, "weather/forecast.json")); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := repository.Refresh(); !changed || err != nil {
+		t.Fatalf("Refresh = %v, %v, want a reload", changed, err)
+	}
+	if _, err := query.LoadForecast(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadForecast without a forecast = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestLoadWithoutDetailedData(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, flatFiles)
+	loader := NewDataLoader(dir)
+
+	attractions, err := loader.LoadAttractions()
+	if err != nil {
+		t.Fatalf("LoadAttractions: %v", err)
+	}
+	if len(attractions) != 1 || attractions[0].District != "" || attractions[0].CrowdLevel != nil {
+		t.Errorf("attractions = %+v, want the flat data", attractions)
+	}
+	if _, err := loader.LoadForecast(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadForecast = %v, want os.ErrNotExist", err)
+	}
+	snapshot, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if snapshot.Forecast != nil || len(snapshot.Districts) != 0 {
+		t.Errorf("snapshot forecast = %+v, districts = %+v", snapshot.Forecast, snapshot.Districts)
+	}
+
+	// A broken detailed dataset is an error, not a missing one
+	writeFiles(t, dir, map[string]string{"weather/forecast.json": `{"daily_forecasts": [{"date": "18/02/2025"}]}`})
+	if _, err := loader.Load(); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load with a broken forecast = %v, want a parse error", err)
+	}
+}